@@ -1,7 +1,9 @@
 package ajson
 
 import (
+	"bytes"
 	"io"
+	"math/big"
 	"strings"
 
 	. "github.com/spyzhov/ajson/internal"
@@ -61,6 +63,8 @@ var (
 	_null  = []byte("null")
 	_true  = []byte("true")
 	_false = []byte("false")
+	// bom is the UTF-8 encoded byte order mark
+	bom = []byte{0xEF, 0xBB, 0xBF}
 )
 
 func newBuffer(body []byte) (b *buffer) {
@@ -119,6 +123,13 @@ func (b *buffer) first() (c byte, err error) {
 	return 0, io.EOF
 }
 
+// skipBOM skips the UTF-8 byte order mark at the beginning of the data
+func (b *buffer) skipBOM() {
+	if b.index == 0 && bytes.HasPrefix(b.data, bom) {
+		b.index = len(bom)
+	}
+}
+
 func (b *buffer) backslash() (result bool) {
 	for i := b.index - 1; i >= 0; i-- {
 		if b.data[i] == backslash {
@@ -153,6 +164,7 @@ func (b *buffer) numeric(token bool) error {
 	if token {
 		b.last = GO
 	}
+	start := b.index
 	for ; b.index < b.length; b.index++ {
 		b.class = b.getClasses(quotes)
 		if b.class == __ {
@@ -174,6 +186,9 @@ func (b *buffer) numeric(token bool) error {
 		b.last = b.state
 	}
 	if b.last != ZE && b.last != IN && b.last != FR && b.last != E3 {
+		if b.index >= b.length && start < b.length {
+			return errorNumber(b, start)
+		}
 		return b.errorSymbol()
 	}
 	return nil
@@ -205,6 +220,10 @@ func (b *buffer) string(search byte, token bool) error {
 	}
 	for ; b.index < b.length; b.index++ {
 		b.class = b.getClasses(search)
+		if token && b.last == ES && (b.data[b.index] == quote || b.data[b.index] == quotes) {
+			// both quotes could be escaped in the strings of JSONPath, regardless of the border
+			b.class = C_QUOTE
+		}
 
 		if b.class == __ {
 			return b.errorSymbol()
@@ -676,6 +695,15 @@ func _floats(left, right *Node) (lnum, rnum float64, err error) {
 	return
 }
 
+func _decimals(left, right *Node) (lnum, rnum *big.Rat, err error) {
+	lnum, err = left.getDecimal()
+	if err != nil {
+		return
+	}
+	rnum, err = right.getDecimal()
+	return
+}
+
 func _ints(left, right *Node) (lnum, rnum int, err error) {
 	lnum, err = left.getInteger()
 	if err != nil {
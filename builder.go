@@ -0,0 +1,162 @@
+package ajson
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// Builder is a helper for the fluent construction of the Node tree.
+//
+// Builder never panics: the first error is stored and returned by Builder.Build, all subsequent calls are ignored.
+// Example:
+//
+//	node, err := NewObject().
+//		Set("status_code", 200).
+//		SetObject("body", NewObject().Set("message", "OK")).
+//		SetArray("tags", NewArray().Append("foo", "bar")).
+//		Build()
+type Builder struct {
+	node *Node
+	err  error
+}
+
+// NewObject creates a Builder for the Node with an Object value
+func NewObject() *Builder {
+	return &Builder{node: ObjectNode("", nil)}
+}
+
+// NewArray creates a Builder for the Node with an Array value
+func NewArray() *Builder {
+	return &Builder{node: ArrayNode("", nil)}
+}
+
+// Set sets the value by key for the Object. Value could be one of: nil, numeric types, string, bool, *Node, *Builder,
+// []interface{} or map[string]interface{}.
+func (b *Builder) Set(key string, value interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+	node, err := anyNode(key, value)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.err = b.node.AppendObject(key, node)
+	return b
+}
+
+// SetObject sets the value of the given Object Builder by key for the Object
+func (b *Builder) SetObject(key string, value *Builder) *Builder {
+	return b.Set(key, value)
+}
+
+// SetArray sets the value of the given Array Builder by key for the Object
+func (b *Builder) SetArray(key string, value *Builder) *Builder {
+	return b.Set(key, value)
+}
+
+// Append appends values to the Array. Values types are the same as for the Builder.Set.
+func (b *Builder) Append(values ...interface{}) *Builder {
+	for _, value := range values {
+		if b.err != nil {
+			return b
+		}
+		node, err := anyNode("", value)
+		if err != nil {
+			b.err = err
+			return b
+		}
+		b.err = b.node.AppendArray(node)
+	}
+	return b
+}
+
+// Build returns the constructed Node, or the first error happened during the construction
+func (b *Builder) Build() (*Node, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.node, nil
+}
+
+// Node returns the constructed Node, or nil if any error happened during the construction
+func (b *Builder) Node() *Node {
+	node, _ := b.Build()
+	return node
+}
+
+// integerNode creates a new Numeric Node from the golang integer, big values (like int64 IDs) are kept exactly,
+// as SetBigInt does, so they are marshaled with all digits and without loss of precision
+func integerNode(key string, value interface{}) (*Node, error) {
+	integer := new(big.Int)
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer.SetInt64(reflected.Int())
+	default:
+		integer.SetUint64(reflected.Uint())
+	}
+	float, _ := new(big.Float).SetInt(integer).Float64()
+	node := NumericNode(key, float)
+	if strconv.FormatFloat(float, 'g', -1, 64) == integer.String() {
+		return node, nil
+	}
+	return node, node.SetBigInt(integer)
+}
+
+// anyNode creates a new Node from the golang value
+func anyNode(key string, value interface{}) (node *Node, err error) {
+	switch typed := value.(type) {
+	case nil:
+		return NullNode(key), nil
+	case float64, float32:
+		float, err := numeric2float64(value)
+		if err != nil {
+			return nil, err
+		}
+		return NumericNode(key, float), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return integerNode(key, value)
+	case string:
+		return StringNode(key, typed), nil
+	case bool:
+		return BoolNode(key, typed), nil
+	case *Node:
+		if typed == nil {
+			return NullNode(key), nil
+		}
+		return typed, nil
+	case *Builder:
+		if typed == nil {
+			return NullNode(key), nil
+		}
+		return typed.Build()
+	case []interface{}:
+		node = ArrayNode(key, nil)
+		for _, element := range typed {
+			child, err := anyNode("", element)
+			if err != nil {
+				return nil, err
+			}
+			if err = node.AppendArray(child); err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	case map[string]interface{}:
+		node = ObjectNode(key, nil)
+		for name, element := range typed {
+			child, err := anyNode(name, element)
+			if err != nil {
+				return nil, err
+			}
+			if err = node.AppendObject(name, child); err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	default:
+		return nil, unsupportedType(value)
+	}
+}
@@ -0,0 +1,113 @@
+package ajson
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func ExampleNewObject() {
+	node, err := NewObject().
+		Set("status_code", 200).
+		SetObject("body", NewObject().Set("message", "OK")).
+		SetArray("tags", NewArray().Append("foo", true, nil)).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(node.MustKey("status_code").MustNumeric())
+	fmt.Println(node.MustKey("body").MustKey("message").MustString())
+	fmt.Println(node.MustKey("tags").MustIndex(1).MustBool())
+	// Output:
+	// 200
+	// OK
+	// true
+}
+
+func TestBuilder(t *testing.T) {
+	node, err := NewObject().
+		Set("status_code", 200).
+		Set("null", nil).
+		Set("float", 1.5).
+		Set("bool", false).
+		Set("node", StringNode("", "value")).
+		Set("list", []interface{}{1, "two", map[string]interface{}{"three": 3}}).
+		SetObject("body", NewObject().Set("message", "OK")).
+		SetArray("tags", NewArray().Append("foo", "bar")).
+		Build()
+	if err != nil {
+		t.Errorf("Build() unexpected error: %s", err)
+		return
+	}
+	expected := ObjectNode("", map[string]*Node{
+		"status_code": NumericNode("", 200),
+		"null":        NullNode(""),
+		"float":       NumericNode("", 1.5),
+		"bool":        BoolNode("", false),
+		"node":        StringNode("", "value"),
+		"list": ArrayNode("", []*Node{
+			NumericNode("", 1),
+			StringNode("", "two"),
+			ObjectNode("", map[string]*Node{
+				"three": NumericNode("", 3),
+			}),
+		}),
+		"body": ObjectNode("", map[string]*Node{
+			"message": StringNode("", "OK"),
+		}),
+		"tags": ArrayNode("", []*Node{
+			StringNode("", "foo"),
+			StringNode("", "bar"),
+		}),
+	})
+	if ok, err := node.Eq(expected); err != nil {
+		t.Errorf("Eq() unexpected error: %s", err)
+	} else if !ok {
+		t.Errorf("Build() result = %s, want %s", node, expected)
+	}
+	if path := node.MustKey("list").MustIndex(2).MustKey("three").Path(); path != "$['list'][2]['three']" {
+		t.Errorf("Wrong path: %s", path)
+	}
+}
+
+func TestBuilder_integers(t *testing.T) {
+	node, err := NewArray().
+		Append(int64(math.MaxInt64), int64(math.MinInt64), uint64(math.MaxUint64), int8(-8), uint16(16), 1<<53+1, 1<<53).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %s", err)
+	}
+	expected := `[9223372036854775807,-9223372036854775808,18446744073709551615,-8,16,9007199254740993,9007199254740992]`
+	if value, err := Marshal(node); err != nil || string(value) != expected {
+		t.Errorf("Marshal() = %s, %v, expected %s", value, err, expected)
+	}
+	if value := node.MustIndex(2).MustNumeric(); value != float64(math.MaxUint64) {
+		t.Errorf("MustNumeric() = %v, expected the closest float64", value)
+	}
+}
+
+func TestBuilder_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+	}{
+		{name: "Set on Array", builder: NewArray().Set("key", 1)},
+		{name: "Append on Object", builder: NewObject().Append(1)},
+		{name: "unsupported type", builder: NewObject().Set("key", struct{}{})},
+		{name: "unsupported type in slice", builder: NewArray().Append([]interface{}{1, struct{}{}})},
+		{name: "unsupported type in map", builder: NewArray().Append(map[string]interface{}{"key": struct{}{}})},
+		{name: "broken child", builder: NewObject().SetObject("key", NewArray().Set("key", 1))},
+		{name: "first error is kept", builder: NewObject().Set("key", struct{}{}).Set("valid", 1).Append(1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, err := test.builder.Build()
+			if err == nil {
+				t.Errorf("Build() expected error, got %s", node)
+			}
+			if test.builder.Node() != nil {
+				t.Errorf("Node() expected nil")
+			}
+		})
+	}
+}
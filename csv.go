@@ -0,0 +1,101 @@
+package ajson
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// CSVOption is a functional option for ToCSV
+type CSVOption func(options *csvOptions)
+
+type csvOptions struct {
+	nestedJSON bool
+}
+
+// NestedAsJSON makes ToCSV to write the Array and Object values into their cells as JSON, instead of the error
+func NestedAsJSON() CSVOption {
+	return func(options *csvOptions) {
+		options.nestedJSON = true
+	}
+}
+
+// ToCSV returns the CSV for current Array node of the flat objects, one record per element, with the header record
+// of the given columns. If headers are empty, columns are the keys of the first element, sorted. Cells are written
+// as AsString returns them: Null and missing members are the empty cells.
+//
+// Array and Object values return the error with their path, which is ErrWrongType, use NestedAsJSON to write them
+// as JSON. Non-object elements return the same error. For non Array node WrongType error will be returned.
+//
+//	root := ajson.Must(ajson.Unmarshal([]byte(`[{"id":1,"name":"foo"},{"id":2,"tags":["a"]}]`)))
+//	result, _ := root.ToCSV([]string{"id", "name", "tags"}, ajson.NestedAsJSON())
+//	// id,name,tags
+//	// 1,foo,
+//	// 2,,"[""a""]"
+func (n *Node) ToCSV(headers []string, options ...CSVOption) ([]byte, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if !n.IsArray() {
+		return nil, errorType()
+	}
+	opts := new(csvOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	elements := n.Inheritors()
+	if len(headers) == 0 && len(elements) != 0 {
+		if !elements[0].IsObject() {
+			return nil, PathError{Path: elements[0].Path(), Err: errorType()}
+		}
+		for _, child := range elements[0].Inheritors() {
+			headers = append(headers, child.Key())
+		}
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if len(headers) != 0 {
+		if err := writer.Write(headers); err != nil {
+			return nil, err
+		}
+	}
+	record := make([]string, len(headers))
+	for _, element := range elements {
+		if !element.IsObject() {
+			return nil, PathError{Path: element.Path(), Err: errorType()}
+		}
+		for i, header := range headers {
+			cell, err := opts.cell(element.children[header])
+			if err != nil {
+				return nil, err
+			}
+			record[i] = cell
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cell returns the value of the CSV cell for the member, nil member is the empty cell
+func (o *csvOptions) cell(member *Node) (string, error) {
+	if member == nil {
+		return "", nil
+	}
+	if member.isContainer() {
+		if !o.nestedJSON {
+			return "", PathError{Path: member.Path(), Err: errorType()}
+		}
+		value, err := Marshal(member)
+		return string(value), err
+	}
+	value, err := member.AsString()
+	if err != nil {
+		return "", PathError{Path: member.Path(), Err: err}
+	}
+	return value, nil
+}
@@ -0,0 +1,98 @@
+package ajson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func ExampleNode_ToCSV() {
+	root := Must(Unmarshal([]byte(`{"field3":[{"sub_field":1,"sub2":"foo"},{"sub_field":2,"sub2":"bar, baz"}]}`)))
+	result, _ := root.MustKey("field3").ToCSV(nil)
+	fmt.Printf("%s", result)
+	// Output:
+	// sub2,sub_field
+	// foo,1
+	// "bar, baz",2
+}
+
+func TestNode_ToCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		headers  []string
+		options  []CSVOption
+		expected string
+		err      string
+	}{
+		{name: "empty", json: `[]`, expected: ``},
+		{name: "empty with headers", json: `[]`, headers: []string{"a", "b"}, expected: "a,b\n"},
+		{
+			name:     "inferred",
+			json:     `[{"b":1,"a":"x"},{"a":"y","c":true}]`,
+			expected: "a,b\nx,1\ny,\n",
+		},
+		{
+			name:     "headers",
+			json:     `[{"b":1,"a":"x"},{"a":"y","c":true}]`,
+			headers:  []string{"c", "a", "d"},
+			expected: "c,a,d\n,x,\ntrue,y,\n",
+		},
+		{
+			name:     "scalars",
+			json:     `[{"n":null,"f":false,"x":1.50e2,"s":"quote \" and\nline"}]`,
+			headers:  []string{"n", "f", "x", "s"},
+			expected: "n,f,x,s\n,false,1.50e2,\"quote \"\" and\nline\"\n",
+		},
+		{
+			name:     "nested as json",
+			json:     `[{"a":[1,"x"],"b":{"c":null}}]`,
+			options:  []CSVOption{NestedAsJSON()},
+			expected: "a,b\n\"[1,\"\"x\"\"]\",\"{\"\"c\"\":null}\"\n",
+		},
+		{
+			name: "nested",
+			json: `[{"a":1},{"a":[1]}]`,
+			err:  "$[1]['a']: wrong type of Node",
+		},
+		{
+			name: "not an object",
+			json: `[{"a":1},2]`,
+			err:  "$[1]: wrong type of Node",
+		},
+		{
+			name: "first is not an object",
+			json: `[[1]]`,
+			err:  "$[0]: wrong type of Node",
+		},
+		{
+			name: "not an array",
+			json: `{"a":1}`,
+			err:  "wrong type of Node",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.ToCSV(test.headers, test.options...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("ToCSV() error = %v, expected %s", err, test.err)
+				}
+				if !errors.Is(err, ErrWrongType) {
+					t.Errorf("ToCSV() error = %v, expected to be ErrWrongType", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToCSV() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("ToCSV() = %q, expected %q", result, test.expected)
+			}
+		})
+	}
+	if _, err := (*Node)(nil).ToCSV(nil); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("ToCSV() error = %v, expected %v", err, ErrNotParsed)
+	}
+}
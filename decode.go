@@ -1,9 +1,16 @@
 package ajson
 
 import (
+	"context"
+	"strconv"
+	"unsafe"
+
 	. "github.com/spyzhov/ajson/internal"
 )
 
+// contextCheckInterval is the count of the parser steps between the checks of the context
+const contextCheckInterval = 1 << 12
+
 // List of action codes.
 // Copy from `internal/state.go:144`
 const (
@@ -17,28 +24,220 @@ const (
 	ec States = -9 /* curly br. empty */
 )
 
+// UnmarshalOption is a functional option to configure UnmarshalWithOptions behaviour
+type UnmarshalOption func(options *unmarshalOptions)
+
+type unmarshalOptions struct {
+	containerRoot bool
+	maxDepth      int
+	duplicateKeys bool
+	ctx           context.Context
+	internKeys    bool
+	bigNumbers    bool
+	noEmptyKeys   bool
+	noOutOfRange  bool
+	keyRanges     bool
+	maxSize       int64
+	readOnly      bool
+}
+
+// ContainerRoot makes Unmarshal to reject JSON with a scalar value (null, number, string or boolean) at the root.
+// By default, any JSON value is accepted as the root, as described in RFC 8259.
+func ContainerRoot() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.containerRoot = true
+	}
+}
+
+// MaxDepth makes Unmarshal to reject JSON with containers nested deeper than the given depth, the root container has
+// depth 1. It protects from the malicious data, which is too expensive to process. Zero or negative depth means no limit.
+func MaxDepth(depth int) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.maxDepth = depth
+	}
+}
+
+// MaxSize makes Unmarshal to reject the data bigger than the given size in bytes. For UnmarshalGzip and
+// UnmarshalGzipReader it limits the size of the decompressed data: reading stops as soon as the limit is exceeded, so
+// the small compressed payload couldn't exhaust the memory (a "gzip bomb"). Zero or negative size means no limit.
+func MaxSize(size int64) UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.maxSize = size
+	}
+}
+
+// DuplicateKeys makes Unmarshal to keep all occurrences of the repeated keys of the objects, they are available with
+// Node.KeyAll and marshaled back in the source order. It's an opt-in, because the children of the Object are stored
+// in the map by keys: all other methods (GetKey, JSONPath, Eq, etc.) see only the last occurrence of the key, as by
+// default. AppendObject replaces all occurrences of the key, DeleteKey and PopKey remove only the last one.
+// Modified objects with repeated keys are marshaled without PreserveFormatting.
+func DuplicateKeys() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.duplicateKeys = true
+	}
+}
+
+// InternKeys makes Unmarshal to keep only one copy of each distinct key of the objects: all members with the same key
+// share the same string. It reduces the memory usage and allocations for the documents with many repeated keys, like
+// arrays of objects, at the cost of the lookup of each key.
+func InternKeys() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.internKeys = true
+	}
+}
+
+// BigNumbers makes Unmarshal to keep the exact value of each number, which couldn't be restored from float64: big
+// integers (like IDs) and decimals with too many digits or too big exponents. Node.BigInt and Node.BigFloat return the
+// exact values, and Marshal encodes them without loss of precision even if the source is dropped, e.g. with
+// CompactSource. Without it, the exact values are available only from the unchanged source. Numbers with the exponent
+// bigger than 4096 by the absolute value are never kept.
+func BigNumbers() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.bigNumbers = true
+	}
+}
+
+// RejectOutOfRange makes Unmarshal to reject numbers, which have no float64 representation: too big by the absolute
+// value, like `1e1000`, which would be ±Inf, and non-zero numbers, which are too small, like `1e-1000`, which would
+// be 0. The error is WrongSymbol at the start of the number, errors.Is(err, strconv.ErrRange) is true for it.
+//
+// By default, such numbers are accepted and kept as is: unchanged nodes are marshaled back from the source,
+// GetNumeric returns the error, which is strconv.ErrRange, for the too big numbers and 0 for the too small ones,
+// and with BigNumbers their exact values are available with Node.BigFloat.
+func RejectOutOfRange() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.noOutOfRange = true
+	}
+}
+
+// KeyRanges makes Unmarshal to keep the borders of the keys of the objects in the data, they are available with
+// Node.KeyRange. It's an opt-in, as most of the callers never need them. DuplicateKeys keeps them as well, to marshal
+// the repeated keys in the source order.
+func KeyRanges() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.keyRanges = true
+	}
+}
+
+// ReadOnly makes Unmarshal to build the frozen tree (see Node.Freeze) for the read-only queries, which shares the
+// memory of the data even more: keys of the objects and the decoded values of the strings are not copied from the
+// data, so there is no allocation per key and per accessed string (except the ones with escapes). It's useful for
+// the read-heavy workloads, like JSONPath queries over the big documents.
+//
+// The data must not be modified or reused not only while the tree is in use, but while any key or string value,
+// returned by it (e.g. with Keys, GetString or JSONPath), is in use as well, otherwise they change with the data.
+// Copy the strings, which outlive the data. Mutation methods return the error of the ErrFrozen category, use Clone
+// to get the changeable copy of the node.
+func ReadOnly() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.readOnly = true
+	}
+}
+
+// RejectEmptyKeys makes Unmarshal to reject objects with the empty key, like `{"":1}`. Such keys are valid in JSON and
+// accepted by default: such members are available with GetKey and JSONPath by the empty key, and marshaled back as is.
+// But usually they are the sign of a bug in the producer of the data.
+func RejectEmptyKeys() UnmarshalOption {
+	return func(options *unmarshalOptions) {
+		options.noEmptyKeys = true
+	}
+}
+
 // Unmarshal parses the JSON-encoded data and return the root node of struct.
 //
 // Doesn't calculate values, just type of stored value. It will store link to the data, on all life long.
+//
+// Parsing is zero-copy: nodes keep the borders of their values in the data, and values are decoded lazily, on the
+// first access, so read-only queries (e.g. JSONPath) don't pay for values they don't use. Node.Source and
+// Node.StringBytes return slices of the data without allocation. Therefore, the data must not be modified or reused
+// while the tree is in use, otherwise unchanged nodes will return corrupted values. Use UnmarshalSafe, if the data
+// could be changed. Mutation methods never write to the data.
+//
+// Unmarshal follows RFC 8259 strictly: numbers with leading zeros (`01`), without digits after the decimal point
+// (`1.`) or in the exponent (`1e`), with leading `+` or `.`, trailing commas, unescaped control characters in the
+// strings and invalid escapes are rejected with the offset of the wrong symbol, or with the UnexpectedEOF error if
+// the data ends in the middle of the value. The number incomplete at the end of the data is reported as the wrong
+// symbol at its start. Use UnmarshalJSON5 for the relaxed syntax.
+//
+// The UTF-8 byte order mark at the beginning of the data is skipped, as well as the whitespaces around the value.
+// Offsets in the errors and Node.Range are still relative to the original data.
 func Unmarshal(data []byte) (root *Node, err error) {
-	buf := newBuffer(data)
+	return UnmarshalWithOptions(data)
+}
+
+// UnmarshalWithOptions do the same thing as Unmarshal, but with the given options
+func UnmarshalWithOptions(data []byte, options ...UnmarshalOption) (root *Node, err error) {
+	opts := new(unmarshalOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	return unmarshal(data, opts)
+}
+
+// UnmarshalContext do the same thing as Unmarshal, but aborts parsing with the ctx.Err() error as soon as ctx is done.
+// Context is checked periodically, not on every byte, so parsing of the small data could complete anyway.
+func UnmarshalContext(ctx context.Context, data []byte) (root *Node, err error) {
+	return unmarshal(data, &unmarshalOptions{ctx: ctx})
+}
+
+func unmarshal(data []byte, options *unmarshalOptions) (root *Node, err error) {
+	if options.maxSize > 0 && int64(len(data)) > options.maxSize {
+		return nil, errorRequest("maximum size %d exceeded", options.maxSize)
+	}
+	return unmarshalBuffer(newBuffer(data), options, false)
+}
+
+// unmarshalBuffer parses the value from the current position of the buffer. If next is true, parsing stops right after
+// the end of the root value, at its last byte, so the following data could be parsed by the next call.
+func unmarshalBuffer(buf *buffer, options *unmarshalOptions, next bool) (root *Node, err error) {
 	var (
 		state   States
 		key     *string
 		current *Node
-		useKey  = func() **string {
-			tmp := cptrs(key)
+		depth   int
+		steps   int
+		keys    map[string]*string
+		// keySpan is the borders of the last parsed key, including quotes, if they are kept
+		keySpan  [2]int
+		keepSpan = options.keyRanges || options.duplicateKeys
+		useKey   = func() **string {
+			tmp := key // the value of the key is never changed, so it's safe to share it between nodes without copying
 			key = nil
 			return &tmp
 		}
+		newChild = func(_type NodeType) (*Node, error) {
+			node, err := newNode(current, buf, _type, useKey())
+			if options.readOnly && err == nil {
+				node.frozen = true
+				node.readOnly = true
+			}
+			if keepSpan && err == nil && current != nil && current._type == Object {
+				span := keySpan
+				node.keyBorders = &span
+			}
+			return node, err
+		}
 	)
 
-	_, err = buf.first()
+	buf.skipBOM()
+	first, err := buf.first()
 	if err != nil {
 		return nil, buf.errorEOF()
 	}
+	if options.internKeys {
+		keys = make(map[string]*string)
+	}
+	if options.containerRoot && first != bracesL && first != bracketL {
+		return nil, buf.errorSymbol()
+	}
 
 	for {
+		if options.ctx != nil && steps%contextCheckInterval == 0 {
+			if err = options.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		steps++
 		state = buf.getState()
 		if state == __ {
 			return nil, buf.errorSymbol()
@@ -50,11 +249,18 @@ func Unmarshal(data []byte) (root *Node, err error) {
 			case ST:
 				if current != nil && current.IsObject() && key == nil {
 					// Detected: Key
-					key, err = getString(buf)
+					start := buf.index
+					key, err = getString(buf, keys, options.readOnly)
+					if keepSpan {
+						keySpan = [2]int{start, buf.index + 1}
+					}
+					if err == nil && options.noEmptyKeys && *key == "" {
+						return nil, errorData(buf, buf.index-1, "empty key")
+					}
 					buf.state = CO
 				} else {
 					// Detected: String
-					current, err = newNode(current, buf, String, useKey())
+					current, err = newChild(String)
 					if err != nil {
 						break
 					}
@@ -66,19 +272,25 @@ func Unmarshal(data []byte) (root *Node, err error) {
 					}
 				}
 			case MI, ZE, IN:
-				current, err = newNode(current, buf, Numeric, useKey())
+				current, err = newChild(Numeric)
 				if err != nil {
 					break
 				}
 				err = buf.numeric(false)
 				current.borders[1] = buf.index
+				if err == nil && options.bigNumbers {
+					current.exact = exactNumber(current.Source())
+				}
+				if err == nil && options.noOutOfRange && outOfRange(current.Source()) {
+					return nil, errorRange(buf, current.borders[0], current.Source())
+				}
 				buf.index -= 1
 				buf.state = OK
 				if current.parent != nil {
 					current = current.parent
 				}
 			case T1, F1:
-				current, err = newNode(current, buf, Bool, useKey())
+				current, err = newChild(Bool)
 				if err != nil {
 					break
 				}
@@ -93,7 +305,7 @@ func Unmarshal(data []byte) (root *Node, err error) {
 					current = current.parent
 				}
 			case N1:
-				current, err = newNode(current, buf, Null, useKey())
+				current, err = newChild(Null)
 				if err != nil {
 					break
 				}
@@ -115,6 +327,10 @@ func Unmarshal(data []byte) (root *Node, err error) {
 				fallthrough
 			case cc: /* } */
 				if current != nil && current.IsObject() && !current.ready() {
+					depth--
+					if len(current.duplicates) == 0 {
+						current.duplicates = nil
+					}
 					current.borders[1] = buf.index + 1
 					if current.parent != nil {
 						current = current.parent
@@ -125,6 +341,7 @@ func Unmarshal(data []byte) (root *Node, err error) {
 				buf.state = OK
 			case bc: /* ] */
 				if current != nil && current.IsArray() && !current.ready() {
+					depth--
 					current.borders[1] = buf.index + 1
 					if current.parent != nil {
 						current = current.parent
@@ -134,10 +351,21 @@ func Unmarshal(data []byte) (root *Node, err error) {
 				}
 				buf.state = OK
 			case co: /* { */
-				current, err = newNode(current, buf, Object, useKey())
+				depth++
+				if options.maxDepth > 0 && depth > options.maxDepth {
+					return nil, errorData(buf, buf.index, "maximum depth %d exceeded", options.maxDepth)
+				}
+				current, err = newChild(Object)
+				if err == nil && options.duplicateKeys {
+					current.duplicates = make(map[string][]*Node)
+				}
 				buf.state = OB
 			case bo: /* [ */
-				current, err = newNode(current, buf, Array, useKey())
+				depth++
+				if options.maxDepth > 0 && depth > options.maxDepth {
+					return nil, errorData(buf, buf.index, "maximum depth %d exceeded", options.maxDepth)
+				}
+				current, err = newChild(Array)
 				buf.state = AR
 			case cm: /* , */
 				if current == nil {
@@ -164,6 +392,9 @@ func Unmarshal(data []byte) (root *Node, err error) {
 		if err != nil {
 			return
 		}
+		if next && buf.state == OK && current != nil && current.parent == nil && current.ready() {
+			break
+		}
 		if buf.step() != nil {
 			break
 		}
@@ -186,6 +417,58 @@ func Unmarshal(data []byte) (root *Node, err error) {
 	return
 }
 
+// UnmarshalMany parses the data with several JSON values, concatenated one after another, with or without
+// whitespaces between them: `{"a":1}{"a":2}[3] 4`. Each value is parsed into its own independent root Node, all
+// of them share the same data. Returns an empty slice for the data without values.
+//
+// Parse errors are reported with the offset in the whole data. Values, which could be joined together, like
+// numbers without a whitespace between them, are parsed as one value.
+func UnmarshalMany(data []byte) (roots []*Node, err error) {
+	roots = make([]*Node, 0)
+	buf := newBuffer(data)
+	options := new(unmarshalOptions)
+	for {
+		if _, err = buf.first(); err != nil {
+			return roots, nil
+		}
+		buf.state, buf.last = GO, GO
+		root, err := unmarshalBuffer(buf, options, true)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+		if buf.step() != nil {
+			return roots, nil
+		}
+	}
+}
+
+// PeekType returns the type of the root value by its first significant byte, without parsing the data. The rest of
+// the data isn't validated, so PeekType could succeed for an invalid JSON.
+func PeekType(data []byte) (NodeType, error) {
+	buf := newBuffer(data)
+	buf.skipBOM()
+	c, err := buf.first()
+	if err != nil {
+		return Null, buf.errorEOF()
+	}
+	switch {
+	case c == bracesL:
+		return Object, nil
+	case c == bracketL:
+		return Array, nil
+	case c == quotes:
+		return String, nil
+	case c == 't' || c == 'f':
+		return Bool, nil
+	case c == 'n':
+		return Null, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return Numeric, nil
+	}
+	return Null, buf.errorSymbol()
+}
+
 // UnmarshalSafe do the same thing as Unmarshal, but copy data to the local variable, to make it editable.
 func UnmarshalSafe(data []byte) (root *Node, err error) {
 	var safe []byte
@@ -193,6 +476,30 @@ func UnmarshalSafe(data []byte) (root *Node, err error) {
 	return Unmarshal(safe)
 }
 
+// UnescapeString returns the value of the JSON string literal, e.g. `"foo\nbar"`, the same way as Unmarshal does
+func UnescapeString(data []byte) (string, error) {
+	buf := newBuffer(data)
+	c, err := buf.current()
+	if err != nil {
+		return "", buf.errorEOF()
+	}
+	if c != quotes {
+		return "", buf.errorSymbol()
+	}
+	if err = buf.string(quotes, false); err != nil {
+		return "", err
+	}
+	if buf.index != buf.length-1 {
+		buf.index++
+		return "", buf.errorSymbol()
+	}
+	value, ok := unquote(data, quotes)
+	if !ok {
+		return "", errorAt(0, quotes)
+	}
+	return value, nil
+}
+
 // Must returns a Node if there was no error. Else - panic with error as the value.
 func Must(root *Node, err error) *Node {
 	if err != nil {
@@ -201,19 +508,64 @@ func Must(root *Node, err error) *Node {
 	return root
 }
 
-func getString(b *buffer) (*string, error) {
+// outOfRange checks that the JSON number overflows float64 or underflows it to zero
+func outOfRange(source []byte) bool {
+	value, err := strconv.ParseFloat(string(source), 64)
+	if err != nil {
+		return true
+	}
+	if value != 0 {
+		return false
+	}
+	for _, c := range source {
+		if c == 'e' || c == 'E' {
+			break
+		}
+		if c >= '1' && c <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// getString returns the unquoted key of the Object, if keys is not nil, the same pointer is returned for equal keys.
+// If view is true, the key isn't copied, see viewString.
+func getString(b *buffer, keys map[string]*string, view bool) (*string, error) {
 	start := b.index
 	err := b.string(quotes, false)
 	if err != nil {
 		return nil, err
 	}
-	value, ok := unquote(b.data[start:b.index+1], quotes)
+	raw, ok := unquoteBytes(b.data[start:b.index+1], quotes)
 	if !ok {
 		return nil, errorSymbol(b)
 	}
+	if keys != nil {
+		if value, ok := keys[string(raw)]; ok {
+			return value, nil
+		}
+	}
+	var value string
+	if view {
+		value = viewString(raw)
+	} else {
+		value = string(raw)
+	}
+	if keys != nil {
+		keys[value] = &value
+	}
 	return &value, nil
 }
 
+// viewString returns the string, which shares the memory with b, without copying: it changes with b, so b must not
+// be modified while the string is in use
+func viewString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return *(*string)(unsafe.Pointer(&b))
+}
+
 func cptrs(cpy *string) *string {
 	if cpy == nil {
 		return nil
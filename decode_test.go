@@ -2,9 +2,14 @@ package ajson
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -422,6 +427,778 @@ func TestUnmarshalSafe(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_ScalarRoot(t *testing.T) {
+	tests := []struct {
+		value string
+		_type NodeType
+	}{
+		{value: `null`, _type: Null},
+		{value: `true`, _type: Bool},
+		{value: `false`, _type: Bool},
+		{value: `-12.5e3`, _type: Numeric},
+		{value: `0`, _type: Numeric},
+		{value: `"one \"encoded\" string"`, _type: String},
+		{value: ` "spaces" `, _type: String},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			root, err := Unmarshal([]byte(test.value))
+			if err != nil {
+				t.Errorf("Error on Unmarshal: %s", err.Error())
+				return
+			}
+			if root.Type() != test._type {
+				t.Errorf("Error on Unmarshal: wrong type %d", root.Type())
+			}
+			value, err := Marshal(root)
+			if err != nil {
+				t.Errorf("Error on Marshal: %s", err.Error())
+			} else if string(value) != strings.TrimSpace(test.value) {
+				t.Errorf("Error on Marshal: %s != %s", value, test.value)
+			}
+
+			root, err = UnmarshalWithOptions([]byte(test.value), ContainerRoot())
+			if err == nil {
+				t.Errorf("Error on UnmarshalWithOptions: error expected, got '%s'", root)
+			} else if root != nil {
+				t.Errorf("Error on UnmarshalWithOptions: root is not nil")
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_ContainerRoot(t *testing.T) {
+	tests := []struct {
+		value string
+		_type NodeType
+	}{
+		{value: `{}`, _type: Object},
+		{value: ` {"foo":1}`, _type: Object},
+		{value: `[]`, _type: Array},
+		{value: "\n[1,null]", _type: Array},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			root, err := UnmarshalWithOptions([]byte(test.value), ContainerRoot())
+			if err != nil {
+				t.Errorf("Error on UnmarshalWithOptions: %s", err.Error())
+			} else if root.Type() != test._type {
+				t.Errorf("Error on UnmarshalWithOptions: wrong type %d", root.Type())
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_MaxDepth(t *testing.T) {
+	tests := []struct {
+		value string
+		depth int
+		err   string
+	}{
+		{value: `1`, depth: 1},
+		{value: `[]`, depth: 1},
+		{value: `[1, {}, [], "[[["]`, depth: 2},
+		{value: `{"a": [{"b": []}], "c": [[]]}`, depth: 4},
+		{value: `[[[]]]`, depth: 0},
+		{value: `[[[]]]`, depth: -1},
+		{value: `[]`, depth: 0},
+		{value: `[[]]`, depth: 1, err: "wrong request: maximum depth 1 exceeded at 1"},
+		{value: `{"a": {"b": {}}}`, depth: 2, err: "wrong request: maximum depth 2 exceeded at 12"},
+		{value: `[[], [], [[]]]`, depth: 2, err: "wrong request: maximum depth 2 exceeded at 10"},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			root, err := UnmarshalWithOptions([]byte(test.value), MaxDepth(test.depth))
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("UnmarshalWithOptions() unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("UnmarshalWithOptions() expected error, got %s", root)
+			} else if err.Error() != test.err {
+				t.Errorf("UnmarshalWithOptions() error = %s, expected %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_MaxSize(t *testing.T) {
+	tests := []struct {
+		value string
+		size  int64
+		err   string
+	}{
+		{value: `[1, 2]`, size: 6},
+		{value: `[1, 2]`, size: 0},
+		{value: `[1, 2]`, size: -1},
+		{value: `[1, 2]`, size: 5, err: "wrong request: maximum size 5 exceeded"},
+		{value: `""`, size: 1, err: "wrong request: maximum size 1 exceeded"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%d", test.value, test.size), func(t *testing.T) {
+			_, err := UnmarshalWithOptions([]byte(test.value), MaxSize(test.size))
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("UnmarshalWithOptions() unexpected error: %s", err)
+				}
+			} else if err == nil || err.Error() != test.err {
+				t.Errorf("UnmarshalWithOptions() error = %v, expected %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_ReadOnly(t *testing.T) {
+	data := []byte(`{"name": "foo", "escaped": "b\u0061r", "list": [1, {"key": true}]}`)
+	root, err := UnmarshalWithOptions(data, ReadOnly())
+	if err != nil {
+		t.Fatalf("UnmarshalWithOptions() unexpected error: %s", err)
+	}
+	if value, err := root.MustKey("name").GetString(); err != nil || value != "foo" {
+		t.Errorf("GetString() = %s, %v", value, err)
+	}
+	if value, err := root.MustKey("escaped").GetString(); err != nil || value != "bar" {
+		t.Errorf("GetString() = %s, %v", value, err)
+	}
+	if result, err := root.JSONPath("$.list[?(@.key == true)].key"); err != nil || len(result) != 1 {
+		t.Errorf("JSONPath() = %v, %v", result, err)
+	}
+	if value, err := Marshal(root); err != nil || string(value) != string(data) {
+		t.Errorf("Marshal() = %s, %v", value, err)
+	}
+	if !root.MustKey("list").MustIndex(1).IsFrozen() {
+		t.Errorf("IsFrozen() = false for the read-only node")
+	}
+	if err = root.AppendObject("new", NullNode("")); !errors.Is(err, ErrFrozen) {
+		t.Errorf("AppendObject() error = %v, expected ErrFrozen", err)
+	}
+	clone := root.Clone()
+	if err = clone.MustKey("name").SetString("baz"); err != nil {
+		t.Errorf("SetString() unexpected error for the clone: %s", err)
+	}
+
+	// keys and strings share the memory with the data
+	data[2] = 'N'
+	keys := root.Keys()
+	sort.Strings(keys)
+	if !sliceEqual(keys, []string{"Name", "escaped", "list"}) {
+		t.Errorf("Keys() = %v, expected the changed key", keys)
+	}
+
+	object := []byte(`{"alpha": "a", "beta": "b", "gamma": "c", "delta": "d"}`)
+	parse := func(options ...UnmarshalOption) func() {
+		return func() {
+			root := Must(UnmarshalWithOptions(object, options...))
+			for _, child := range root.Inheritors() {
+				_, _ = child.GetString()
+			}
+		}
+	}
+	if copied, shared := testing.AllocsPerRun(10, parse()), testing.AllocsPerRun(10, parse(ReadOnly())); shared >= copied {
+		t.Errorf("ReadOnly() allocations = %v, expected less than %v", shared, copied)
+	}
+}
+
+func TestUnmarshal_emptyKey(t *testing.T) {
+	root, err := Unmarshal([]byte(`{"": 1, "a": {"": [""]}}`))
+	if err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %s", err)
+	}
+	if value, err := root.MustKey("").GetNumeric(); err != nil || value != 1 {
+		t.Errorf("GetKey('') = %v, %v, expected 1", value, err)
+	}
+	nested := root.MustKey("a").MustKey("")
+	if path := nested.Path(); path != "$['a']['']" {
+		t.Errorf("Path() = %s, expected $['a']['']", path)
+	}
+	for path, expected := range map[string]string{
+		"$['']":              "[$['']]",
+		"$.a['']":            "[$['a']['']]",
+		"$..['']":            "[$[''], $['a']['']]",
+		"$.a[''][0]":         "[$['a'][''][0]]",
+		"$.a[?(@[0] == '')]": "[$['a']['']]",
+	} {
+		result, err := root.JSONPath(path)
+		if err != nil {
+			t.Errorf("JSONPath(%s) unexpected error: %s", path, err)
+			continue
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Path() < result[j].Path() })
+		if value := fullPath(result); value != expected {
+			t.Errorf("JSONPath(%s) = %s, expected %s", path, value, expected)
+		}
+	}
+	if err = nested.AppendArray(StringNode("", "b")); err != nil {
+		t.Fatalf("AppendArray() unexpected error: %s", err)
+	}
+	if err = root.AppendObject("", NullNode("")); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	value, err := MarshalWithOptions(root, SortKeys())
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if string(value) != `{"":null,"a":{"":["","b"]}}` {
+		t.Errorf("Marshal() = %s", value)
+	}
+	if copied, err := Unmarshal(value); err != nil || !copied.MustKey("").IsNull() || copied.MustKey("a").MustKey("").Size() != 2 {
+		t.Errorf("Unmarshal() round-trip = %s, %v", copied, err)
+	}
+}
+
+func TestUnmarshalWithOptions_RejectEmptyKeys(t *testing.T) {
+	tests := []struct {
+		value string
+		err   string
+	}{
+		{value: `{"a": 1, "b": {"c": []}}`},
+		{value: `[""]`},
+		{value: `{"a": ""}`},
+		{value: `{"": 1}`, err: "wrong request: empty key at 1"},
+		{value: `{"a": [{"b": 1, "": 2}]}`, err: "wrong request: empty key at 16"},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if _, err := Unmarshal([]byte(test.value)); err != nil {
+				t.Errorf("Unmarshal() unexpected error: %s", err)
+			}
+			root, err := UnmarshalWithOptions([]byte(test.value), RejectEmptyKeys())
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("UnmarshalWithOptions() unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("UnmarshalWithOptions() expected error, got %s", root)
+			} else if err.Error() != test.err {
+				t.Errorf("UnmarshalWithOptions() error = %s, expected %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalWithOptions_DuplicateKeys(t *testing.T) {
+	data := []byte(`{"a": 1, "b": {"c": 2, "c": 3}, "a": 4, "a": null}`)
+	root, err := UnmarshalWithOptions(data, DuplicateKeys())
+	if err != nil {
+		t.Fatalf("UnmarshalWithOptions() unexpected error: %s", err)
+	}
+	marshal := func(node *Node, expected string, options ...MarshalOption) {
+		t.Helper()
+		result, err := MarshalWithOptions(node, options...)
+		if err != nil {
+			t.Errorf("Marshal() unexpected error: %s", err)
+		} else if string(result) != expected {
+			t.Errorf("Marshal() = %s, expected %s", result, expected)
+		}
+	}
+	values := func(nodes []*Node) string {
+		result := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			result = append(result, node.String())
+		}
+		return strings.Join(result, ",")
+	}
+
+	if value := values(root.KeyAll("a")); value != "1,4,null" {
+		t.Errorf("KeyAll(a) = %s", value)
+	}
+	if value := values(root.MustKey("b").KeyAll("c")); value != "2,3" {
+		t.Errorf("KeyAll(c) = %s", value)
+	}
+	if value := values(root.KeyAll("b")); value != `{"c": 2, "c": 3}` {
+		t.Errorf("KeyAll(b) = %s", value)
+	}
+	if value := root.KeyAll("x"); value != nil {
+		t.Errorf("KeyAll(x) = %v", value)
+	}
+	if value := root.MustKey("a"); !value.IsNull() {
+		t.Errorf("GetKey(a) = %s, expected the last occurrence", value)
+	}
+	marshal(root, string(data))
+
+	clone := root.Clone()
+	if value := values(clone.KeyAll("a")); value != "1,4,null" {
+		t.Errorf("Clone().KeyAll(a) = %s", value)
+	}
+	for _, node := range clone.KeyAll("a") {
+		if node.Parent() != clone {
+			t.Errorf("Clone().KeyAll(a) has wrong parent")
+		}
+	}
+
+	if err = root.MustKey("b").AppendObject("d", NullNode("")); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	marshal(root.MustKey("b"), `{"c":2,"c":3,"d":null}`, SortKeys())
+	marshal(root, `{"a":1,"a":4,"b":{"c":2,"c":3}}`, SortKeys(), OmitNull(), PreserveFormatting())
+
+	if err = root.KeyAll("a")[1].Delete(); err != nil {
+		t.Fatalf("Delete() unexpected error: %s", err)
+	}
+	if value := values(root.KeyAll("a")); value != "1,null" {
+		t.Errorf("KeyAll(a) = %s, after Delete", value)
+	}
+	if err = root.DeleteKey("a"); err != nil {
+		t.Fatalf("DeleteKey() unexpected error: %s", err)
+	}
+	if value := values(root.KeyAll("a")); value != "1" || root.MustKey("a").MustNumeric() != 1 {
+		t.Errorf("KeyAll(a) = %s, after DeleteKey", value)
+	}
+	if err = root.MustKey("b").AppendObject("c", NumericNode("", 5)); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	marshal(root, `{"a":1,"b":{"c":5,"d":null}}`, SortKeys())
+	if err = clone.AppendObject("e", NumericNode("", 6)); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	marshal(clone, `{"a":1,"a":4,"a":null,"b":{"c":2,"c":3},"e":6}`, SortKeys())
+
+	root = Must(Unmarshal(data))
+	if value := values(root.KeyAll("a")); value != "null" {
+		t.Errorf("KeyAll(a) = %s, without DuplicateKeys", value)
+	}
+}
+
+func TestUnmarshalMany(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		err      string
+	}{
+		{name: "empty", input: ``, expected: []string{}},
+		{name: "whitespaces", input: " \n\t ", expected: []string{}},
+		{name: "single", input: `{"a":1}`, expected: []string{`{"a":1}`}},
+		{name: "objects", input: `{"a":1}{"a":2}`, expected: []string{`{"a":1}`, `{"a":2}`}},
+		{name: "mixed", input: `{"a":[1]}[2,{}]"x"true null`, expected: []string{`{"a":[1]}`, `[2,{}]`, `"x"`, `true`, `null`}},
+		{name: "whitespaces between", input: " [1]\n [2] \r\n3 4\t", expected: []string{`[1]`, `[2]`, `3`, `4`}},
+		{name: "joined numbers", input: `12`, expected: []string{`12`}},
+		{name: "strings", input: `"a""b"`, expected: []string{`"a"`, `"b"`}},
+		{name: "wrong value", input: `{"a":1}[1,]`, err: "wrong symbol ']' at 10"},
+		{name: "wrong close", input: `{}}`, err: "wrong symbol '}' at 2"},
+		{name: "unclosed", input: `[1][2`, err: "unexpected end of file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			roots, err := UnmarshalMany([]byte(test.input))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("UnmarshalMany() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalMany() unexpected error: %s", err)
+			}
+			result := make([]string, 0, len(roots))
+			for _, root := range roots {
+				if root.Parent() != nil {
+					t.Errorf("UnmarshalMany() root %s has a parent", root)
+				}
+				result = append(result, root.String())
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("UnmarshalMany() = %v, expected %v", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestPeekType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected NodeType
+		err      string
+	}{
+		{input: `{"a":1}`, expected: Object},
+		{input: " \r\n\t[1]", expected: Array},
+		{input: `"foo"`, expected: String},
+		{input: `true`, expected: Bool},
+		{input: `false`, expected: Bool},
+		{input: `null`, expected: Null},
+		{input: `-1`, expected: Numeric},
+		{input: `0.5`, expected: Numeric},
+		{input: `{"not validated"`, expected: Object},
+		{input: ``, err: "unexpected end of file"},
+		{input: " \n", err: "unexpected end of file"},
+		{input: ` }`, err: "wrong symbol '}' at 1"},
+		{input: `+1`, err: "wrong symbol '+' at 0"},
+		{input: `'a'`, err: "wrong symbol ''' at 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := PeekType([]byte(test.input))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("PeekType() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PeekType() unexpected error: %s", err)
+			}
+			if result != test.expected {
+				t.Errorf("PeekType() = %v, expected %v", result, test.expected)
+			}
+		})
+	}
+}
+
+// countdownContext is cancelled after the given count of the Err calls
+type countdownContext struct {
+	context.Context
+	count int
+}
+
+func (c *countdownContext) Err() error {
+	if c.count--; c.count < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestUnmarshalContext(t *testing.T) {
+	data := []byte("[" + strings.Repeat(`{"a":[1,"b",true]},`, 100000) + "null]")
+	root, err := UnmarshalContext(context.Background(), data)
+	if err != nil {
+		t.Fatalf("UnmarshalContext() unexpected error: %s", err)
+	}
+	if size := root.Size(); size != 100001 {
+		t.Errorf("UnmarshalContext() size = %d", size)
+	}
+
+	ctx := &countdownContext{Context: context.Background(), count: 10}
+	root, err = UnmarshalContext(ctx, data)
+	if err != context.Canceled {
+		t.Errorf("UnmarshalContext() error = %v, expected %v", err, context.Canceled)
+	}
+	if root != nil {
+		t.Errorf("UnmarshalContext() root = %s, expected nil", root)
+	}
+	if ctx.count != -1 {
+		t.Errorf("UnmarshalContext() checked context %d times after cancel", -1-ctx.count)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = UnmarshalContext(cancelled, []byte(`{}`)); err != context.Canceled {
+		t.Errorf("UnmarshalContext() error = %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestUnmarshal_strict(t *testing.T) {
+	tests := []struct {
+		input string
+		err   ErrorType
+		index int
+	}{
+		{input: `01`, err: WrongSymbol, index: 1},
+		{input: `-01`, err: WrongSymbol, index: 2},
+		{input: `00`, err: WrongSymbol, index: 1},
+		{input: `[0, 01]`, err: WrongSymbol, index: 5},
+		{input: `1.`, err: WrongSymbol, index: 0},
+		{input: `[1.]`, err: WrongSymbol, index: 3},
+		{input: `1.e1`, err: WrongSymbol, index: 2},
+		{input: `1e`, err: WrongSymbol, index: 0},
+		{input: `1e+`, err: WrongSymbol, index: 0},
+		{input: `[1e]`, err: WrongSymbol, index: 3},
+		{input: `[1E-]`, err: WrongSymbol, index: 4},
+		{input: `-`, err: WrongSymbol, index: 0},
+		{input: `--1`, err: WrongSymbol, index: 1},
+		{input: `+1`, err: WrongSymbol, index: 0},
+		{input: `.1`, err: WrongSymbol, index: 0},
+		{input: `1.5.2`, err: WrongSymbol, index: 3},
+		{input: `0x1`, err: WrongSymbol, index: 1},
+		{input: `[1,]`, err: WrongSymbol, index: 3},
+		{input: `{"a":1,}`, err: WrongSymbol, index: 7},
+		{input: "\"a\tb\"", err: WrongSymbol, index: 2},
+		{input: `"\x"`, err: WrongSymbol, index: 2},
+		{input: `"\u12"`, err: WrongSymbol, index: 5},
+		{input: `'a'`, err: WrongSymbol, index: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			root, err := Unmarshal([]byte(test.input))
+			if err == nil {
+				t.Fatalf("Unmarshal() expected error, got %s", root)
+			}
+			var typed Error
+			if !errors.As(err, &typed) {
+				t.Fatalf("Unmarshal() error = %#v, expected Error", err)
+			}
+			if typed.Type != test.err || typed.Index != test.index {
+				t.Errorf("Unmarshal() error = %d at %d (%s), expected %d at %d", typed.Type, typed.Index, err, test.err, test.index)
+			}
+		})
+	}
+	for _, input := range []string{`0`, `-0`, `0.0e-0`, `1E5`, `1e05`, `-1.5e+10`, `[0,-0.0]`} {
+		if _, err := Unmarshal([]byte(input)); err != nil {
+			t.Errorf("Unmarshal(%s) unexpected error: %s", input, err)
+		}
+	}
+}
+
+func TestUnmarshalWithOptions_InternKeys(t *testing.T) {
+	data := []byte(`[{"sub_field":1,"ab":2},{"sub_field":3,"ab":4},{"sub_field":{"sub_field":5}}]`)
+	root, err := UnmarshalWithOptions(data, InternKeys())
+	if err != nil {
+		t.Fatalf("UnmarshalWithOptions() unexpected error: %s", err)
+	}
+	if result, err := Marshal(root); err != nil || string(result) != string(data) {
+		t.Errorf("Marshal() = %s, %v", result, err)
+	}
+	first := root.MustIndex(0).MustKey("sub_field")
+	for _, node := range root.FindKey("sub_field") {
+		if node.key != first.key {
+			t.Errorf("key of %s is not interned", node.Path())
+		}
+	}
+	if root.MustIndex(0).MustKey("ab").key != root.MustIndex(1).MustKey("ab").key {
+		t.Errorf("escaped key is not interned")
+	}
+
+	// changes of the keys don't affect other nodes
+	node, err := root.MustIndex(0).PopKey("sub_field")
+	if err != nil {
+		t.Fatalf("PopKey() unexpected error: %s", err)
+	}
+	if err = root.MustIndex(1).AppendObject("other", node); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	if key := root.MustIndex(1).MustKey("sub_field").Key(); key != "sub_field" {
+		t.Errorf("Key() = %s", key)
+	}
+	if key := node.Key(); key != "other" {
+		t.Errorf("Key() = %s", key)
+	}
+
+	root = Must(Unmarshal(data))
+	if root.MustIndex(0).MustKey("sub_field").key == root.MustIndex(1).MustKey("sub_field").key {
+		t.Errorf("keys are interned without the option")
+	}
+}
+
+func BenchmarkUnmarshal_InternKeys(b *testing.B) {
+	data := []byte("[" + strings.Repeat(`{"sub_field":1,"sub_field2":"value","sub_field3":[true]},`, 10000) + "{}]")
+	b.Run("default", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Unmarshal(data); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.Run("InternKeys", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := UnmarshalWithOptions(data, InternKeys()); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func TestUnmarshal_BOM(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		value  string
+		offset int
+		err    string
+	}{
+		{name: "without BOM", data: `{"a":1}`, value: `{"a":1}`, offset: 0},
+		{name: "with BOM", data: "\xEF\xBB\xBF" + `{"a":1}`, value: `{"a":1}`, offset: 3},
+		{name: "BOM and whitespaces", data: "\xEF\xBB\xBF \r\n\t[1] \n", value: `[1]`, offset: 7},
+		{name: "BOM and scalar", data: "\xEF\xBB\xBF\"foo\"", value: `"foo"`, offset: 3},
+		{name: "only BOM", data: "\xEF\xBB\xBF", err: "unexpected end of file"},
+		{name: "BOM and whitespaces only", data: "\xEF\xBB\xBF  ", err: "unexpected end of file"},
+		{name: "error offset with BOM", data: "\xEF\xBB\xBF" + `{"a":}`, err: "wrong symbol '}' at 8"},
+		{name: "error offset without BOM", data: `  {"a":}`, err: "wrong symbol '}' at 7"},
+		{name: "double BOM", data: "\xEF\xBB\xBF\xEF\xBB\xBF1", err: "wrong symbol '\xEF' at 3"},
+		{name: "BOM after whitespace", data: " \xEF\xBB\xBF1", err: "wrong symbol '\xEF' at 1"},
+		{name: "partial BOM", data: "\xEF\xBB1", err: "wrong symbol '\xEF' at 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := Unmarshal([]byte(test.data))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Unmarshal() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error: %s", err)
+			}
+			if value := string(root.Source()); value != test.value {
+				t.Errorf("Source() = %s, expected %s", value, test.value)
+			}
+			if start, _, _ := root.Range(); start != test.offset {
+				t.Errorf("Range() start = %d, expected %d", start, test.offset)
+			}
+			if kind, err := PeekType([]byte(test.data)); err != nil || kind != root.Type() {
+				t.Errorf("PeekType() = %v, %v", kind, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_BOM_formatting(t *testing.T) {
+	root := Must(Unmarshal([]byte("\xEF\xBB\xBF\n{\"a\": 1}\n")))
+	if err := root.AppendObject("b", NumericNode("", 2)); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	result, err := MarshalWithOptions(root, PreserveFormatting())
+	if err != nil {
+		t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+	}
+	if string(result) != "\n{\"a\": 1,\"b\": 2}\n" {
+		t.Errorf("MarshalWithOptions() = %q", result)
+	}
+	roots, err := UnmarshalMany([]byte("\xEF\xBB\xBF1 2"))
+	if err != nil || len(roots) != 2 {
+		t.Errorf("UnmarshalMany() = %v, %v", roots, err)
+	}
+}
+
+func TestUnmarshalWithOptions_BigNumbers(t *testing.T) {
+	data := []byte(`{"id":12345678901234567890123,"small":42,"price":0.1,"precise":1.000000000000000000001,"huge":1e400,"tiny":-2.5e-400,"limit":1e5000}`)
+	root, err := UnmarshalWithOptions(data, BigNumbers())
+	if err != nil {
+		t.Fatalf("UnmarshalWithOptions() unexpected error: %s", err)
+	}
+	tests := []struct {
+		key   string
+		exact bool
+		text  string
+	}{
+		{key: "id", exact: true, text: "12345678901234567890123"},
+		{key: "small", exact: false},
+		{key: "price", exact: false},
+		{key: "precise", exact: true, text: "1.000000000000000000001"},
+		{key: "huge", exact: true, text: "1" + strings.Repeat("0", 400)},
+		{key: "tiny", exact: true, text: "-0." + strings.Repeat("0", 399) + "25"},
+		{key: "limit", exact: false},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			node := root.MustKey(test.key)
+			if (node.exact != nil) != test.exact {
+				t.Fatalf("exact = %v, expected %v", node.exact, test.exact)
+			}
+			if test.exact {
+				if value := decimalString(node.exact); value != test.text {
+					t.Errorf("decimalString() = %s, expected %s", value, test.text)
+				}
+			}
+		})
+	}
+	if err = root.CompactSource(); err == nil {
+		t.Errorf("CompactSource() expected error for the number out of range, which isn't kept")
+	}
+	if err = root.DeleteKey("limit"); err != nil {
+		t.Fatalf("DeleteKey() unexpected error: %s", err)
+	}
+	if err = root.CompactSource(); err != nil {
+		t.Fatalf("CompactSource() unexpected error: %s", err)
+	}
+	if value, ok := root.MustKey("id").BigInt(); !ok || value.String() != "12345678901234567890123" {
+		t.Errorf("BigInt() = %v, %v", value, ok)
+	}
+	result, err := Marshal(Must(root.Select("id", "small", "precise")))
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if string(result) != `{"id":12345678901234567890123,"small":42,"precise":1.000000000000000000001}` {
+		t.Errorf("Marshal() = %s", result)
+	}
+	if result, err = Marshal(root); err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if size, err := root.MarshalSize(); err != nil || size != len(result) {
+		t.Errorf("MarshalSize() = %d, %v, expected %d", size, err, len(result))
+	}
+
+	root = Must(Unmarshal(data))
+	if err = root.DeleteKey("limit"); err != nil {
+		t.Fatalf("DeleteKey() unexpected error: %s", err)
+	}
+	if err = root.CompactSource(); err == nil {
+		t.Errorf("CompactSource() expected error for the number out of range without BigNumbers")
+	}
+}
+
+func TestUnmarshalWithOptions_RejectOutOfRange(t *testing.T) {
+	tests := []struct {
+		value string
+		err   string
+		index int
+	}{
+		{value: `[0, -0, 0.0, 0e1000, -0.000e-1000, 1.5, -2e10]`},
+		{value: `1.7976931348623157e308`},
+		{value: `-1.7976931348623157e308`},
+		{value: `4.9e-324`},
+		{value: `5e-324`},
+		{value: `-5e-324`},
+		{value: `3e-324`},
+		{value: `2.2250738585072014e-308`},
+		{value: `1e1000`, err: "wrong symbol '1' at 0", index: 0},
+		{value: `-1e1000`, err: "wrong symbol '-' at 0", index: 0},
+		{value: `1e-1000`, err: "wrong symbol '1' at 0", index: 0},
+		{value: `-0.001e-1000`, err: "wrong symbol '-' at 0", index: 0},
+		{value: `1.7976931348623159e308`, err: "wrong symbol '1' at 0", index: 0},
+		{value: `2e-324`, err: "wrong symbol '2' at 0", index: 0},
+		{value: `{"a": [1, 1E+309]}`, err: "wrong symbol '1' at 10", index: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if _, err := Unmarshal([]byte(test.value)); err != nil {
+				t.Errorf("Unmarshal() unexpected error: %s", err)
+			}
+			root, err := UnmarshalWithOptions([]byte(test.value), RejectOutOfRange())
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("UnmarshalWithOptions() unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("UnmarshalWithOptions() expected error, got %s", root)
+			} else if err.Error() != test.err {
+				t.Errorf("UnmarshalWithOptions() error = %s, expected %s", err, test.err)
+			} else if typed, ok := err.(Error); !ok || typed.Type != WrongSymbol || typed.Index != test.index {
+				t.Errorf("UnmarshalWithOptions() error = %#v, expected WrongSymbol at %d", err, test.index)
+			} else if !errors.Is(err, strconv.ErrRange) || !strings.HasSuffix(typed.Message, " out of range") {
+				t.Errorf("UnmarshalWithOptions() error = %#v, expected %v", err, strconv.ErrRange)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_outOfRange(t *testing.T) {
+	data := []byte(`{"huge":1e1000,"tiny":1e-1000}`)
+	root := Must(UnmarshalWithOptions(data, BigNumbers()))
+	if _, err := root.MustKey("huge").GetNumeric(); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("GetNumeric() error = %v, expected %v", err, strconv.ErrRange)
+	}
+	if value, err := root.MustKey("tiny").GetNumeric(); err != nil || value != 0 {
+		t.Errorf("GetNumeric() = %v, %v, expected 0", value, err)
+	}
+	if value, ok := root.MustKey("huge").BigFloat(); !ok || value.Text('g', 5) != "1e+1000" {
+		t.Errorf("BigFloat() = %v, %v", value, ok)
+	}
+	if value, ok := root.MustKey("tiny").BigFloat(); !ok || value.Text('g', 5) != "1e-1000" {
+		t.Errorf("BigFloat() = %v, %v", value, ok)
+	}
+	if result, err := Marshal(root); err != nil || string(result) != string(data) {
+		t.Errorf("Marshal() = %s, %v, expected %s", result, err, data)
+	}
+}
+
 func TestUnmarshal_Must(t *testing.T) {
 	root, err := Unmarshal(jsonExample)
 	if err != nil {
@@ -651,6 +1428,25 @@ func BenchmarkUnmarshal_AJSON(b *testing.B) {
 	}
 }
 
+func BenchmarkUnmarshal_AJSON_readOnly(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		root, err := UnmarshalWithOptions(jsonExample, ReadOnly())
+		if err != nil {
+			b.Fatalf("Error on Unmarshal: %s", err)
+		}
+		nodes, err := root.JSONPath("$..book[?(@.price < 10)].title")
+		if err != nil || len(nodes) != 2 {
+			b.Fatalf("Error on JSONPath: %v", err)
+		}
+		for _, node := range nodes {
+			if _, err = node.GetString(); err != nil {
+				b.Fatalf("Error on GetString: %s", err)
+			}
+		}
+	}
+}
+
 func BenchmarkUnmarshal_JSON(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		root := new(storeExample)
@@ -2893,3 +3689,32 @@ line"]`)},
 		})
 	}
 }
+
+func TestUnescapeString(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "empty", value: `""`, expected: ""},
+		{name: "simple", value: `"foo"`, expected: "foo"},
+		{name: "escaped", value: `"\"foo\"\nА😹\/"`, expected: "\"foo\"\nА😹/"},
+		{name: "blank", value: ``, wantErr: true},
+		{name: "not a string", value: `foo`, wantErr: true},
+		{name: "unclosed", value: `"foo`, wantErr: true},
+		{name: "trailing data", value: `"foo" `, wantErr: true},
+		{name: "wrong escape", value: `"\x"`, wantErr: true},
+		{name: "control symbol", value: "\"\n\"", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := UnescapeString([]byte(test.value))
+			if (err != nil) != test.wantErr {
+				t.Errorf("UnescapeString() error = %v, wantErr %v", err, test.wantErr)
+			} else if value != test.expected {
+				t.Errorf("UnescapeString() = %q, expected %q", value, test.expected)
+			}
+		})
+	}
+}
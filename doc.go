@@ -6,6 +6,7 @@
 //
 // Each Node has it's own type and calculated value, which will be calculated on demand.
 // Calculated value saves in atomic.Value, so it's thread safe.
+// Use Node.Freeze to make the tree read-only, before sharing it between goroutines: all mutations of the frozen node return an error.
 //
 // Method JSONPath will returns slice of founded elements in current JSON data, by it's JSONPath.
 //
@@ -46,6 +47,15 @@
 //    ?()     applies a filter (script) expression.
 //    ()      script expression, using the underlying script engine.
 //
+// Inside the script expressions the identifier @index can be used to get the index of the current element, it is only
+// meaningful for the children of an Array, for any other element @index will be null.
+//
+// The JSONPath can be applied to any node of the tree, not only to the root: in this case $ refers to the given node,
+// so the recursive descent, the wildcards and the $ inside the script expressions are scoped to its subtree.
+//
+// Comparison with a missing value is always false: @.key == null is true only if the key exists and its value is null,
+// @.key != null is true only if the key exists and its value is not null.
+//
 //
 // JSONPath Script engine
 //
@@ -114,6 +124,11 @@
 //
 // Package has several predefined functions. You are free to add new one with AddFunction
 //
+// Function could be called for the path in the method style, i.e. `@.price.isNumber()` is the same as `isNumber(@.price)`.
+//
+// String values could be filtered by the methods with the string literal argument: `startsWith`, `endsWith` and
+// `contains`, i.e. `@.name.startsWith('At')`. The result is false for non-string values.
+//
 //     abs          math.Abs          integers, floats
 //     acos         math.Acos         integers, floats
 //     acosh        math.Acosh        integers, floats
@@ -139,6 +154,12 @@
 //     factorial    N!                unsigned integer
 //     floor        math.Floor        integers, floats
 //     gamma        math.Gamma        integers, floats
+//     isArray      type check        any
+//     isBool       type check        any
+//     isNull       type check        any
+//     isNumber     type check        any
+//     isObject     type check        any
+//     isString     type check        any
 //     j0           math.J0           integers, floats
 //     j1           math.J1           integers, floats
 //     length       len               array
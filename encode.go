@@ -1,12 +1,689 @@
 package ajson
 
 import (
+	"io"
+	"math"
+	"sort"
 	"strconv"
 )
 
+// MarshalOption is a functional option to configure MarshalWithOptions behaviour
+type MarshalOption func(options *marshalOptions)
+
+type marshalOptions struct {
+	nonFiniteAsNull    bool
+	sortKeys           bool
+	preserveFormatting bool
+	omitNull           bool
+	omitNullElements   bool
+	floatFormat        byte
+	floatPrecision     int
+	indent             func(depth int) string
+	indents            []string // prefixes of the lines by the depth, built from the indent function
+	depth              int      // depth of the marshaled node, for the indentation
+	sortArrays         map[string]string
+	sortBy             map[*Node]string // arrays found by the SortArraysBy paths, with the keys to sort them by
+	canonical          bool
+	noHTMLEscape       bool
+	checkBorders       bool
+}
+
+// marshalState is the state of one marshaling call, it's created for each call and never shared, unlike the options
+type marshalState struct {
+	visiting map[*Node]bool // containers on the current marshal path, to detect cycles
+}
+
+// NonFiniteAsNull makes Marshal to encode NaN, +Inf and -Inf numeric values as null, instead of returning an error
+func NonFiniteAsNull() MarshalOption {
+	return func(options *marshalOptions) {
+		options.nonFiniteAsNull = true
+	}
+}
+
+// EscapeString returns the string as a JSON string literal, quoted and escaped the same way as Marshal does
+func EscapeString(s string) []byte {
+	result := make([]byte, 0, len(s)+2)
+	result = append(result, quotes)
+	result = append(result, quoteString(s, true)...)
+	return append(result, quotes)
+}
+
+// SortKeys makes Marshal to encode keys of the objects sorted, recursively. The tree itself stays untouched.
+func SortKeys() MarshalOption {
+	return func(options *marshalOptions) {
+		options.sortKeys = true
+	}
+}
+
+// PreserveFormatting makes Marshal to keep the original formatting of the parsed data: whitespaces, order of keys and
+// whitespaces around the root value. Unmodified data is reproduced byte-for-byte, modified containers keep the layout
+// of the unmodified members, new members are added to the end. SortKeys, OmitNull and OmitNullElements options take
+// precedence over this one.
+func PreserveFormatting() MarshalOption {
+	return func(options *marshalOptions) {
+		options.preserveFormatting = true
+	}
+}
+
+// OmitNull makes Marshal to skip members of the objects with null values, recursively. Null elements of the arrays
+// are kept, use OmitNullElements to skip them too. The tree itself stays untouched.
+func OmitNull() MarshalOption {
+	return func(options *marshalOptions) {
+		options.omitNull = true
+	}
+}
+
+// OmitNullElements makes Marshal to skip null elements of the arrays, recursively. The tree itself stays untouched.
+func OmitNullElements() MarshalOption {
+	return func(options *marshalOptions) {
+		options.omitNullElements = true
+	}
+}
+
+// FloatFormat makes Marshal to encode the values of the modified Numeric nodes with strconv.FormatFloat, using the given
+// format and precision, e.g. FloatFormat('f', 2) encodes 0.1+0.2 as 0.30. Only 'e', 'E', 'f', 'g' and 'G' formats
+// produce a valid JSON, for other formats an error will be returned. Parsed and unmodified values are encoded from
+// the source as is. By default, values are encoded with the 'g' format and the precision -1.
+func FloatFormat(format byte, precision int) MarshalOption {
+	return func(options *marshalOptions) {
+		options.floatFormat = format
+		options.floatPrecision = precision
+	}
+}
+
+// Canonical makes Marshal to encode the value in the canonical form, the same as Node.Normalize writes to the source:
+// without whitespaces, with sorted keys, numbers in the shortest form which keeps their exact value and minimally
+// escaped strings. Equal values are encoded to the same bytes. The tree itself stays untouched.
+func Canonical() MarshalOption {
+	return func(options *marshalOptions) {
+		options.sortKeys = true
+		options.canonical = true
+	}
+}
+
+// DisableHTMLEscape makes Marshal to keep the characters <, > and & in the encoded strings and keys as is. By default,
+// they are escaped as \u003c, \u003e and \u0026, so the result is safe to embed into HTML, as in encoding/json.
+// Unmodified strings are copied from the source as is.
+func DisableHTMLEscape() MarshalOption {
+	return func(options *marshalOptions) {
+		options.noHTMLEscape = true
+	}
+}
+
+// CheckBorders makes Marshal to check the borders of the unmodified nodes against their source data before encoding,
+// as Node.Validate does, and return the PathError of the first broken node. It's a debug assertion for the trees,
+// changed by hand, it costs one more walk of the tree. Without it, the broken borders are reported, if at all, as
+// the Unparsed error of the node, or corrupt the result silently.
+func CheckBorders() MarshalOption {
+	return func(options *marshalOptions) {
+		options.checkBorders = true
+	}
+}
+
+// escapeHTML returns true, if the characters <, > and & should be escaped in the encoded strings
+func (o *marshalOptions) escapeHTML() bool {
+	return !o.noHTMLEscape && !o.canonical
+}
+
+// Indent makes Marshal to put every element of the arrays and every member of the objects on a new line, indented
+// with the given string once per nesting level, e.g. Indent("  ") or Indent("\t"). Empty containers stay on one line.
+// The tree itself stays untouched.
+func Indent(indent string) MarshalOption {
+	return IndentFunc(func(int) string {
+		return indent
+	})
+}
+
+// IndentFunc is the same as Indent, but the indentation of every nesting level is returned by indentFor: the line on
+// the depth N is prefixed with indentFor(0) + ... + indentFor(N-1), where depth 0 is the level of the root value.
+// It allows to use different styles for different levels, e.g. two spaces on the top levels and tabs deeper:
+//
+//	result, err := ajson.MarshalWithOptions(root, ajson.IndentFunc(func(depth int) string {
+//		if depth < 2 {
+//			return "  "
+//		}
+//		return "\t"
+//	}))
+func IndentFunc(indentFor func(depth int) string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.indent = indentFor
+		options.indents = nil
+	}
+}
+
+// SortArraysBy makes Marshal to sort the elements of the arrays, found by the JSONPath keys of the rules, by the values
+// of the given members of the elements, e.g. SortArraysBy(map[string]string{"$.users": "id"}). Paths are evaluated
+// for the marshaled node. Values are compared by their types first, in the order of NodeType constants, then numbers
+// and strings by their values, false goes before true. Elements without the member (including non-Object elements) go
+// last, sorting is stable, so equal elements keep their order. The tree itself stays untouched.
+func SortArraysBy(rules map[string]string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.sortArrays = rules
+	}
+}
+
+// prepare checks the borders with the CheckBorders option and evaluates the paths of the SortArraysBy option for the
+// marshaled node
+func (o *marshalOptions) prepare(node *Node) error {
+	if o.checkBorders {
+		if err := node.validateBorders(); err != nil {
+			return err
+		}
+	}
+	if len(o.sortArrays) == 0 {
+		return nil
+	}
+	o.sortBy = make(map[*Node]string)
+	for path, key := range o.sortArrays {
+		nodes, err := node.JSONPath(path)
+		if err != nil {
+			return err
+		}
+		for _, array := range nodes {
+			if !array.IsArray() {
+				continue
+			}
+			if current, ok := o.sortBy[array]; ok && current != key {
+				return errorRequest("array '%s' should be sorted by different keys", array.Path())
+			}
+			o.sortBy[array] = key
+		}
+	}
+	return nil
+}
+
+// elements returns the keys of the elements of the array in the marshal order, or nil to marshal all elements as is
+func (o *marshalOptions) elements(node *Node) []string {
+	by, sorted := o.sortBy[node]
+	if !sorted && !o.omitNullElements {
+		return nil
+	}
+	keys := make([]string, 0, len(node.children))
+	for i := 0; i < len(node.children); i++ {
+		key := strconv.Itoa(i)
+		if child, ok := node.children[key]; !ok || !o.omitNullElements || !child.IsNull() {
+			keys = append(keys, key)
+		}
+	}
+	if sorted {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return lessMember(node.children[keys[i]], node.children[keys[j]], by)
+		})
+	}
+	return keys
+}
+
+// lessMember returns true, if the member key of the left Object goes before the same member of the right one
+func lessMember(left, right *Node, key string) bool {
+	lvalue, lok := left.member(key)
+	rvalue, rok := right.member(key)
+	if !lok || !rok {
+		return lok && !rok
+	}
+	if lvalue._type != rvalue._type {
+		return lvalue._type < rvalue._type
+	}
+	switch lvalue._type {
+	case Numeric:
+		lnum, lerr := lvalue.GetNumeric()
+		rnum, rerr := rvalue.GetNumeric()
+		return lerr == nil && rerr == nil && lnum < rnum
+	case String:
+		lstr, lerr := lvalue.GetString()
+		rstr, rerr := rvalue.GetString()
+		return lerr == nil && rerr == nil && lstr < rstr
+	case Bool:
+		lbool, lerr := lvalue.GetBool()
+		rbool, rerr := rvalue.GetBool()
+		return lerr == nil && rerr == nil && !lbool && rbool
+	}
+	return false
+}
+
+// member returns the child of the Object node by the key, false will be returned for non Object node
+func (n *Node) member(key string) (*Node, bool) {
+	if n == nil || n._type != Object {
+		return nil, false
+	}
+	child, ok := n.children[key]
+	return child, ok
+}
+
+// newline appends the line break and the indentation of the depth, if the Indent option is set
+func (o *marshalOptions) newline(result []byte, depth int) []byte {
+	if o.indent == nil {
+		return result
+	}
+	if o.indents == nil {
+		o.indents = []string{""}
+	}
+	for len(o.indents) <= depth {
+		last := len(o.indents) - 1
+		o.indents = append(o.indents, o.indents[last]+o.indent(last))
+	}
+	result = append(result, skipN)
+	return append(result, o.indents[depth]...)
+}
+
+// formatFloat returns the encoded numeric value, with the format of the FloatFormat option
+func (o *marshalOptions) formatFloat(result []byte, value float64) ([]byte, error) {
+	switch o.floatFormat {
+	case 0:
+		return strconv.AppendFloat(result, value, 'g', -1, 64), nil
+	case 'e', 'E', 'f', 'g', 'G':
+		return strconv.AppendFloat(result, value, o.floatFormat, o.floatPrecision, 64), nil
+	}
+	return nil, errorRequest("unsupported float format '%c'", o.floatFormat)
+}
+
+// enter marks the container as being marshaled, error will be returned if it is already on the current path
+func (s *marshalState) enter(node *Node) error {
+	if s.visiting == nil {
+		s.visiting = make(map[*Node]bool)
+	}
+	if s.visiting[node] {
+		return errorRequest("cycle detected")
+	}
+	s.visiting[node] = true
+	return nil
+}
+
+// leave marks the container as marshaled
+func (s *marshalState) leave(node *Node) {
+	delete(s.visiting, node)
+}
+
+// rebuild returns true, if containers should be encoded from the children even if the source is available
+func (o *marshalOptions) rebuild() bool {
+	return o.sortKeys || o.omitNull || o.omitNullElements || o.indent != nil || len(o.sortBy) != 0 || o.canonical
+}
+
 // Marshal returns slice of bytes, marshaled from current value
+//
+// NaN, +Inf and -Inf numeric values have no representation in JSON, so Marshal returns an error for them.
+// Mutation methods never create a cycle in the tree, but if it exists anyway, Marshal returns an error for it.
+//
+// Marshal uses an explicit stack instead of the recursion, so very deep trees don't overflow the goroutine stack.
+// Use MaxDepth option of UnmarshalWithOptions to reject such trees on parsing.
+//
+// Use MarshalWith or MarshalWithOptions to configure the output: indentation, order of keys, etc.
 func Marshal(node *Node) (result []byte, err error) {
+	return MarshalWithOptions(node)
+}
+
+// MarshalIndent is like Marshal, but every element of the arrays and every member of the objects is placed on a new
+// line, indented with the given string once per nesting level. Use IndentFunc option of MarshalWithOptions to set
+// the indentation of every level separately.
+func MarshalIndent(node *Node, indent string) (result []byte, err error) {
+	return MarshalWithOptions(node, Indent(indent))
+}
+
+// MarshalSize returns the length of the result of Marshal for the node, without building it. Unmodified nodes are
+// counted by the length of their source, so it's cheap for the parsed trees with a few changes.
+func (n *Node) MarshalSize() (size int, err error) {
+	var (
+		stack = []sizeFrame{{node: n}}
+		frame sizeFrame
+		node  *Node
+		state = new(marshalState)
+		buf   [32]byte
+	)
+	for len(stack) != 0 {
+		frame, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		node = frame.node
+		if frame.leave {
+			state.leave(node)
+			continue
+		}
+		if node == nil {
+			return 0, errorUnparsed()
+		}
+		if !node.dirty {
+			if !node.ready() {
+				return 0, errorUnparsed()
+			}
+			size += node.borders[1] - node.borders[0]
+			continue
+		}
+		switch node._type {
+		case Null:
+			size += len(_null)
+		case Numeric:
+			if node.exact != nil {
+				size += len(decimalString(node.exact))
+				break
+			}
+			value, err := node.GetNumeric()
+			if err != nil {
+				return 0, err
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return 0, errorRequest("unsupported numeric value '%v'", value)
+			}
+			size += len(strconv.AppendFloat(buf[:0], value, 'g', -1, 64))
+		case String:
+			value, err := node.GetString()
+			if err != nil {
+				return 0, err
+			}
+			size += quotedLength(value, true) + 2
+		case Bool:
+			value, err := node.GetBool()
+			if err != nil {
+				return 0, err
+			}
+			if value {
+				size += len(_true)
+			} else {
+				size += len(_false)
+			}
+		case Array, Object:
+			if err = state.enter(node); err != nil {
+				return 0, err
+			}
+			stack = append(stack, sizeFrame{node: node, leave: true})
+			count := 0
+			if node._type == Array {
+				for i := 0; i < len(node.children); i++ {
+					child, ok := node.children[strconv.Itoa(i)]
+					if !ok {
+						return 0, errorRequest("wrong length of array")
+					}
+					stack = append(stack, sizeFrame{node: child})
+					count++
+				}
+			} else {
+				for key, child := range node.children {
+					children := []*Node{child}
+					if list, ok := node.duplicates[key]; ok {
+						children = list
+					}
+					for _, child := range children {
+						size += quotedLength(key, true) + 3 // quotes and colon
+						stack = append(stack, sizeFrame{node: child})
+						count++
+					}
+				}
+			}
+			size += 2 // brackets
+			if count > 1 {
+				size += count - 1 // commas
+			}
+		}
+	}
+	return size, nil
+}
+
+// sizeFrame is the step of MarshalSize: the node to count, or the container to leave after all of its children
+type sizeFrame struct {
+	node  *Node
+	leave bool
+}
+
+// MarshalStyle is the way of encoding of the values, which are not changed since parsing, see MarshalOptions
+type MarshalStyle int
+
+const (
+	// SourceStyle copies the unmodified values from the source as is, dropping whitespaces around the root value only
+	// and encoding the modified containers without whitespaces. It's the style of Marshal.
+	SourceStyle MarshalStyle = iota
+	// PreserveStyle keeps the original formatting of the source, see PreserveFormatting
+	PreserveStyle
+	// CanonicalStyle encodes every value in the canonical form, see Canonical
+	CanonicalStyle
+)
+
+// MarshalOptions are the options of MarshalWith, an alternative to the functional options of MarshalWithOptions.
+// Zero value encodes the node the same way as Marshal does.
+type MarshalOptions struct {
+	// Style of encoding of the unmodified values, SourceStyle by default
+	Style MarshalStyle
+	// Indent is the indentation of one nesting level, see Indent; no indentation by default
+	Indent string
+	// IndentFunc returns the indentation of the nesting level, see IndentFunc; it takes precedence over Indent
+	IndentFunc func(depth int) string
+	// SortKeys sorts the keys of the objects, see SortKeys; by default, keys of the modified objects are not ordered
+	SortKeys bool
+	// DisableHTMLEscape keeps <, > and & in the encoded strings, see DisableHTMLEscape; they are escaped by default
+	DisableHTMLEscape bool
+	// OmitNull skips the members of the objects with null values, see OmitNull
+	OmitNull bool
+	// OmitNullElements skips the null elements of the arrays, see OmitNullElements
+	OmitNullElements bool
+	// NonFiniteAsNull encodes NaN and Inf as null, see NonFiniteAsNull; an error is returned for them by default
+	NonFiniteAsNull bool
+	// FloatFormat and FloatPrecision are the format of the modified numbers, see FloatFormat; 'g' and -1 by default.
+	// Zero FloatPrecision of the 'g' and 'G' formats is -1 as well, the precision 0 is the same as 1 for them anyway.
+	FloatFormat    byte
+	FloatPrecision int
+	// SortArraysBy sorts the elements of the found arrays by the values of the members, see SortArraysBy
+	SortArraysBy map[string]string
+	// CheckBorders checks the borders of the unmodified nodes before encoding, see CheckBorders
+	CheckBorders bool
+}
+
+// options returns the functional options, which are the same as current ones
+func (o MarshalOptions) options() (result []MarshalOption) {
+	switch o.Style {
+	case PreserveStyle:
+		result = append(result, PreserveFormatting())
+	case CanonicalStyle:
+		result = append(result, Canonical())
+	}
+	if o.IndentFunc != nil {
+		result = append(result, IndentFunc(o.IndentFunc))
+	} else if o.Indent != "" {
+		result = append(result, Indent(o.Indent))
+	}
+	if o.SortKeys {
+		result = append(result, SortKeys())
+	}
+	if o.DisableHTMLEscape {
+		result = append(result, DisableHTMLEscape())
+	}
+	if o.OmitNull {
+		result = append(result, OmitNull())
+	}
+	if o.OmitNullElements {
+		result = append(result, OmitNullElements())
+	}
+	if o.NonFiniteAsNull {
+		result = append(result, NonFiniteAsNull())
+	}
+	if o.FloatFormat != 0 {
+		precision := o.FloatPrecision
+		if precision == 0 && (o.FloatFormat == 'g' || o.FloatFormat == 'G') {
+			precision = -1
+		}
+		result = append(result, FloatFormat(o.FloatFormat, precision))
+	}
+	if len(o.SortArraysBy) != 0 {
+		result = append(result, SortArraysBy(o.SortArraysBy))
+	}
+	if o.CheckBorders {
+		result = append(result, CheckBorders())
+	}
+	return result
+}
+
+// MarshalWith returns slice of bytes, marshaled from current value with the given options. It's the same as
+// MarshalWithOptions with the corresponding functional options, e.g.
+//
+//	result, err := ajson.MarshalWith(root, ajson.MarshalOptions{Indent: "  ", SortKeys: true})
+//
+// is the same as
+//
+//	result, err := ajson.MarshalWithOptions(root, ajson.Indent("  "), ajson.SortKeys())
+func MarshalWith(node *Node, options MarshalOptions) (result []byte, err error) {
+	return MarshalWithOptions(node, options.options()...)
+}
+
+// RoundTrip parses the data and encodes it back with the Canonical option, so every value is decoded and encoded
+// again, instead of being copied from the source. The result is the valid JSON, which is parsed to the tree equal
+// to the one of the data (as in Node.Eq), and RoundTrip of the result returns it unchanged. It's intended to be used
+// as a fuzz target, to check the parser and the encoder together, and to normalize the JSON data.
+func RoundTrip(data []byte) ([]byte, error) {
+	root, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalWithOptions(root, Canonical())
+}
+
+// MarshalWithOptions returns slice of bytes, marshaled from current value with the given options
+func MarshalWithOptions(node *Node, options ...MarshalOption) (result []byte, err error) {
+	opts := new(marshalOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	if err = opts.prepare(node); err != nil {
+		return nil, err
+	}
+	result, err = marshal(node, opts, new(marshalState))
+	if err != nil || !opts.preserveFormatting || opts.rebuild() {
+		return
+	}
+	return node.surround(result), nil
+}
+
+// MarshalArrayStream writes the Array node to w element by element, so only one element is marshaled in memory at a
+// time. Options are the same as for MarshalWithOptions. For non Array node WrongType error will be returned.
+func MarshalArrayStream(node *Node, w io.Writer, options ...MarshalOption) (err error) {
+	if node == nil {
+		return errorUnparsed()
+	}
+	if !node.IsArray() {
+		return errorType()
+	}
+	opts := new(marshalOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	if err = opts.prepare(node); err != nil {
+		return err
+	}
+	keys := opts.elements(node)
+	if keys == nil {
+		keys = make([]string, 0, len(node.children))
+		for i := 0; i < len(node.children); i++ {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	}
+	if _, err = w.Write([]byte{bracketL}); err != nil {
+		return err
+	}
+	opts.depth = 1
+	state := new(marshalState)
+	written := false
+	for _, key := range keys {
+		child, ok := node.children[key]
+		if !ok {
+			return errorRequest("wrong length of array")
+		}
+		value, err := marshal(child, opts, state)
+		if err != nil {
+			return err
+		}
+		separator := make([]byte, 0)
+		if written {
+			separator = append(separator, coma)
+		}
+		written = true
+		if _, err = w.Write(opts.newline(separator, 1)); err != nil {
+			return err
+		}
+		if _, err = w.Write(value); err != nil {
+			return err
+		}
+	}
+	closing := []byte{bracketR}
+	if written {
+		closing = append(opts.newline(nil, 0), bracketR)
+	}
+	_, err = w.Write(closing)
+	return err
+}
+
+// marshalFrame is the container being marshaled, frames are kept on the explicit stack instead of the recursion,
+// so the depth of the tree is not limited by the size of the goroutine stack
+type marshalFrame struct {
+	node  *Node
+	keys  []string // keys of the children in the marshal order, nil for all elements of the Array
+	nodes []*Node  // children of the Object in the marshal order, same length as keys
+	size  int      // count of the children
+	next  int      // position of the next child
+}
+
+func marshal(node *Node, options *marshalOptions, state *marshalState) (result []byte, err error) {
 	result = make([]byte, 0)
+	var (
+		stack []*marshalFrame
+		frame *marshalFrame
+		child *Node
+		ok    bool
+	)
+	for {
+		result, frame, err = marshalNode(result, node, options, state)
+		if err != nil {
+			return nil, err
+		}
+		if frame != nil {
+			stack = append(stack, frame)
+		}
+		for node = nil; node == nil && len(stack) != 0; {
+			frame = stack[len(stack)-1]
+			depth := options.depth + len(stack)
+			if frame.next == frame.size {
+				if frame.size != 0 {
+					result = options.newline(result, depth-1)
+				}
+				if frame.node._type == Array {
+					result = append(result, bracketR)
+				} else {
+					result = append(result, bracesR)
+				}
+				state.leave(frame.node)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if frame.next != 0 {
+				result = append(result, coma)
+			}
+			result = options.newline(result, depth)
+			if frame.node._type == Array {
+				if frame.keys == nil {
+					child, ok = frame.node.children[strconv.Itoa(frame.next)]
+				} else {
+					child, ok = frame.node.children[frame.keys[frame.next]]
+				}
+				if !ok {
+					return nil, errorRequest("wrong length of array")
+				}
+			} else {
+				key := frame.keys[frame.next]
+				child = frame.nodes[frame.next]
+				result = append(result, quotes)
+				result = append(result, quoteString(key, options.escapeHTML())...)
+				result = append(result, quotes, colon)
+				if options.indent != nil {
+					result = append(result, skipS)
+				}
+			}
+			frame.next++
+			if child == nil {
+				return nil, errorUnparsed()
+			}
+			node = child
+		}
+		if node == nil {
+			return result, nil
+		}
+	}
+}
+
+// marshalNode appends the encoded node to the result, for the modified container only the opening bracket is appended
+// and the frame is returned, to marshal its children
+func marshalNode(result []byte, node *Node, options *marshalOptions, state *marshalState) (_ []byte, frame *marshalFrame, err error) {
 	var (
 		sValue string
 		bValue bool
@@ -15,29 +692,74 @@ func Marshal(node *Node) (result []byte, err error) {
 	)
 
 	if node == nil {
-		return nil, errorUnparsed()
-	} else if node.dirty {
+		return nil, nil, errorUnparsed()
+	}
+	if node.isContainer() && (node.dirty || options.rebuild()) {
+		if err = state.enter(node); err != nil {
+			return nil, nil, err
+		}
+	}
+	if node.dirty && node.isContainer() && options.preserveFormatting && !options.rebuild() && node.formatted() &&
+		node.duplicates == nil {
+		oValue, err = marshalFormatted(node, options, state)
+		if err != nil {
+			return nil, nil, err
+		}
+		state.leave(node)
+		return append(result, oValue...), nil, nil
+	} else if node.dirty || (options.rebuild() && node.isContainer()) ||
+		(options.canonical && (node._type == Numeric || node._type == String)) {
 		switch node._type {
 		case Null:
 			result = append(result, _null...)
 		case Numeric:
+			if node.exact != nil {
+				result = append(result, decimalString(node.exact)...)
+				break
+			}
+			if options.canonical && !node.dirty {
+				if exact := exactNumber(node.Source()); exact != nil {
+					result = append(result, decimalString(exact)...)
+					break
+				}
+			}
 			nValue, err = node.GetNumeric()
+			if err != nil && options.canonical && !node.dirty {
+				// the exponent is too big to keep the exact value, see BigNumbers
+				result = append(result, node.Source()...)
+				err = nil
+				break
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if options.canonical && nValue == 0 {
+				result = append(result, '0')
+				break
+			}
+			if math.IsNaN(nValue) || math.IsInf(nValue, 0) {
+				if !options.nonFiniteAsNull {
+					return nil, nil, errorRequest("unsupported numeric value '%v'", nValue)
+				}
+				result = append(result, _null...)
+				break
+			}
+			result, err = options.formatFloat(result, nValue)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
-			result = append(result, strconv.FormatFloat(nValue, 'g', -1, 64)...)
 		case String:
 			sValue, err = node.GetString()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			result = append(result, quotes)
-			result = append(result, quoteString(sValue, true)...)
+			result = append(result, quoteString(sValue, options.escapeHTML())...)
 			result = append(result, quotes)
 		case Bool:
 			bValue, err = node.GetBool()
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			} else if bValue {
 				result = append(result, _true...)
 			} else {
@@ -45,46 +767,46 @@ func Marshal(node *Node) (result []byte, err error) {
 			}
 		case Array:
 			result = append(result, bracketL)
-			for i := 0; i < len(node.children); i++ {
-				if i != 0 {
-					result = append(result, coma)
-				}
-				child, ok := node.children[strconv.Itoa(i)]
-				if !ok {
-					return nil, errorRequest("wrong length of array")
-				}
-				oValue, err = Marshal(child)
-				if err != nil {
-					return nil, err
-				}
-				result = append(result, oValue...)
+			frame = &marshalFrame{node: node, size: len(node.children)}
+			if frame.keys = options.elements(node); frame.keys != nil {
+				frame.size = len(frame.keys)
 			}
-			result = append(result, bracketR)
 		case Object:
 			result = append(result, bracesL)
-			bValue = false
-			for key, child := range node.children {
-				if bValue {
-					result = append(result, coma)
-				} else {
-					bValue = true
+			keys := make([]string, 0, len(node.children))
+			for key := range node.children {
+				keys = append(keys, key)
+			}
+			if options.sortKeys {
+				sort.Strings(keys)
+			}
+			members := make([]*Node, 0, len(keys))
+			for _, key := range keys {
+				children := []*Node{node.children[key]}
+				if list, ok := node.duplicates[key]; ok {
+					children = list
 				}
-				result = append(result, quotes)
-				result = append(result, quoteString(key, true)...)
-				result = append(result, quotes, colon)
-				oValue, err = Marshal(child)
-				if err != nil {
-					return nil, err
+				for _, child := range children {
+					if !options.omitNull || !child.IsNull() {
+						members = append(members, child)
+					}
 				}
-				result = append(result, oValue...)
 			}
-			result = append(result, bracesR)
+			if node.duplicates != nil && !options.sortKeys {
+				// keep the repeated keys at their places in the source, not grouped together
+				sort.SliceStable(members, func(i, j int) bool {
+					return sourceBefore(members[i], members[j])
+				})
+			}
+			frame = &marshalFrame{node: node, keys: make([]string, len(members)), nodes: members, size: len(members)}
+			for i, child := range members {
+				frame.keys[i] = *child.key
+			}
 		}
 	} else if node.ready() {
 		result = append(result, node.Source()...)
 	} else {
-		return nil, errorUnparsed()
+		return nil, nil, errorUnparsed()
 	}
-
-	return
+	return result, frame, nil
 }
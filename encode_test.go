@@ -1,7 +1,14 @@
 package ajson
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -103,6 +110,26 @@ func TestMarshal_Primitive(t *testing.T) {
 func TestMarshal_Unparsed(t *testing.T) {
 	node := Must(Unmarshal([]byte(`{"foo":"bar"}`)))
 	node.borders[1] = 0 // broken borders
+	if err := node.validateBorders(); err == nil {
+		t.Errorf("expected borders error")
+	}
+	if err := node.Validate(); err == nil {
+		t.Errorf("expected validation error")
+	}
+	if _, err := MarshalWithOptions(node, CheckBorders()); err == nil || err.Error() != "$: wrong request: node is not dirty, but has no right border" {
+		t.Errorf("MarshalWithOptions(CheckBorders) error = %v", err)
+	}
+	if _, err := MarshalWith(node, MarshalOptions{CheckBorders: true}); err == nil {
+		t.Errorf("MarshalWith(CheckBorders) expected error")
+	}
+	if value, err := MarshalWithOptions(Must(Unmarshal([]byte(`{"foo":"bar"}`))), CheckBorders()); err != nil || string(value) != `{"foo":"bar"}` {
+		t.Errorf("MarshalWithOptions(CheckBorders) = %s, %v", value, err)
+	}
+	gapped := Must(Unmarshal([]byte(`[1,2]`)))
+	delete(gapped.children, "0")
+	if _, err := MarshalWithOptions(gapped, CheckBorders()); err == nil || err.Error() != "$: wrong request: node has element '1' with wrong index" {
+		t.Errorf("MarshalWithOptions(CheckBorders) error = %v", err)
+	}
 	_, err := Marshal(node)
 	if err == nil {
 		t.Errorf("expected error")
@@ -194,3 +221,1068 @@ func TestMarshal_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshal_NonFinite(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+	}{
+		{name: "+Inf", value: math.Inf(1)},
+		{name: "-Inf", value: math.Inf(-1)},
+		{name: "NaN", value: math.NaN()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := ArrayNode("", []*Node{NumericNode("", test.value)})
+			value, err := Marshal(node)
+			if err == nil {
+				t.Errorf("expected error, got: %s", value)
+			} else if err.Error() != "wrong request: unsupported numeric value '"+test.name+"'" {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			value, err = MarshalWithOptions(node, NonFiniteAsNull())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if string(value) != "[null]" {
+				t.Errorf("wrong result: '%s', expected '[null]'", value)
+			}
+		})
+	}
+}
+
+func TestNode_MarshalSize(t *testing.T) {
+	parsed := func(data string) func() *Node {
+		return func() *Node {
+			return Must(Unmarshal([]byte(data)))
+		}
+	}
+	tests := []struct {
+		name string
+		node func() *Node
+	}{
+		{name: "parsed", node: parsed(`{"a": [1, 2.50, "xA"], "b" : null}`)},
+		{name: "parsed scalar", node: parsed(` "foo" `)},
+		{name: "null", node: func() *Node { return NullNode("") }},
+		{name: "bool", node: func() *Node { return ArrayNode("", []*Node{BoolNode("", true), BoolNode("", false)}) }},
+		{name: "numeric", node: func() *Node {
+			return ArrayNode("", []*Node{NumericNode("", 0.1), NumericNode("", -1e21), NumericNode("", 1e-7), NumericNode("", 123456789)})
+		}},
+		{name: "escaped", node: func() *Node {
+			return ObjectNode("", map[string]*Node{
+				"a\"b\\c\n":    StringNode("", "<tag> & \t\r\n\x01\x1f \u2028\u2029 \xff é 😀"),
+				"\u2028<>":     StringNode("", ""),
+				"plain key 12": StringNode("", "plain value"),
+			})
+		}},
+		{name: "empty", node: func() *Node {
+			return ArrayNode("", []*Node{ArrayNode("", nil), ObjectNode("", nil), ArrayNode("", []*Node{NullNode("")})})
+		}},
+		{name: "modified", node: func() *Node {
+			root := Must(Unmarshal([]byte(`{"a": {"b": [1, 2]}, "c": {"d": "e"}, "f": "g"}`)))
+			_ = root.MustKey("a").MustKey("b").AppendArray(StringNode("", "h\"i"))
+			_ = root.MustKey("f").SetNumeric(1.5)
+			return root
+		}},
+		{name: "shared", node: func() *Node {
+			object := ObjectNode("", map[string]*Node{"a": ArrayNode("", []*Node{NumericNode("", 1)})})
+			return ArrayNode("", []*Node{object, object})
+		}},
+		{name: "duplicates", node: func() *Node {
+			root := Must(UnmarshalWithOptions([]byte(`{"a": 1, "a": [2], "b": 3}`), DuplicateKeys()))
+			_ = root.MustKey("b").SetNull()
+			return root
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := test.node()
+			result, err := Marshal(node)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			size, err := node.MarshalSize()
+			if err != nil {
+				t.Fatalf("MarshalSize() unexpected error: %s", err)
+			}
+			if size != len(result) {
+				t.Errorf("MarshalSize() = %d, expected %d for %s", size, len(result), result)
+			}
+		})
+	}
+}
+
+func TestNode_MarshalSize_error(t *testing.T) {
+	var node *Node
+	if _, err := node.MarshalSize(); err == nil {
+		t.Errorf("MarshalSize() expected error for nil")
+	}
+	node = ArrayNode("", []*Node{NumericNode("", math.NaN())})
+	if _, err := node.MarshalSize(); err == nil || err.Error() != "wrong request: unsupported numeric value 'NaN'" {
+		t.Errorf("MarshalSize() error = %v", err)
+	}
+	node = ObjectNode("", nil)
+	node.children["a"] = node
+	if _, err := node.MarshalSize(); err == nil || err.Error() != "wrong request: cycle detected" {
+		t.Errorf("MarshalSize() error = %v", err)
+	}
+}
+
+func TestMarshal_cycle(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":{"bar":[]}}`)))
+	bar := root.MustKey("foo").MustKey("bar")
+	// the cycle can't be created with the mutation methods, so it is made by hand
+	bar.children["0"] = root
+	bar.mark()
+
+	options := [][]MarshalOption{nil, {SortKeys()}, {PreserveFormatting()}}
+	for _, option := range options {
+		if value, err := MarshalWithOptions(root, option...); err == nil {
+			t.Errorf("Marshal() expected error, got %s", value)
+		} else if err.Error() != "wrong request: cycle detected" {
+			t.Errorf("Marshal() error = %v, expected cycle detected", err)
+		}
+	}
+
+	bar.children["0"] = NullNode("")
+	if value, err := MarshalWithOptions(root, SortKeys()); err != nil {
+		t.Errorf("Marshal() unexpected error: %s", err)
+	} else if string(value) != `{"foo":{"bar":[null]}}` {
+		t.Errorf("Marshal() = %s", value)
+	}
+}
+
+func TestMarshal_concurrent(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":[1,{"c":null}]},"d":[[]]}`)))
+	if err := root.MustKey("a").MustKey("b").AppendArray(ArrayNode("", nil)); err != nil {
+		t.Fatalf("AppendArray() unexpected error: %s", err)
+	}
+	expected := `{"a":{"b":[1,{"c":null},[]]},"d":[[]]}`
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if value, err := MarshalWithOptions(root, SortKeys()); err != nil || string(value) != expected {
+					t.Errorf("Marshal() = %s, %v", value, err)
+					return
+				}
+				if _, err := root.MarshalSize(); err != nil {
+					t.Errorf("MarshalSize() unexpected error: %s", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMarshal_deep(t *testing.T) {
+	const depth = 100000
+	// recursive Marshal of such a tree needs much more than 1MB of the stack, so it would crash the test
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	data := []byte(strings.Repeat(`[{"a":`, depth) + `null` + strings.Repeat(`}]`, depth))
+	root, err := UnmarshalWithOptions(data, MaxDepth(2*depth))
+	if err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %s", err)
+	}
+	result, err := MarshalWithOptions(root, SortKeys())
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if !bytes.Equal(result, data) {
+		t.Errorf("Marshal() result is not equal to the source")
+	}
+
+	node := NullNode("")
+	for i := 0; i < depth; i++ {
+		node = ArrayNode("", []*Node{node})
+	}
+	result, err = Marshal(node)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if expected := strings.Repeat(`[`, depth) + `null` + strings.Repeat(`]`, depth); string(result) != expected {
+		t.Errorf("Marshal() result is wrong")
+	}
+}
+
+func TestMarshalWithOptions_SortKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     func() *Node
+		expected string
+	}{
+		{
+			name: "parsed",
+			node: func() *Node {
+				return Must(Unmarshal([]byte(`{"c": {"z": 1, "a": [{"y": 2, "b": 3}, 1.0]}, "b": null, "a": "x"}`)))
+			},
+			expected: `{"a":"x","b":null,"c":{"a":[{"b":3,"y":2},1.0],"z":1}}`,
+		},
+		{
+			name: "created",
+			node: func() *Node {
+				return ObjectNode("", map[string]*Node{
+					"foo": NumericNode("", 1),
+					"bar": ArrayNode("", []*Node{
+						ObjectNode("", map[string]*Node{
+							"2": BoolNode("", true),
+							"1": BoolNode("", false),
+						}),
+					}),
+				})
+			},
+			expected: `{"bar":[{"1":false,"2":true}],"foo":1}`,
+		},
+		{
+			name: "scalar",
+			node: func() *Node {
+				return Must(Unmarshal([]byte(`1.50`)))
+			},
+			expected: `1.50`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := test.node()
+			source := node.Clone()
+			dirty := node.IsDirty()
+			value, err := MarshalWithOptions(node, SortKeys())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if string(value) != test.expected {
+				t.Errorf("wrong result: '%s', expected '%s'", value, test.expected)
+			}
+			if ok, err := node.Eq(source); err != nil || !ok || node.IsDirty() != dirty {
+				t.Errorf("tree was changed: '%s', expected '%s'", node, source)
+			}
+			value, err = MarshalWith(node, MarshalOptions{SortKeys: true})
+			if err != nil {
+				t.Errorf("MarshalWith() unexpected error: %s", err)
+			} else if string(value) != test.expected {
+				t.Errorf("MarshalWith() = '%s', expected '%s'", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalWithOptions_FloatFormat(t *testing.T) {
+	a, b := 0.1, 0.2 // not a constant expression, to be calculated in float64
+	tests := []struct {
+		name      string
+		value     float64
+		format    byte
+		precision int
+		expected  string
+		err       string
+	}{
+		{name: "default", value: a + b, expected: `0.30000000000000004`},
+		{name: "g", value: a + b, format: 'g', precision: 15, expected: `0.3`},
+		{name: "g shortest", value: 1e21, format: 'g', precision: -1, expected: `1e+21`},
+		{name: "G", value: 1e21, format: 'G', precision: 3, expected: `1E+21`},
+		{name: "f", value: a + b, format: 'f', precision: 2, expected: `0.30`},
+		{name: "f integer", value: 1234.5678, format: 'f', precision: 0, expected: `1235`},
+		{name: "f shortest", value: 1e21, format: 'f', precision: -1, expected: `1000000000000000000000`},
+		{name: "e", value: 1234.5678, format: 'e', precision: 3, expected: `1.235e+03`},
+		{name: "E", value: -0.00012, format: 'E', precision: -1, expected: `-1.2E-04`},
+		{name: "b", value: 1, format: 'b', precision: -1, err: "wrong request: unsupported float format 'b'"},
+		{name: "x", value: 1, format: 'x', precision: -1, err: "wrong request: unsupported float format 'x'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var options []MarshalOption
+			if test.format != 0 {
+				options = append(options, FloatFormat(test.format, test.precision))
+			}
+			result, err := MarshalWithOptions(ArrayNode("", []*Node{NumericNode("", test.value)}), options...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("MarshalWithOptions() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+			}
+			if string(result) != "["+test.expected+"]" {
+				t.Errorf("MarshalWithOptions() = %s, expected [%s]", result, test.expected)
+			}
+			if _, err = Unmarshal(result); err != nil {
+				t.Errorf("Unmarshal() unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestMarshalWithOptions_FloatFormat_source(t *testing.T) {
+	a, b := 0.1, 0.2
+	root := Must(Unmarshal([]byte(`[0.30000000000000004, 1.5e3, 7]`)))
+	if err := root.AppendArray(NumericNode("", a+b)); err != nil {
+		t.Fatalf("AppendArray() unexpected error: %s", err)
+	}
+	result, err := MarshalWithOptions(root, FloatFormat('f', 1))
+	if err != nil {
+		t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+	}
+	if expected := `[0.30000000000000004,1.5e3,7,0.3]`; string(result) != expected {
+		t.Errorf("MarshalWithOptions() = %s, expected %s", result, expected)
+	}
+}
+
+func TestMarshalWithOptions_OmitNull(t *testing.T) {
+	data := `{"a": null, "b": [null, 1, {"c": null, "d": [null]}], "e": {"f": null}, "g": "null"}`
+	tests := []struct {
+		name     string
+		options  []MarshalOption
+		with     MarshalOptions
+		expected string
+	}{
+		{name: "none", options: nil, expected: `{"a":null,"b":[null,1,{"c":null,"d":[null]}],"e":{"f":null},"g":"null"}`},
+		{name: "OmitNull", options: []MarshalOption{OmitNull()}, with: MarshalOptions{OmitNull: true}, expected: `{"b":[null,1,{"d":[null]}],"e":{},"g":"null"}`},
+		{name: "OmitNullElements", options: []MarshalOption{OmitNullElements()}, with: MarshalOptions{OmitNullElements: true}, expected: `{"a":null,"b":[1,{"c":null,"d":[]}],"e":{"f":null},"g":"null"}`},
+		{name: "both", options: []MarshalOption{OmitNull(), OmitNullElements()}, with: MarshalOptions{OmitNull: true, OmitNullElements: true}, expected: `{"b":[1,{"d":[]}],"e":{},"g":"null"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := Must(Unmarshal([]byte(data)))
+			value, err := MarshalWithOptions(node, append(test.options, SortKeys())...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(value) != test.expected {
+				t.Errorf("wrong result: '%s', expected '%s'", value, test.expected)
+			}
+			test.with.SortKeys = true
+			if value, err = MarshalWith(node, test.with); err != nil || string(value) != test.expected {
+				t.Errorf("MarshalWith() = '%s', %v, expected '%s'", value, err, test.expected)
+			}
+			if node.IsDirty() || node.String() != data {
+				t.Errorf("tree was changed: '%s'", node)
+			}
+		})
+	}
+}
+
+func TestMarshalArrayStream_OmitNullElements(t *testing.T) {
+	root := Must(Unmarshal([]byte(`[null, 1, null, [null], {"a": null}, null]`)))
+	buf := new(bytes.Buffer)
+	if err := MarshalArrayStream(root, buf, OmitNull(), OmitNullElements()); err != nil {
+		t.Fatalf("MarshalArrayStream() unexpected error: %s", err)
+	}
+	if value := buf.String(); value != `[1,[],{}]` {
+		t.Errorf("MarshalArrayStream() = %s", value)
+	}
+}
+
+func TestMarshalWithOptions_SortArraysBy(t *testing.T) {
+	data := `{"users":[{"id":3},{"id":1,"n":"a"},{"n":"b"},{"id":"2"},{"id":1,"n":"c"},5,{"id":true}],"ids":[{"id":2},{"id":1}]}`
+	tests := []struct {
+		name     string
+		rules    map[string]string
+		options  []MarshalOption
+		expected string
+		err      string
+	}{
+		{
+			name:     "by id",
+			rules:    map[string]string{"$.users": "id"},
+			expected: `{"ids":[{"id":2},{"id":1}],"users":[{"id":1,"n":"a"},{"id":1,"n":"c"},{"id":3},{"id":"2"},{"id":true},{"n":"b"},5]}`,
+		},
+		{
+			name:     "by name",
+			rules:    map[string]string{"$.users": "n", "$.ids": "id"},
+			expected: `{"ids":[{"id":1},{"id":2}],"users":[{"id":1,"n":"a"},{"n":"b"},{"id":1,"n":"c"},{"id":3},{"id":"2"},5,{"id":true}]}`,
+		},
+		{
+			name:     "recursive",
+			rules:    map[string]string{"$..*": "id"},
+			options:  []MarshalOption{OmitNullElements()},
+			expected: `{"ids":[{"id":1},{"id":2}],"users":[{"id":1,"n":"a"},{"id":1,"n":"c"},{"id":3},{"id":"2"},{"id":true},{"n":"b"},5]}`,
+		},
+		{name: "not found", rules: map[string]string{"$.none": "id", "$.users[0]": "id"}, expected: `{"ids":[{"id":2},{"id":1}],"users":[{"id":3},{"id":1,"n":"a"},{"n":"b"},{"id":"2"},{"id":1,"n":"c"},5,{"id":true}]}`},
+		{name: "conflict", rules: map[string]string{"$.users": "id", "$..users": "n"}, err: "wrong request: array '$['users']' should be sorted by different keys"},
+		{name: "wrong path", rules: map[string]string{"$[": "id"}, err: "unexpected end of file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := Must(Unmarshal([]byte(data)))
+			value, err := MarshalWithOptions(node, append(test.options, SortArraysBy(test.rules), SortKeys())...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("MarshalWithOptions() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+			}
+			if string(value) != test.expected {
+				t.Errorf("MarshalWithOptions() = %s, expected %s", value, test.expected)
+			}
+			if node.IsDirty() || node.String() != data {
+				t.Errorf("tree was changed: '%s'", node)
+			}
+		})
+	}
+}
+
+func TestMarshalWith_SortArraysBy(t *testing.T) {
+	data := `{"field1":1,"field3":[{"sub_field":"b"},{"other":0},{"sub_field":"a"}]}`
+	node := Must(Unmarshal([]byte(data)))
+	value, err := MarshalWith(node, MarshalOptions{SortArraysBy: map[string]string{"$.field3": "sub_field"}, SortKeys: true})
+	if err != nil {
+		t.Fatalf("MarshalWith() unexpected error: %s", err)
+	}
+	if expected := `{"field1":1,"field3":[{"sub_field":"a"},{"sub_field":"b"},{"other":0}]}`; string(value) != expected {
+		t.Errorf("MarshalWith() = %s, expected %s", value, expected)
+	}
+	if node.IsDirty() || node.String() != data {
+		t.Errorf("tree was changed: '%s'", node)
+	}
+}
+
+func TestMarshalArrayStream_SortArraysBy(t *testing.T) {
+	root := Must(Unmarshal([]byte(`[{"a":2},{"a":1,"b":[{"a":4},{"a":3}]}]`)))
+	buf := new(bytes.Buffer)
+	if err := MarshalArrayStream(root, buf, SortArraysBy(map[string]string{"$": "a", "$..b": "a"}), SortKeys()); err != nil {
+		t.Fatalf("MarshalArrayStream() unexpected error: %s", err)
+	}
+	if value := buf.String(); value != `[{"a":1,"b":[{"a":3},{"a":4}]},{"a":2}]` {
+		t.Errorf("MarshalArrayStream() = %s", value)
+	}
+}
+
+func TestMarshalWithOptions_PreserveFormatting(t *testing.T) {
+	data := "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [ ]\n}\n"
+	tests := []struct {
+		name     string
+		data     string
+		modify   func(root *Node) error
+		expected string
+	}{
+		{name: "unmodified", data: data, expected: data},
+		{name: "unmodified scalar", data: " \t\"foo\"\r\n", expected: " \t\"foo\"\r\n"},
+		{
+			name: "set value",
+			data: data,
+			modify: func(root *Node) error {
+				return root.MustKey("name").SetString("bar")
+			},
+			expected: "\n{\n  \"name\": \"bar\",\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [ ]\n}\n",
+		},
+		{
+			name: "set nested value",
+			data: data,
+			modify: func(root *Node) error {
+				return root.MustKey("tags").MustIndex(1).SetNumeric(20)
+			},
+			expected: "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,20 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [ ]\n}\n",
+		},
+		{
+			name: "append object",
+			data: data,
+			modify: func(root *Node) error {
+				return root.AppendObject("new", NumericNode("", 1))
+			},
+			expected: "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [ ],\n  \"new\": 1\n}\n",
+		},
+		{
+			name: "append array",
+			data: data,
+			modify: func(root *Node) error {
+				return root.MustKey("tags").AppendArray(NumericNode("", 4))
+			},
+			expected: "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,2 ,  3,  4 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [ ]\n}\n",
+		},
+		{
+			name: "append empty",
+			data: data,
+			modify: func(root *Node) error {
+				return root.MustKey("empty").AppendArray(NumericNode("", 1), NumericNode("", 2))
+			},
+			expected: "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [1,2 ]\n}\n",
+		},
+		{
+			name: "delete first",
+			data: data,
+			modify: func(root *Node) error {
+				return root.DeleteKey("name")
+			},
+			expected: "\n{\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}},\n  \"empty\": [ ]\n}\n",
+		},
+		{
+			name: "delete last",
+			data: data,
+			modify: func(root *Node) error {
+				return root.DeleteKey("empty")
+			},
+			expected: "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"a\": null, \"b\": {}}\n}\n",
+		},
+		{
+			name: "delete all",
+			data: `{ "a" : [ 1 ] }`,
+			modify: func(root *Node) error {
+				if err := root.MustKey("a").DeleteIndex(0); err != nil {
+					return err
+				}
+				return root.DeleteKey("a")
+			},
+			expected: `{}`,
+		},
+		{
+			name: "replaced container",
+			data: data,
+			modify: func(root *Node) error {
+				return root.MustKey("nested").SetObject(map[string]*Node{"c": NullNode("")})
+			},
+			expected: "\n{\n  \"name\": \"foo\",\n  \"tags\" : [ 1,2 ,  3 ],\n\t\"nested\": {\"c\":null},\n  \"empty\": [ ]\n}\n",
+		},
+		{
+			name: "escaped key",
+			data: `{ "a\"b" : 1 }`,
+			modify: func(root *Node) error {
+				return root.MustKey(`a"b`).SetNumeric(2)
+			},
+			expected: `{ "a\"b" : 2 }`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.data)))
+			if test.modify != nil {
+				if err := test.modify(root); err != nil {
+					t.Fatalf("modify() unexpected error: %s", err)
+				}
+			}
+			result, err := MarshalWithOptions(root, PreserveFormatting())
+			if err != nil {
+				t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("MarshalWithOptions() = %q\nexpected %q", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalWithOptions_PreserveFormatting_roundTrip(t *testing.T) {
+	for _, data := range [][]byte{jsonPathTestData, jsonExample} {
+		result, err := MarshalWithOptions(Must(Unmarshal(data)), PreserveFormatting())
+		if err != nil {
+			t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+		}
+		if !bytes.Equal(result, data) {
+			t.Errorf("MarshalWithOptions() = %s\nexpected %s", result, data)
+		}
+	}
+	root := Must(Unmarshal([]byte(` {"b": 1, "a": [2]} `)))
+	if err := root.MustKey("b").SetNumeric(3); err != nil {
+		t.Fatal(err)
+	}
+	result, err := MarshalWithOptions(root, PreserveFormatting(), SortKeys())
+	if err != nil || string(result) != `{"a":[2],"b":3}` {
+		t.Errorf("MarshalWithOptions() = %s, %v, expected sorted keys", result, err)
+	}
+	result, err = MarshalWithOptions(root.MustKey("a"), PreserveFormatting())
+	if err != nil || string(result) != `[2]` {
+		t.Errorf("MarshalWithOptions() = %s, %v, expected no whitespaces around the child", result, err)
+	}
+}
+
+type failWriter struct {
+	limit int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.limit < len(p) {
+		return 0, errors.New("write failed")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestNode_Compact(t *testing.T) {
+	data := []byte(`
+	{
+		"a" : [ 1 , 2.50 , "x y" ] ,
+		"b" : { "c" : null , "d" : "\" , \\" } ,
+		"e" : [ ] ,
+		"f" : true
+	}
+	`)
+	root := Must(Unmarshal(data))
+	b := root.MustKey("b")
+	if err := b.AppendObject("g", NumericNode("", 1.5)); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	cache := NewQueryCache(root)
+	if _, err := cache.JSONPath("$..*"); err != nil {
+		t.Fatalf("JSONPath() unexpected error: %s", err)
+	}
+	if err := root.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %s", err)
+	}
+	expected := `{"a":[1,2.50,"x y"],"b":{"c":null,"d":"\" , \\","g":1.5},"e":[],"f":true}`
+	if value := root.Source(); len(value) != len(expected) || string(compact(value)) != string(value) {
+		t.Errorf("Source() = %s", value)
+	}
+	if value, err := Marshal(root); err != nil || string(value) != string(root.Source()) {
+		t.Errorf("Marshal() = %s, %v", value, err)
+	}
+	if root.IsDirty() || b.IsDirty() || b.MustKey("g").IsDirty() {
+		t.Errorf("Compact() nodes are still dirty")
+	}
+	if value := string(root.MustKey("a").MustIndex(1).Source()); value != "2.50" {
+		t.Errorf("Source() = %s, expected 2.50", value)
+	}
+	if value := b.MustKey("d").MustString(); value != `" , \` {
+		t.Errorf("MustString() = %s", value)
+	}
+	if value := b.MustKey("g").MustNumeric(); value != 1.5 {
+		t.Errorf("MustNumeric() = %v", value)
+	}
+	if size := cache.Len(); size != 1 {
+		t.Errorf("Compact() has invalidated the cache")
+	}
+	if err := root.validateBorders(); err != nil {
+		t.Errorf("validateBorders() = %s", err)
+	}
+	if value, _ := MarshalWithOptions(root, SortKeys()); string(value) != expected {
+		t.Errorf("MarshalWithOptions() = %s", value)
+	}
+
+	sub := Must(UnmarshalWithOptions(data, KeyRanges())).MustKey("b")
+	if err := sub.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %s", err)
+	}
+	if value := string(sub.Source()); value != `{"c":null,"d":"\" , \\"}` {
+		t.Errorf("Source() = %s", value)
+	}
+	if !sub.Parent().IsDirty() {
+		t.Errorf("Compact() the parent is not modified")
+	}
+	if err := sub.Parent().Validate(); err != nil {
+		t.Errorf("Validate() = %s", err)
+	}
+	plain := Must(Unmarshal(data))
+	if err := plain.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %s", err)
+	}
+	if _, _, ok := plain.MustKey("b").MustKey("c").KeyRange(); ok {
+		t.Errorf("KeyRange() is ok after Compact without KeyRanges option")
+	}
+	if _, _, ok := sub.Range(); ok {
+		t.Errorf("Range() is ok for the compacted child")
+	}
+	if start, end, ok := sub.KeyRange(); !ok || string(data[start:end]) != `"b"` {
+		t.Errorf("KeyRange() = [%d:%d], %v", start, end, ok)
+	}
+	if _, _, ok := sub.MustKey("c").KeyRange(); ok {
+		t.Errorf("KeyRange() is ok for the member of the compacted child")
+	}
+	if value, err := Marshal(sub.Parent()); err != nil || !strings.Contains(string(value), `{"c":null,"d":"\" , \\"}`) {
+		t.Errorf("Marshal() = %s, %v", value, err)
+	}
+}
+
+func TestNode_Compact_duplicates(t *testing.T) {
+	root, err := UnmarshalWithOptions([]byte(`{ "a" : 1 , "a" : [ 2 ] }`), DuplicateKeys())
+	if err != nil {
+		t.Fatalf("UnmarshalWithOptions() unexpected error: %s", err)
+	}
+	if err = root.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %s", err)
+	}
+	if value := string(root.Source()); value != `{"a":1,"a":[2]}` {
+		t.Errorf("Source() = %s", value)
+	}
+	if values := root.KeyAll("a"); len(values) != 2 || values[0].String() != "1" || values[1].String() != "[2]" {
+		t.Errorf("KeyAll() = %v", values)
+	}
+}
+
+func TestMarshal_duplicatesOrder(t *testing.T) {
+	root := Must(UnmarshalWithOptions([]byte(`{"a":1,"b":2,"a":3,"c":4}`), DuplicateKeys()))
+	if err := root.MustKey("c").SetNumeric(5); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if err := root.AppendObject("d", NullNode("")); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		result, err := Marshal(root)
+		if err != nil {
+			t.Fatalf("Marshal() unexpected error: %s", err)
+		}
+		if value := string(result); value != `{"a":1,"b":2,"a":3,"c":5,"d":null}` {
+			t.Fatalf("Marshal() = %s", value)
+		}
+	}
+	result, err := MarshalWithOptions(root, SortKeys())
+	if err != nil {
+		t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+	}
+	if value := string(result); value != `{"a":1,"a":3,"b":2,"c":5,"d":null}` {
+		t.Errorf("MarshalWithOptions(SortKeys) = %s", value)
+	}
+}
+
+func TestNode_Compact_error(t *testing.T) {
+	root := Must(Unmarshal([]byte(`[ 1 ]`)))
+	if err := root.AppendArray(NumericNode("", math.NaN())); err != nil {
+		t.Fatalf("AppendArray() unexpected error: %s", err)
+	}
+	if err := root.Compact(); err == nil {
+		t.Errorf("Compact() expected error")
+	}
+	if err := (*Node)(nil).Compact(); err == nil {
+		t.Errorf("Compact() expected error for nil node")
+	}
+}
+
+func TestNode_Normalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		expected string
+	}{
+		{name: "numbers", left: `[1.50, 15e-1, 0.15E1, -0, 100]`, right: `[ 1.5 ,1.5,1.5, 0, 1e2 ]`, expected: `[1.5,1.5,1.5,0,100]`},
+		{name: "big numbers", left: `[12345678901234567890, 1e300, 0.1000000000000000000001]`, right: `[1234567890123456789e1, 10e299, 1.000000000000000000001e-1]`, expected: `[12345678901234567890,1e+300,0.1000000000000000000001]`},
+		{name: "strings", left: `["\u0041\/", "<&>", "\"\\\n"]`, right: `["A/", "\u003c\u0026\u003e", "\u0022\u005c\u000a"]`, expected: `["A/","<&>","\"\\\n"]`},
+		{name: "keys", left: `{"b": 1, "\u0061": {"d": true, "c": null}}`, right: `{"a": {"c": null, "d": true}, "b": 1.0}`, expected: `{"a":{"c":null,"d":true},"b":1}`},
+		{name: "scalar", left: ` 1.0 `, right: `1`, expected: `1`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			left := Must(Unmarshal([]byte(test.left)))
+			right := Must(Unmarshal([]byte(test.right)))
+			if err := left.Normalize(); err != nil {
+				t.Fatalf("Normalize() unexpected error: %s", err)
+			}
+			if err := right.Normalize(); err != nil {
+				t.Fatalf("Normalize() unexpected error: %s", err)
+			}
+			if value := string(left.Source()); value != test.expected {
+				t.Errorf("Normalize() = %s, expected %s", value, test.expected)
+			}
+			if value := string(right.Source()); value != test.expected {
+				t.Errorf("Normalize() = %s, expected %s", value, test.expected)
+			}
+			if value, err := Marshal(left); err != nil || string(value) != test.expected {
+				t.Errorf("Marshal() = %s, %v, expected %s", value, err, test.expected)
+			}
+			if equal, err := left.Eq(right); left.IsDirty() || err != nil || !equal {
+				t.Errorf("Normalize() changed the value")
+			}
+		})
+	}
+}
+
+func TestNode_Normalize_modified(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a": [1.0], "b": "x"}`)))
+	if err := root.MustKey("a").AppendArray(NumericNode("", 2.5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Normalize(); err != nil {
+		t.Fatalf("Normalize() unexpected error: %s", err)
+	}
+	if value := root.MustKey("a").String(); value != `[1,2.5]` {
+		t.Errorf("Source() = %s", value)
+	}
+	sub := Must(Unmarshal([]byte(`{"a": {"c": 1.50, "b": "\u0041"}, "d": 1}`))).MustKey("a")
+	if err := sub.Normalize(); err != nil {
+		t.Fatalf("Normalize() unexpected error: %s", err)
+	}
+	if value, err := MarshalWithOptions(sub.Parent(), SortKeys()); err != nil || string(value) != `{"a":{"b":"A","c":1.5},"d":1}` {
+		t.Errorf("Marshal() = %s, %v", value, err)
+	}
+	if err := sub.Parent().Validate(); err != nil {
+		t.Errorf("Validate() = %s", err)
+	}
+	root.MustKey("b").Freeze()
+	if err := root.Normalize(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Normalize() error = %v, expected ErrFrozen", err)
+	}
+	if err := (*Node)(nil).Normalize(); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("Normalize() error = %v, expected ErrNotParsed", err)
+	}
+}
+
+func TestMarshalIndent(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		indent   string
+		expected string
+	}{
+		{name: "scalar", json: ` "foo" `, indent: "  ", expected: `"foo"`},
+		{name: "empty array", json: `[ ]`, indent: "  ", expected: `[]`},
+		{name: "empty object", json: `{ }`, indent: "  ", expected: `{}`},
+		{name: "array", json: `[1, "a", null]`, indent: "  ", expected: "[\n  1,\n  \"a\",\n  null\n]"},
+		{name: "object", json: `{"b":1,"a":{"c":[]}}`, indent: "\t", expected: "{\n\t\"a\": {\n\t\t\"c\": []\n\t},\n\t\"b\": 1\n}"},
+		{name: "nested", json: `[[[1]],{}]`, indent: " ", expected: "[\n [\n  [\n   1\n  ]\n ],\n {}\n]"},
+		{name: "no indent", json: `{"a":[1,2]}`, indent: "", expected: "{\n\"a\": [\n1,\n2\n]\n}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := MarshalWithOptions(root, Indent(test.indent), SortKeys())
+			if err != nil {
+				t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("MarshalWithOptions() = %q, expected %q", result, test.expected)
+			}
+			if root.isContainer() && len(root.children) <= 1 {
+				if result, err = MarshalIndent(root, test.indent); err != nil || string(result) != test.expected {
+					t.Errorf("MarshalIndent() = %q, %v, expected %q", result, err, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMarshalWithOptions_IndentFunc(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":[1,{"c":[]}]}}`)))
+	if err := root.MustKey("a").MustKey("b").AppendArray(StringNode("", "d")); err != nil {
+		t.Fatal(err)
+	}
+	var depths []int
+	result, err := MarshalWithOptions(root, IndentFunc(func(depth int) string {
+		depths = append(depths, depth)
+		if depth < 2 {
+			return "  "
+		}
+		return "\t"
+	}))
+	if err != nil {
+		t.Fatalf("MarshalWithOptions() unexpected error: %s", err)
+	}
+	expected := "{\n  \"a\": {\n    \"b\": [\n    \t1,\n    \t{\n    \t\t\"c\": []\n    \t},\n    \t\"d\"\n    ]\n  }\n}"
+	if string(result) != expected {
+		t.Errorf("MarshalWithOptions() = %q, expected %q", result, expected)
+	}
+	if len(depths) != 4 || depths[0] != 0 || depths[3] != 3 {
+		t.Errorf("indentFor() was called for the depths %v, expected [0 1 2 3]", depths)
+	}
+	if root.String() != `{"a":{"b":[1,{"c":[]},"d"]}}` {
+		t.Errorf("source was changed: %s", root)
+	}
+}
+
+func TestMarshalWith(t *testing.T) {
+	data := `{"b": [3, null, 1.50], "a": {"c": "<&>", "d": null}}`
+	tests := []struct {
+		name     string
+		options  MarshalOptions
+		expected string
+	}{
+		{name: "default", options: MarshalOptions{}, expected: data},
+		{name: "sort keys", options: MarshalOptions{SortKeys: true}, expected: `{"a":{"c":"<&>","d":null},"b":[3,null,1.50]}`},
+		{name: "preserve", options: MarshalOptions{Style: PreserveStyle}, expected: data},
+		{name: "canonical", options: MarshalOptions{Style: CanonicalStyle}, expected: `{"a":{"c":"<&>","d":null},"b":[3,null,1.5]}`},
+		{name: "indent", options: MarshalOptions{Indent: "\t", SortKeys: true, OmitNull: true}, expected: "{\n\t\"a\": {\n\t\t\"c\": \"<&>\"\n\t},\n\t\"b\": [\n\t\t3,\n\t\tnull,\n\t\t1.50\n\t]\n}"},
+		{name: "indent func", options: MarshalOptions{Indent: "\t", IndentFunc: func(int) string { return " " }, Style: CanonicalStyle, OmitNullElements: true}, expected: "{\n \"a\": {\n  \"c\": \"<&>\",\n  \"d\": null\n },\n \"b\": [\n  3,\n  1.5\n ]\n}"},
+		{name: "sort arrays", options: MarshalOptions{SortKeys: true, SortArraysBy: map[string]string{"$": "x"}}, expected: `{"a":{"c":"<&>","d":null},"b":[3,null,1.50]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(data)))
+			result, err := MarshalWith(root, test.options)
+			if err != nil {
+				t.Fatalf("MarshalWith() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("MarshalWith() = %q, expected %q", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalWith_modified(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a": [1]}`)))
+	if err := root.AppendObject("b", StringNode("", "<&>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.MustKey("a").AppendArray(NumericNode("", math.Inf(1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.MustKey("a").AppendArray(NumericNode("", 0.125)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		options  MarshalOptions
+		expected string
+		err      bool
+	}{
+		{name: "default", options: MarshalOptions{}, err: true},
+		{name: "non finite", options: MarshalOptions{SortKeys: true, NonFiniteAsNull: true}, expected: `{"a":[1,null,0.125],"b":"\u003c\u0026\u003e"}`},
+		{name: "html", options: MarshalOptions{SortKeys: true, NonFiniteAsNull: true, DisableHTMLEscape: true}, expected: `{"a":[1,null,0.125],"b":"<&>"}`},
+		{name: "float format", options: MarshalOptions{SortKeys: true, NonFiniteAsNull: true, FloatFormat: 'f', FloatPrecision: 1}, expected: `{"a":[1,null,0.1],"b":"\u003c\u0026\u003e"}`},
+		{name: "float format g", options: MarshalOptions{SortKeys: true, NonFiniteAsNull: true, FloatFormat: 'g'}, expected: `{"a":[1,null,0.125],"b":"\u003c\u0026\u003e"}`},
+		{name: "float format f", options: MarshalOptions{SortKeys: true, NonFiniteAsNull: true, FloatFormat: 'f'}, expected: `{"a":[1,null,0],"b":"\u003c\u0026\u003e"}`},
+		{name: "preserve", options: MarshalOptions{Style: PreserveStyle, NonFiniteAsNull: true, DisableHTMLEscape: true}, expected: `{"a": [1,null,0.125],"b": "<&>"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MarshalWith(root, test.options)
+			if test.err {
+				if err == nil {
+					t.Errorf("MarshalWith() expected error, got %s", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MarshalWith() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("MarshalWith() = %s, expected %s", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{data: ` null `, expected: `null`},
+		{data: `[1.50, -0, 1E2, 12345678901234567890, 1e400]`, expected: `[1.5,0,100,12345678901234567890,1` + strings.Repeat("0", 400) + `]`},
+		{data: `1e5000`, expected: `1e5000`},
+		{data: `"\u0041\/\"\\\b\f\n\r\t\u001f"`, expected: `"A/\"\\\u0008\u000c\n\r\t\u001f"`},
+		{data: `"😀\ud83d\ude00 <&> \u2028"`, expected: `"😀😀 <&> \u2028"`},
+		{data: `{"b": {"\u0061": [true, false, null, {}, []]}, "a": ""}`, expected: `{"a":"","b":{"a":[true,false,null,{},[]]}}`},
+		{data: strings.Repeat("[", 1000) + strings.Repeat("]", 1000), expected: strings.Repeat("[", 1000) + strings.Repeat("]", 1000)},
+	}
+	for _, test := range tests {
+		t.Run(test.data, func(t *testing.T) {
+			result, err := RoundTrip([]byte(test.data))
+			if err != nil {
+				t.Fatalf("RoundTrip() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("RoundTrip() = %s, expected %s", result, test.expected)
+			}
+			checkRoundTrip(t, []byte(test.data))
+		})
+	}
+	if _, err := RoundTrip([]byte(`[1,]`)); err == nil {
+		t.Errorf("RoundTrip() expected error for the wrong JSON")
+	}
+}
+
+func checkRoundTrip(t *testing.T, data []byte) {
+	t.Helper()
+	root, err := Unmarshal(data)
+	if err != nil {
+		if _, err := RoundTrip(data); err == nil {
+			t.Fatalf("RoundTrip(%q) expected error", data)
+		}
+		return
+	}
+	result, err := RoundTrip(data)
+	if err != nil {
+		t.Fatalf("RoundTrip(%q) unexpected error: %s", data, err)
+	}
+	if !json.Valid(result) {
+		t.Fatalf("RoundTrip(%q) = %q, invalid JSON", data, result)
+	}
+	copied, err := Unmarshal(result)
+	if err != nil {
+		t.Fatalf("RoundTrip(%q) = %q, Unmarshal() error: %s", data, result, err)
+	}
+	// Eq returns an error for the numbers out of the range of float64, their exact values are checked by stability
+	if equal, err := root.Eq(copied); err == nil && !equal {
+		t.Fatalf("RoundTrip(%q) = %q, not equal to the source", data, result)
+	}
+	again, err := RoundTrip(result)
+	if err != nil || string(again) != string(result) {
+		t.Fatalf("RoundTrip(%q) = %q, expected to be stable, got %q, %v", result, result, again, err)
+	}
+}
+
+func TestMarshalArrayStream(t *testing.T) {
+	edited := Must(Unmarshal([]byte(`[1, {"a": "b"}]`)))
+	if err := edited.AppendArray(NumericNode("", math.NaN())); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		node     *Node
+		options  []MarshalOption
+		expected string
+		err      error
+	}{
+		{name: "empty", node: ArrayNode("", nil), expected: `[]`},
+		{name: "parsed", node: Must(Unmarshal([]byte(`[1, {"a": "b"}, [null]]`))), expected: `[1,{"a": "b"},[null]]`},
+		{name: "constructed", node: ArrayNode("", []*Node{StringNode("", "foo"), BoolNode("", true)}), expected: `["foo",true]`},
+		{name: "options", node: edited, options: []MarshalOption{NonFiniteAsNull()}, expected: `[1,{"a": "b"},null]`},
+		{name: "indent", node: Must(Unmarshal([]byte(`[1, {"a": "b"}, []]`))), options: []MarshalOption{Indent("  ")}, expected: "[\n  1,\n  {\n    \"a\": \"b\"\n  },\n  []\n]"},
+		{name: "indent empty", node: ArrayNode("", nil), options: []MarshalOption{Indent("  ")}, expected: `[]`},
+		{name: "element error", node: edited, err: Error{Type: WrongRequest, Message: "unsupported numeric value 'NaN'"}},
+		{name: "object", node: ObjectNode("", nil), err: ErrWrongType},
+		{name: "nil", node: nil, err: ErrNotParsed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := MarshalArrayStream(test.node, buf, test.options...)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("MarshalArrayStream() error = %v, expected %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MarshalArrayStream() unexpected error: %s", err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("MarshalArrayStream() = %s, expected %s", buf.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalArrayStream_writer(t *testing.T) {
+	node := Must(Unmarshal([]byte(`[1, 2, 3]`)))
+	for limit := 0; limit < 6; limit++ {
+		if err := MarshalArrayStream(node, &failWriter{limit: limit}); err == nil || err.Error() != "write failed" {
+			t.Errorf("MarshalArrayStream() with limit %d error = %v, expected write error", limit, err)
+		}
+	}
+	if err := MarshalArrayStream(node, &failWriter{limit: 7}); err != nil {
+		t.Errorf("MarshalArrayStream() unexpected error: %s", err)
+	}
+}
+
+func TestEscapeString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "", expected: `""`},
+		{value: "string", expected: `"string"`},
+		{value: `one "encoded" string`, expected: `"one \"encoded\" string"`},
+		{value: "spec.symbols: \r\n\t; UTF-8: 😹; \u2028 \000", expected: `"spec.symbols: \r\n\t; UTF-8: 😹; \u2028 \u0000"`},
+		{value: "<html>", expected: `"\u003chtml\u003e"`},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			value := EscapeString(test.value)
+			if string(value) != test.expected {
+				t.Errorf("EscapeString() = %s, expected %s", value, test.expected)
+			}
+			if result, err := UnescapeString(value); err != nil {
+				t.Errorf("UnescapeString() unexpected error: %s", err)
+			} else if result != test.value {
+				t.Errorf("UnescapeString() = %q, expected %q", result, test.value)
+			}
+		})
+	}
+}
@@ -1,16 +1,45 @@
 package ajson
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
-// Error is common struct to provide internal errors
+// Error is common struct to provide internal errors.
+// For the parse errors (WrongSymbol and UnexpectedEOF) Offset is the byte offset of the wrong symbol in the data,
+// or of the last symbol, if the data ends unexpectedly, use Snippet to show it. The same is true for the WrongRequest
+// errors of the data rejected by the options of UnmarshalWithOptions, e.g. MaxDepth. Index has the same value for
+// them, as it had before Offset was added.
 type Error struct {
 	Type    ErrorType
 	Index   int
+	Offset  int
 	Char    byte
 	Message string
 	Value   interface{}
+
+	cause error
+	data  *[]byte
 }
 
+var (
+	// ErrNotParsed is the category of errors for the nodes, that wasn't parsed yet (Unparsed)
+	ErrNotParsed = errors.New("not parsed yet")
+	// ErrWrongType is the category of errors for the requests of a wrong type of Node (WrongType)
+	ErrWrongType = errors.New("wrong type of Node")
+	// ErrKeyNotFound is the category of errors for the requests of a missing key of the Object
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrIndexOutOfRange is the category of errors for the requests of a missing index of the Array or the String
+	ErrIndexOutOfRange = errors.New("index out of range")
+	// ErrKeyExists is the category of errors for the attempts to append the existing key to the Object
+	ErrKeyExists = errors.New("key already exists")
+	// ErrFrozen is the category of errors for the attempts to change the frozen node
+	ErrFrozen = errors.New("node is frozen")
+)
+
 // ErrorType is container for reflection type of error
 type ErrorType int
 
@@ -35,9 +64,11 @@ func errorSymbol(b *buffer) error {
 		symbol = 0
 	}
 	return Error{
-		Type:  WrongSymbol,
-		Index: b.index,
-		Char:  symbol,
+		Type:   WrongSymbol,
+		Index:  b.index,
+		Offset: b.index,
+		Char:   symbol,
+		data:   &b.data,
 	}
 }
 
@@ -49,10 +80,38 @@ func errorAt(index int, symbol byte) error {
 	}
 }
 
+// errorNumber returns the WrongSymbol error for the incomplete number at the end of the data, like `1.` or `1e`,
+// pointing at the start of the number
+func errorNumber(b *buffer, start int) error {
+	return Error{
+		Type:   WrongSymbol,
+		Index:  start,
+		Offset: start,
+		Char:   b.data[start],
+		data:   &b.data,
+	}
+}
+
+// errorRange returns the WrongSymbol error for the number at index, which has no float64 representation, it's
+// strconv.ErrRange for errors.Is
+func errorRange(b *buffer, index int, value []byte) error {
+	return Error{
+		Type:    WrongSymbol,
+		Index:   index,
+		Offset:  index,
+		Char:    b.data[index],
+		Message: fmt.Sprintf("numeric value %s out of range", value),
+		cause:   strconv.ErrRange,
+		data:    &b.data,
+	}
+}
+
 func errorEOF(b *buffer) error {
 	return Error{
-		Type:  UnexpectedEOF,
-		Index: b.index,
+		Type:   UnexpectedEOF,
+		Index:  b.index,
+		Offset: b.index,
+		data:   &b.data,
 	}
 }
 
@@ -82,6 +141,159 @@ func errorRequest(format string, args ...interface{}) error {
 	}
 }
 
+// errorData returns the WrongRequest error for the data at index, rejected by the parsing options
+func errorData(b *buffer, index int, format string, args ...interface{}) error {
+	return Error{
+		Type:    WrongRequest,
+		Index:   index,
+		Offset:  index,
+		Message: fmt.Sprintf(format, args...) + fmt.Sprintf(" at %d", index),
+		data:    &b.data,
+	}
+}
+
+// errorNode returns the WrongRequest error for the broken node, with its path
+func errorNode(node *Node, format string, args ...interface{}) error {
+	return PathError{Path: node.Path(), Err: errorRequest(format, args...)}
+}
+
+func errorKeyNotFound(key string) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("wrong key '%s'", key),
+		cause:   ErrKeyNotFound,
+	}
+}
+
+func errorKeyExists(key string) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("key '%s' already exists", key),
+		cause:   ErrKeyExists,
+	}
+}
+
+func errorUnresolved(name, path string) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("unresolved variable '%s' at %s", name, path),
+		cause:   ErrKeyNotFound,
+	}
+}
+
+func errorOutOfRange(format string, args ...interface{}) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf(format, args...),
+		cause:   ErrIndexOutOfRange,
+	}
+}
+
+func errorFrozen() error {
+	return Error{
+		Type:    WrongRequest,
+		Message: "node is frozen",
+		cause:   ErrFrozen,
+	}
+}
+
+func errorLine(line int, err error) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("line %d: %s", line, err),
+		cause:   err,
+	}
+}
+
+func errorGzip(err error) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("wrong gzip data: %s", err),
+		cause:   err,
+	}
+}
+
+func errorPart(index int, part interface{}, err error) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("part #%d (%#v): %s", index, part, err),
+		cause:   err,
+	}
+}
+
+func errorStep(index int, err error) error {
+	return Error{
+		Type:    WrongRequest,
+		Message: fmt.Sprintf("step #%d: %s", index, err),
+		cause:   err,
+	}
+}
+
+// Unwrap returns the category of the error to be used with errors.Is, or nil if there is no category for it
+func (err Error) Unwrap() error {
+	if err.cause != nil {
+		return err.cause
+	}
+	switch err.Type {
+	case Unparsed:
+		return ErrNotParsed
+	case WrongType:
+		return ErrWrongType
+	}
+	return nil
+}
+
+// Snippet returns the line of the data around the Offset of the parse error with the caret under the wrong symbol,
+// like compilers do. Long lines are cut to the snippetRadius bytes around the Offset, tabs are replaced with spaces.
+// It's built on demand, from the data kept by the error. Returns an empty string for other errors.
+//
+//	{"name": tru}
+//	            ^
+func (err Error) Snippet() string {
+	if err.data == nil {
+		return ""
+	}
+	return snippet(*err.data, err.Offset)
+}
+
+// snippetRadius is the maximal count of bytes before and after the Index, shown by the Error.Snippet
+const snippetRadius = 32
+
+// snippet returns the line of the data around the index and the line with the caret under the index
+func snippet(data []byte, index int) string {
+	if index > len(data) {
+		index = len(data)
+	}
+	start, end := index, index
+	for start > 0 && index-start < snippetRadius && data[start-1] != skipN {
+		start--
+	}
+	for start < index && !utf8.RuneStart(data[start]) {
+		start++
+	}
+	for end < len(data) && end-index < snippetRadius && data[end] != skipN {
+		end++
+	}
+	for end < len(data) && end > index && !utf8.RuneStart(data[end]) {
+		end--
+	}
+	line := make([]byte, 0, end-start+6)
+	if start > 0 && data[start-1] != skipN {
+		line = append(line, "..."...)
+	}
+	column := len(line) + utf8.RuneCount(data[start:index])
+	for _, c := range data[start:end] {
+		if c == skipT || c == skipR {
+			c = skipS
+		}
+		line = append(line, c)
+	}
+	if end < len(data) && data[end] != skipN {
+		line = append(line, "..."...)
+	}
+	return string(line) + "\n" + strings.Repeat(" ", column) + "^"
+}
+
 // Error interface implementation
 func (err Error) Error() string {
 	switch err.Type {
@@ -1,6 +1,10 @@
 package ajson
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestError_Error(t *testing.T) {
 	tests := []struct {
@@ -83,3 +87,99 @@ func Test_unsupportedType(t *testing.T) {
 		})
 	}
 }
+
+func TestError_Is(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":[1,"bar"]}`)))
+	broken := Must(Unmarshal([]byte(`{}`)))
+	broken.borders[1] = 0
+	_, errKey := root.GetKey("baz")
+	_, errIndex := root.MustKey("foo").GetIndex(10)
+	_, errType := root.GetIndex(0)
+	_, errUnparsed := Marshal(broken)
+	_, errSubstring := root.MustKey("foo").MustIndex(1).Substring(0, 10)
+	_, errSymbol := Unmarshal([]byte(`{]`))
+
+	tests := []struct {
+		name    string
+		err     error
+		target  error
+		message string
+	}{
+		{name: "ErrKeyNotFound", err: errKey, target: ErrKeyNotFound, message: "wrong request: wrong key 'baz'"},
+		{name: "ErrIndexOutOfRange", err: errIndex, target: ErrIndexOutOfRange, message: "wrong request: out of index 10"},
+		{name: "ErrIndexOutOfRange substring", err: errSubstring, target: ErrIndexOutOfRange, message: "wrong request: out of range [0:10] with length 3"},
+		{name: "ErrWrongType", err: errType, target: ErrWrongType, message: "wrong type of Node"},
+		{name: "ErrNotParsed", err: errUnparsed, target: ErrNotParsed, message: "not parsed yet"},
+	}
+	sentinels := []error{ErrKeyNotFound, ErrIndexOutOfRange, ErrWrongType, ErrNotParsed}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.err == nil {
+				t.Errorf("expected error")
+				return
+			}
+			if test.err.Error() != test.message {
+				t.Errorf("Wrong error message: %s", test.err.Error())
+			}
+			for _, sentinel := range sentinels {
+				if errors.Is(test.err, sentinel) != (sentinel == test.target) {
+					t.Errorf("errors.Is(%v, %v) = %v", test.err, sentinel, !(sentinel == test.target))
+				}
+			}
+		})
+	}
+	for _, sentinel := range sentinels {
+		if errors.Is(errSymbol, sentinel) {
+			t.Errorf("errors.Is(%v, %v) = true", errSymbol, sentinel)
+		}
+	}
+}
+
+func TestError_Snippet(t *testing.T) {
+	long := strings.Repeat("1,", 30)
+	tests := []struct {
+		name    string
+		data    string
+		options []UnmarshalOption
+		index   int
+		snippet string
+	}{
+		{name: "symbol", data: `{"name": tru}`, index: 12, snippet: "{\"name\": tru}\n            ^"},
+		{name: "first", data: `]`, index: 0, snippet: "]\n^"},
+		{name: "eof", data: `{"a":[1,2`, index: 8, snippet: "{\"a\":[1,2\n        ^"},
+		{name: "empty", data: ``, index: 0, snippet: "\n^"},
+		{name: "multiline", data: "{\n\t\"a\": 1,\n\t\"b\": x\n}", index: 17, snippet: " \"b\": x\n      ^"},
+		{name: "crlf", data: "[\r\n1,\r\n]", index: 7, snippet: "]\n^"},
+		{name: "unicode", data: `{"ключ": значение}`, index: 13, snippet: "{\"ключ\": значение}\n         ^"},
+		{name: "long line", data: "[" + long + "x," + long + "0]", index: 61, snippet: "..." + long[28:] + "x," + long[:30] + "...\n" + strings.Repeat(" ", 35) + "^"},
+		{name: "long line begin", data: "[" + long + "0]]", index: 63, snippet: "..." + long[30:] + "0]]\n" + strings.Repeat(" ", 35) + "^"},
+		{name: "unicode cut", data: "[ \"" + strings.Repeat("ж", 20) + "\", x]", index: 46, snippet: "..." + strings.Repeat("ж", 14) + "\", x]\n" + strings.Repeat(" ", 20) + "^"},
+		{name: "empty key", data: `{"a": {"": 1}}`, options: []UnmarshalOption{RejectEmptyKeys()}, index: 7, snippet: "{\"a\": {\"\": 1}}\n       ^"},
+		{name: "out of range", data: `[1, 1e400]`, options: []UnmarshalOption{RejectOutOfRange()}, index: 4, snippet: "[1, 1e400]\n    ^"},
+		{name: "max depth", data: `{"a": [[1]]}`, options: []UnmarshalOption{MaxDepth(2)}, index: 7, snippet: "{\"a\": [[1]]}\n       ^"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnmarshalWithOptions([]byte(test.data), test.options...)
+			var result Error
+			if !errors.As(err, &result) {
+				t.Fatalf("UnmarshalWithOptions() error = %v, expected Error", err)
+			}
+			if result.Offset != test.index || result.Index != test.index {
+				t.Errorf("Offset = %d, Index = %d, expected %d", result.Offset, result.Index, test.index)
+			}
+			if value := result.Snippet(); value != test.snippet {
+				t.Errorf("Snippet() = \n%s\nexpected\n%s", value, test.snippet)
+			}
+		})
+	}
+	if value := errorType().(Error).Snippet(); value != "" {
+		t.Errorf("Snippet() = %s, expected empty", value)
+	}
+	if _, err := JSONPath([]byte(`{}`), "$.a[?(@ == 1]"); err != nil {
+		var result Error
+		if errors.As(err, &result) && result.Snippet() == "" && (result.Type == WrongSymbol || result.Type == UnexpectedEOF) {
+			t.Errorf("Snippet() is empty for %v", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ajson
+
+import (
+	"math"
+	"net/url"
+)
+
+// FormOption is a functional option for FromURLValues
+type FormOption func(options *formOptions)
+
+type formOptions struct {
+	coerce bool
+}
+
+// CoerceFormValues makes FromURLValues to convert values, which are valid JSON numbers or booleans, to Numeric and
+// Bool nodes, instead of String ones. Numbers are marshaled as they are written in the form, numbers out of the float64
+// range are kept as strings.
+func CoerceFormValues() FormOption {
+	return func(options *formOptions) {
+		options.coerce = true
+	}
+}
+
+// FromURLValues returns an Object node built from the form data: keys with a single value become String nodes,
+// repeated keys become Array nodes of the values in the given order. Keys without values become empty Array nodes.
+//
+//	values, _ := url.ParseQuery("name=foo&tag=a&tag=b&age=42")
+//	root := ajson.FromURLValues(values, ajson.CoerceFormValues())
+//	// {"age":42,"name":"foo","tag":["a","b"]}
+func FromURLValues(v url.Values, options ...FormOption) *Node {
+	opts := new(formOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	children := make(map[string]*Node, len(v))
+	for key, values := range v {
+		if len(values) == 1 {
+			children[key] = formValue(key, values[0], opts)
+			continue
+		}
+		elements := make([]*Node, len(values))
+		for i, value := range values {
+			elements[i] = formValue("", value, opts)
+		}
+		children[key] = ArrayNode(key, elements)
+	}
+	return ObjectNode("", children)
+}
+
+// formValue returns the node for the single form value
+func formValue(key string, value string, options *formOptions) *Node {
+	if options.coerce {
+		if node, err := Unmarshal([]byte(value)); err == nil && string(node.Source()) == value {
+			switch node._type {
+			case Numeric:
+				if number, err := node.GetNumeric(); err == nil && !math.IsInf(number, 0) {
+					// the parsed node keeps the source of the number, so big numbers don't lose precision
+					node.key = &key
+					return node
+				}
+			case Bool:
+				return BoolNode(key, node.MustBool())
+			}
+		}
+	}
+	return StringNode(key, value)
+}
@@ -0,0 +1,70 @@
+package ajson
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func ExampleFromURLValues() {
+	values, _ := url.ParseQuery("name=foo&tag=a&tag=b&age=42")
+	result, _ := MarshalWithOptions(FromURLValues(values, CoerceFormValues()), SortKeys())
+	fmt.Printf("%s", result)
+	// Output:
+	// {"age":42,"name":"foo","tag":["a","b"]}
+}
+
+func TestFromURLValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		coerce   bool
+		expected string
+	}{
+		{name: "empty", query: ``, expected: `{}`},
+		{name: "single", query: `a=1&b=foo`, expected: `{"a":"1","b":"foo"}`},
+		{name: "repeated", query: `a=1&a=2&a=1`, expected: `{"a":["1","2","1"]}`},
+		{name: "empty value", query: `a=&b`, expected: `{"a":"","b":""}`},
+		{name: "escaped", query: `a%22b=%5C%0A`, expected: `{"a\"b":"\\\n"}`},
+		{name: "coerce", query: `a=1&b=-1.5e3&c=true&d=false&e=foo`, coerce: true,
+			expected: `{"a":1,"b":-1.5e3,"c":true,"d":false,"e":"foo"}`},
+		{name: "coerce big", query: `id=7301896625109403654&ids=7301896625109403654&ids=0.10000000000000000001`, coerce: true,
+			expected: `{"id":7301896625109403654,"ids":[7301896625109403654,0.10000000000000000001]}`},
+		{name: "coerce repeated", query: `a=1&a=true&a=x`, coerce: true, expected: `{"a":[1,true,"x"]}`},
+		{name: "coerce not JSON", query: `a=+1&b=01&c=0x10&d=True&e=null&f=%201&g=1e999&h=[1]`, coerce: true,
+			expected: `{"a":" 1","b":"01","c":"0x10","d":"True","e":"null","f":" 1","g":"1e999","h":"[1]"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() unexpected error: %s", err)
+			}
+			var options []FormOption
+			if test.coerce {
+				options = append(options, CoerceFormValues())
+			}
+			root := FromURLValues(values, options...)
+			result, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("Marshal() = %s, expected %s", result, test.expected)
+			}
+			if _, err = Unmarshal(result); err != nil {
+				t.Errorf("Unmarshal() unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestFromURLValues_keyless(t *testing.T) {
+	root := FromURLValues(url.Values{"a": nil})
+	if result, err := Marshal(root); err != nil || string(result) != `{"a":[]}` {
+		t.Errorf("Marshal() = %s, %v", result, err)
+	}
+	if root.MustKey("a").Parent() != root {
+		t.Errorf("wrong parent")
+	}
+}
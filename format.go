@@ -0,0 +1,294 @@
+package ajson
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// member is the layout of the container element in the source data, all positions are relative to the container
+type member struct {
+	key       string // unquoted key of the Object element
+	after     int    // position after the opening bracket or the previous comma
+	start     int    // position of the key for the Object, or of the value for the Array
+	head      int    // position after the key for the Object, or of the value for the Array
+	value     [2]int // borders of the value
+	separator int    // position of the comma or the closing bracket after the value
+}
+
+// formatted returns true, if the source of the container is still available to restore its formatting
+func (n *Node) formatted() bool {
+	return n.data != nil && n.ready()
+}
+
+// surround adds whitespaces around the root value, as they were in the source data
+func (n *Node) surround(value []byte) []byte {
+	if !n.formatted() {
+		return value
+	}
+	prefix := bytes.TrimPrefix((*n.data)[:n.borders[0]], bom)
+	suffix := (*n.data)[n.borders[1]:]
+	if !whitespaces(prefix) || !whitespaces(suffix) {
+		return value
+	}
+	result := make([]byte, 0, len(prefix)+len(value)+len(suffix))
+	result = append(result, prefix...)
+	result = append(result, value...)
+	return append(result, suffix...)
+}
+
+// members returns the layout of the container elements in the source data
+func (n *Node) members() (result []member, err error) {
+	source := (*n.data)[n.borders[0]:n.borders[1]]
+	lexer := NewLexer(source)
+	depth := 0
+	var current *member
+	add := func(value member) {
+		value.after = 1
+		if current != nil {
+			value.after = current.separator + 1
+		}
+		result = append(result, value)
+		current = &result[len(result)-1]
+	}
+	open := func(token Token) {
+		if n._type == Array {
+			add(member{start: token.Offset, head: token.Offset})
+		}
+		current.value[0] = token.Offset
+	}
+	for {
+		token, err := lexer.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token.Type {
+		case TokenObjectStart, TokenArrayStart:
+			if depth == 1 {
+				open(token)
+			}
+			depth++
+		case TokenObjectEnd, TokenArrayEnd:
+			depth--
+			if depth == 1 {
+				current.value[1] = token.Offset + 1
+			} else if depth == 0 && current != nil {
+				current.separator = token.Offset
+			}
+		case TokenComma:
+			if depth == 1 {
+				current.separator = token.Offset
+			}
+		case TokenColon:
+		case TokenKey:
+			if depth == 1 {
+				key, ok := unquote(token.Value, quotes)
+				if !ok {
+					return nil, errorAt(n.borders[0]+token.Offset, token.Value[0])
+				}
+				add(member{key: key, start: token.Offset, head: token.Offset + len(token.Value)})
+			}
+		default:
+			if depth == 1 {
+				open(token)
+				current.value[1] = token.Offset + len(token.Value)
+			}
+		}
+	}
+}
+
+// marshalFormatted marshals the modified container, keeping the layout of its unmodified elements
+func marshalFormatted(node *Node, options *marshalOptions, state *marshalState) (result []byte, err error) {
+	members, err := node.members()
+	if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		key    string
+		child  *Node
+		layout *member
+	}
+	entries := make([]entry, 0, len(node.children))
+	used := make(map[string]bool, len(node.children))
+	for i := range members {
+		key := members[i].key
+		if node._type == Array {
+			key = strconv.Itoa(i)
+		}
+		if child, ok := node.children[key]; ok && !used[key] {
+			used[key] = true
+			entries = append(entries, entry{key: key, child: child, layout: &members[i]})
+		}
+	}
+	keys := make([]string, 0)
+	if node._type == Array {
+		for i := len(members); i < len(node.children); i++ {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	} else {
+		for key := range node.children {
+			if !used[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+	}
+	for _, key := range keys {
+		child, ok := node.children[key]
+		if !ok {
+			return nil, errorRequest("wrong length of array")
+		}
+		entries = append(entries, entry{key: key, child: child})
+	}
+
+	source := (*node.data)[node.borders[0]:node.borders[1]]
+	var last *member
+	if len(members) != 0 {
+		last = &members[len(members)-1]
+	}
+	result = append(result, source[0])
+	for i, element := range entries {
+		if i != 0 {
+			result = append(result, coma)
+		}
+		layout := element.layout
+		if layout == nil {
+			layout = last
+		}
+		if layout != nil {
+			result = append(result, source[layout.after:layout.start]...)
+		}
+		if element.layout != nil {
+			result = append(result, source[element.layout.start:element.layout.value[0]]...)
+		} else if node._type == Object {
+			result = append(result, quotes)
+			result = append(result, quoteString(element.key, options.escapeHTML())...)
+			result = append(result, quotes)
+			if last != nil {
+				result = append(result, source[last.head:last.value[0]]...)
+			} else {
+				result = append(result, colon)
+			}
+		}
+		value, err := marshal(element.child, options, state)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value...)
+		if i != len(entries)-1 && element.layout != nil && element.layout != last {
+			result = append(result, source[element.layout.value[1]:element.layout.separator]...)
+		}
+	}
+	if last != nil {
+		if len(entries) != 0 {
+			result = append(result, source[last.value[1]:last.separator]...)
+		}
+	} else {
+		result = append(result, source[1:len(source)-1]...)
+	}
+	return append(result, source[len(source)-1]), nil
+}
+
+// whitespaces returns true, if data contains only whitespaces
+func whitespaces(data []byte) bool {
+	for _, c := range data {
+		if c != skipS && c != skipN && c != skipR && c != skipT {
+			return false
+		}
+	}
+	return true
+}
+
+// Compact removes the insignificant whitespaces from the source of current node and all of its children in place,
+// values and the order of the elements stay the same. After it, Source and Marshal return the compact value without
+// rebuilding it, modified children are encoded once and become unmodified. Unlike the marshaling, which doesn't change
+// the node, Compact drops the original formatting, so PreserveFormatting has nothing to preserve.
+//
+// Gaps in the indexes of the arrays (see RepairIndices) are closed before compacting.
+//
+// Parents of current node become modified, as the source of current node is no longer a part of their source, use
+// Compact for the root to get the compact value of the whole document.
+func (n *Node) Compact() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if err := n.writableTree(); err != nil {
+		return err
+	}
+	if err := n.repairTree(); err != nil {
+		return err
+	}
+	value, err := Marshal(n)
+	if err != nil {
+		return err
+	}
+	source, err := UnmarshalWithOptions(compact(value), DuplicateKeys())
+	if err != nil {
+		return err
+	}
+	n.graft(source)
+	if n.parent != nil {
+		n.parent.mark()
+	}
+	return nil
+}
+
+// Normalize rewrites the source of current node and all of its children in place to the canonical form: without
+// whitespaces, with the keys of the objects sorted, numbers in the shortest form which keeps their exact value
+// (e.g. `1.50`, `15e-1` and `0.15E1` become `1.5`, `-0` becomes `0`) and strings escaped minimally (e.g. `"\u0041\/"` becomes `"A/"`).
+// Equal documents become byte-identical after it, so their sources could be hashed or compared as is. Values of the
+// nodes stay the same, the original formatting is dropped, as with Compact.
+//
+// Gaps in the indexes of the arrays are closed before normalizing, as with Compact.
+//
+// Parents of current node become modified, as with Compact, use Normalize for the root to normalize the whole document.
+func (n *Node) Normalize() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if err := n.writableTree(); err != nil {
+		return err
+	}
+	if err := n.repairTree(); err != nil {
+		return err
+	}
+	value, err := marshal(n, &marshalOptions{sortKeys: true, canonical: true}, new(marshalState))
+	if err != nil {
+		return err
+	}
+	source, err := UnmarshalWithOptions(value, DuplicateKeys())
+	if err != nil {
+		return err
+	}
+	n.graft(source)
+	if n.parent != nil {
+		n.parent.mark()
+	}
+	return nil
+}
+
+// graft replaces the source of current node and all of its children with the source of the same node from another tree,
+// the key of current node stays in the source of its parent. The borders of the keys are replaced only for the children
+// which kept them, as the source is parsed with DuplicateKeys.
+func (n *Node) graft(source *Node) {
+	n.data = source.data
+	n.borders = source.borders
+	n.dirty = false
+	for key, child := range n.children {
+		if list, ok := n.duplicates[key]; ok {
+			for i, node := range list {
+				node.keyBorders = source.duplicates[key][i].keyBorders
+				node.graft(source.duplicates[key][i])
+			}
+			continue
+		}
+		if child.keyBorders != nil {
+			child.keyBorders = source.children[key].keyBorders
+		}
+		child.graft(source.children[key])
+	}
+}
@@ -0,0 +1,41 @@
+package ajson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// UnmarshalGzip decompresses the gzip-compressed data and parses the result, same as UnmarshalWithOptions does.
+// Concatenated gzip members are read as one stream. Invalid or truncated gzip data is reported with the WrongRequest
+// error, the original error of compress/gzip is available with errors.Is, e.g. errors.Is(err, gzip.ErrHeader).
+func UnmarshalGzip(data []byte, options ...UnmarshalOption) (root *Node, err error) {
+	return UnmarshalGzipReader(bytes.NewReader(data), options...)
+}
+
+// UnmarshalGzipReader reads the gzip-compressed data from r until EOF, decompresses and parses it, same as
+// UnmarshalGzip does. Decompressed data is kept in memory, as the result tree refers to it: use MaxSize option to
+// limit it for the untrusted data.
+func UnmarshalGzipReader(r io.Reader, options ...UnmarshalOption) (root *Node, err error) {
+	opts := new(unmarshalOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errorGzip(err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	var limited io.Reader = reader
+	if opts.maxSize > 0 {
+		// one byte over the limit is enough to reject the data in unmarshal
+		limited = io.LimitReader(reader, opts.maxSize+1)
+	}
+	data, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, errorGzip(err)
+	}
+	return unmarshal(data, opts)
+}
@@ -0,0 +1,108 @@
+package ajson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+)
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	writer := gzip.NewWriter(buf)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatalf("Write() unexpected error: %s", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnmarshalGzip(t *testing.T) {
+	valid := gzipData(t, `{"a": [1, 2, {"b": null}]}`)
+	tests := []struct {
+		name     string
+		data     []byte
+		options  []UnmarshalOption
+		expected string
+		err      string
+		cause    error
+	}{
+		{name: "valid", data: valid, expected: `{"a": [1, 2, {"b": null}]}`},
+		{name: "options", data: valid, options: []UnmarshalOption{MaxDepth(2)}, err: "wrong request: maximum depth 2 exceeded at 13"},
+		{name: "concatenated", data: append(gzipData(t, `[1, `), gzipData(t, `2]`)...), expected: `[1, 2]`},
+		{name: "wrong json", data: gzipData(t, `{"a":`), err: "unexpected end of file"},
+		{name: "empty", data: nil, err: "wrong request: wrong gzip data: EOF", cause: io.EOF},
+		{name: "plain json", data: []byte(`{"key": "value"}`), err: "wrong request: wrong gzip data: gzip: invalid header", cause: gzip.ErrHeader},
+		{name: "max size", data: valid, options: []UnmarshalOption{MaxSize(26)}, expected: `{"a": [1, 2, {"b": null}]}`},
+		{name: "max size exceeded", data: valid, options: []UnmarshalOption{MaxSize(25)}, err: "wrong request: maximum size 25 exceeded"},
+		{name: "truncated", data: valid[:len(valid)-4], err: "wrong request: wrong gzip data: unexpected EOF", cause: io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := UnmarshalGzip(test.data, test.options...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("UnmarshalGzip() error = %v, expected %s", err, test.err)
+				}
+				if test.cause != nil && !errors.Is(err, test.cause) {
+					t.Errorf("UnmarshalGzip() error = %v, expected to be %v", err, test.cause)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalGzip() unexpected error: %s", err)
+			}
+			if value := root.String(); value != test.expected {
+				t.Errorf("UnmarshalGzip() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalGzipReader_MaxSize(t *testing.T) {
+	// 64 MiB of zeros are compressed to ~64 KiB, reading must stop right after the limit
+	buf := new(bytes.Buffer)
+	writer := gzip.NewWriter(buf)
+	chunk := make([]byte, 1<<20)
+	for i := 0; i < 1<<6; i++ {
+		if _, err := writer.Write(chunk); err != nil {
+			t.Fatalf("Write() unexpected error: %s", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %s", err)
+	}
+	counter := &countingReader{reader: buf}
+	_, err := UnmarshalGzipReader(counter, MaxSize(1<<10))
+	if err == nil || err.Error() != "wrong request: maximum size 1024 exceeded" {
+		t.Errorf("UnmarshalGzipReader() error = %v", err)
+	}
+	if counter.read > 1<<14 {
+		t.Errorf("UnmarshalGzipReader() has read %d bytes of the compressed data", counter.read)
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+func TestUnmarshalGzipReader(t *testing.T) {
+	root, err := UnmarshalGzipReader(bytes.NewReader(gzipData(t, `["foo"]`)))
+	if err != nil {
+		t.Fatalf("UnmarshalGzipReader() unexpected error: %s", err)
+	}
+	if value, err := root.MustIndex(0).GetString(); err != nil || value != "foo" {
+		t.Errorf("UnmarshalGzipReader() = %s, %v", root, err)
+	}
+}
@@ -0,0 +1,495 @@
+package ajson
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// UnmarshalJSON5 parses the JSON5-encoded data (https://spec.json5.org) and return the root node of struct.
+//
+// JSON5 data is converted to the strict JSON at first, so the result tree is the same as it was produced by Unmarshal,
+// and Node.Source returns the strict JSON value. Comments are dropped, keys and strings are double-quoted,
+// hexadecimal numbers are converted to the decimal ones. NaN and Infinity have no representation in JSON,
+// so an error is returned for them.
+func UnmarshalJSON5(data []byte) (root *Node, err error) {
+	parser := &json5{
+		buf:    newBuffer(data),
+		result: make([]byte, 0, len(data)),
+	}
+	if err = parser.document(); err != nil {
+		return nil, err
+	}
+	return Unmarshal(parser.result)
+}
+
+// json5 converts the JSON5 data to the strict JSON
+type json5 struct {
+	buf    *buffer
+	result []byte
+}
+
+func (p *json5) document() error {
+	if err := p.skip(); err != nil {
+		return p.eof(err)
+	}
+	if err := p.value(); err != nil {
+		return err
+	}
+	if err := p.skip(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return p.buf.errorSymbol()
+	}
+	return nil
+}
+
+// skip skips whitespaces and comments, returns io.EOF if data is ended
+func (p *json5) skip() error {
+	b := p.buf
+	for b.index < b.length {
+		c := b.data[b.index]
+		switch {
+		case c == skipS || c == skipT || c == skipN || c == skipR || c == '\v' || c == '\f':
+			b.index++
+		case c == '/':
+			if b.index+1 >= b.length {
+				b.index++
+				return b.errorEOF()
+			}
+			switch b.data[b.index+1] {
+			case '/':
+				for b.index < b.length && b.data[b.index] != skipN && b.data[b.index] != skipR {
+					b.index++
+				}
+			case asterisk:
+				end := bytes.Index(b.data[b.index+2:], []byte("*/"))
+				if end < 0 {
+					b.index = b.length
+					return b.errorEOF()
+				}
+				b.index += end + 4
+			default:
+				b.index++
+				return b.errorSymbol()
+			}
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(b.data[b.index:])
+			if r != '\uFEFF' && r != '\u2028' && r != '\u2029' && !unicode.Is(unicode.Zs, r) {
+				return nil
+			}
+			b.index += size
+		default:
+			return nil
+		}
+	}
+	return io.EOF
+}
+
+func (p *json5) value() error {
+	b := p.buf
+	switch c := b.data[b.index]; c {
+	case bracesL:
+		return p.object()
+	case bracketL:
+		return p.array()
+	case quotes, quote:
+		value, err := p.string(c)
+		if err != nil {
+			return err
+		}
+		p.quote(value)
+	case 'n':
+		return p.word(_null)
+	case 't':
+		return p.word(_true)
+	case 'f':
+		return p.word(_false)
+	default:
+		return p.numeric()
+	}
+	return nil
+}
+
+func (p *json5) object() error {
+	b := p.buf
+	b.index++
+	p.result = append(p.result, bracesL)
+	for next := false; ; next = true {
+		if err := p.skip(); err != nil {
+			return p.eof(err)
+		}
+		if b.data[b.index] == bracesR {
+			break
+		}
+		if next {
+			p.result = append(p.result, coma)
+		}
+		if err := p.key(); err != nil {
+			return err
+		}
+		if err := p.skip(); err != nil {
+			return p.eof(err)
+		}
+		if b.data[b.index] != colon {
+			return b.errorSymbol()
+		}
+		b.index++
+		p.result = append(p.result, colon)
+		if err := p.skip(); err != nil {
+			return p.eof(err)
+		}
+		if err := p.value(); err != nil {
+			return err
+		}
+		if err := p.skip(); err != nil {
+			return p.eof(err)
+		}
+		if b.data[b.index] == bracesR {
+			break
+		}
+		if b.data[b.index] != coma {
+			return b.errorSymbol()
+		}
+		b.index++
+	}
+	b.index++
+	p.result = append(p.result, bracesR)
+	return nil
+}
+
+func (p *json5) array() error {
+	b := p.buf
+	b.index++
+	p.result = append(p.result, bracketL)
+	for next := false; ; next = true {
+		if err := p.skip(); err != nil {
+			return p.eof(err)
+		}
+		if b.data[b.index] == bracketR {
+			break
+		}
+		if next {
+			p.result = append(p.result, coma)
+		}
+		if err := p.value(); err != nil {
+			return err
+		}
+		if err := p.skip(); err != nil {
+			return p.eof(err)
+		}
+		if b.data[b.index] == bracketR {
+			break
+		}
+		if b.data[b.index] != coma {
+			return b.errorSymbol()
+		}
+		b.index++
+	}
+	b.index++
+	p.result = append(p.result, bracketR)
+	return nil
+}
+
+// key reads the quoted key or the ECMAScript identifier name
+func (p *json5) key() error {
+	b := p.buf
+	if c := b.data[b.index]; c == quotes || c == quote {
+		value, err := p.string(c)
+		if err != nil {
+			return err
+		}
+		p.quote(value)
+		return nil
+	}
+	name := make([]byte, 0)
+	for b.index < b.length {
+		start := b.index
+		r, size := utf8.DecodeRune(b.data[b.index:])
+		if r == '\\' {
+			b.index++
+			if b.index >= b.length {
+				return b.errorEOF()
+			}
+			if b.data[b.index] != 'u' {
+				return b.errorSymbol()
+			}
+			b.index++
+			var err error
+			if r, err = p.hex(4); err != nil {
+				return err
+			}
+			if !identifierRune(r, len(name) == 0) {
+				b.index = start
+				return b.errorSymbol()
+			}
+		} else if identifierRune(r, len(name) == 0) {
+			b.index += size
+		} else {
+			break
+		}
+		name = appendRune(name, r)
+	}
+	if len(name) == 0 {
+		if b.index >= b.length {
+			return b.errorEOF()
+		}
+		return b.errorSymbol()
+	}
+	p.quote(string(name))
+	return nil
+}
+
+// string reads the single- or double-quoted string value
+func (p *json5) string(border byte) (string, error) {
+	b := p.buf
+	b.index++
+	value := make([]byte, 0)
+	for b.index < b.length {
+		c := b.data[b.index]
+		switch c {
+		case border:
+			b.index++
+			return string(value), nil
+		case skipN, skipR:
+			return "", b.errorSymbol()
+		case backslash:
+			b.index++
+			if b.index >= b.length {
+				return "", b.errorEOF()
+			}
+			c = b.data[b.index]
+			b.index++
+			switch c {
+			case 'b':
+				value = append(value, '\b')
+			case 'f':
+				value = append(value, '\f')
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'v':
+				value = append(value, '\v')
+			case '0':
+				if b.index < b.length && b.data[b.index] >= '0' && b.data[b.index] <= '9' {
+					return "", b.errorSymbol()
+				}
+				value = append(value, 0)
+			case 'x':
+				r, err := p.hex(2)
+				if err != nil {
+					return "", err
+				}
+				value = appendRune(value, r)
+			case 'u':
+				r, err := p.hex(4)
+				if err != nil {
+					return "", err
+				}
+				if utf16.IsSurrogate(r) && b.index+1 < b.length && b.data[b.index] == backslash && b.data[b.index+1] == 'u' {
+					index := b.index
+					b.index += 2
+					low, err := p.hex(4)
+					if err != nil {
+						return "", err
+					}
+					if pair := utf16.DecodeRune(r, low); pair != unicode.ReplacementChar {
+						r = pair
+					} else {
+						b.index = index
+					}
+				}
+				value = appendRune(value, r)
+			case skipN:
+				// line continuation
+			case skipR:
+				// line continuation
+				if b.index < b.length && b.data[b.index] == skipN {
+					b.index++
+				}
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				b.index--
+				return "", b.errorSymbol()
+			default:
+				b.index--
+				r, size := utf8.DecodeRune(b.data[b.index:])
+				b.index += size
+				if r != '\u2028' && r != '\u2029' { // line continuation
+					value = appendRune(value, r)
+				}
+			}
+		default:
+			value = append(value, c)
+			b.index++
+		}
+	}
+	return "", b.errorEOF()
+}
+
+// numeric reads the decimal or hexadecimal numeric value with the optional sign
+func (p *json5) numeric() error {
+	b := p.buf
+	start := b.index
+	minus := false
+	if c := b.data[b.index]; c == plus || c == '-' {
+		minus = c == '-'
+		b.index++
+	}
+	if b.index >= b.length {
+		return b.errorEOF()
+	}
+	switch c := b.data[b.index]; {
+	case c == 'I':
+		if err := p.word([]byte("Infinity")); err != nil {
+			return err
+		}
+		return errorRequest("unsupported numeric value '%s'", b.data[start:b.index])
+	case c == 'N':
+		if err := p.word([]byte("NaN")); err != nil {
+			return err
+		}
+		return errorRequest("unsupported numeric value '%s'", b.data[start:b.index])
+	case c == '0' && b.index+1 < b.length && (b.data[b.index+1] == 'x' || b.data[b.index+1] == 'X'):
+		b.index += 2
+		digits := p.digits(isHex)
+		if len(digits) == 0 {
+			return p.eof(io.EOF)
+		}
+		value, _ := new(big.Int).SetString(string(digits), 16)
+		if minus {
+			p.result = append(p.result, '-')
+		}
+		p.result = append(p.result, value.String()...)
+		return nil
+	}
+	integer := p.digits(isDigit)
+	if len(integer) > 1 && integer[0] == '0' {
+		b.index -= len(integer) - 1
+		return b.errorSymbol()
+	}
+	var fraction []byte
+	if b.index < b.length && b.data[b.index] == dot {
+		b.index++
+		fraction = p.digits(isDigit)
+	}
+	if len(integer) == 0 && len(fraction) == 0 {
+		return p.eof(io.EOF)
+	}
+	exponent := b.index
+	if b.index < b.length && (b.data[b.index] == 'e' || b.data[b.index] == 'E') {
+		b.index++
+		if b.index < b.length && (b.data[b.index] == plus || b.data[b.index] == '-') {
+			b.index++
+		}
+		if len(p.digits(isDigit)) == 0 {
+			return p.eof(io.EOF)
+		}
+	}
+
+	if minus {
+		p.result = append(p.result, '-')
+	}
+	if len(integer) == 0 {
+		p.result = append(p.result, '0')
+	} else {
+		p.result = append(p.result, integer...)
+	}
+	if len(fraction) != 0 {
+		p.result = append(p.result, dot)
+		p.result = append(p.result, fraction...)
+	}
+	p.result = append(p.result, b.data[exponent:b.index]...)
+	return nil
+}
+
+// digits reads the sequence of digits
+func (p *json5) digits(fn func(c byte) bool) []byte {
+	b := p.buf
+	start := b.index
+	for b.index < b.length && fn(b.data[b.index]) {
+		b.index++
+	}
+	return b.data[start:b.index]
+}
+
+// hex reads the code point of the given count of hexadecimal digits
+func (p *json5) hex(count int) (r rune, err error) {
+	b := p.buf
+	for i := 0; i < count; i++ {
+		if b.index >= b.length {
+			return 0, b.errorEOF()
+		}
+		c := b.data[b.index]
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, b.errorSymbol()
+		}
+		r = r<<4 | rune(c)
+		b.index++
+	}
+	return r, nil
+}
+
+// word reads the given word and writes it to the result
+func (p *json5) word(word []byte) error {
+	b := p.buf
+	if err := b.word(word); err != nil {
+		return err
+	}
+	b.index++
+	p.result = append(p.result, word...)
+	return nil
+}
+
+// quote writes the double-quoted string to the result
+func (p *json5) quote(value string) {
+	p.result = append(p.result, quotes)
+	p.result = append(p.result, quoteString(value, true)...)
+	p.result = append(p.result, quotes)
+}
+
+// eof returns the error for the io.EOF or the wrong symbol at the current position
+func (p *json5) eof(err error) error {
+	if err != io.EOF {
+		return err
+	}
+	if p.buf.index >= p.buf.length {
+		return p.buf.errorEOF()
+	}
+	return p.buf.errorSymbol()
+}
+
+// identifierRune checks the rune to be valid for the ECMAScript identifier name
+func identifierRune(r rune, first bool) bool {
+	if r == '$' || r == '_' || unicode.IsLetter(r) || unicode.Is(unicode.Nl, r) {
+		return true
+	}
+	return !first && (r == '\u200C' || r == '\u200D' || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc))
+}
+
+func appendRune(data []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	size := utf8.EncodeRune(buf[:], r)
+	return append(data, buf[:size]...)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,233 @@
+package ajson
+
+import (
+	"testing"
+)
+
+func TestUnmarshalJSON5_example(t *testing.T) {
+	// example from https://json5.org
+	data := []byte(`{
+  // comments
+  unquoted: 'and you can quote me on that',
+  singleQuotes: 'I can use "double quotes" here',
+  lineBreaks: "Look, Mom! \
+No \\n's!",
+  hexadecimal: 0xdecaf,
+  leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+  positiveSign: +1,
+  trailingComma: 'in objects', andIn: ['arrays',],
+  "backwardsCompatible": "with JSON",
+}`)
+	expected := `{"unquoted":"and you can quote me on that",` +
+		`"singleQuotes":"I can use \"double quotes\" here",` +
+		`"lineBreaks":"Look, Mom! No \\n's!",` +
+		`"hexadecimal":912559,` +
+		`"leadingDecimalPoint":0.8675309,"andTrailing":8675309,` +
+		`"positiveSign":1,` +
+		`"trailingComma":"in objects","andIn":["arrays"],` +
+		`"backwardsCompatible":"with JSON"}`
+
+	root, err := UnmarshalJSON5(data)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON5() unexpected error: %s", err)
+	}
+	result, err := Marshal(root)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if string(result) != expected {
+		t.Errorf("Marshal() = %s\nexpected %s", result, expected)
+	}
+	if value := root.MustKey("lineBreaks").MustString(); value != `Look, Mom! No \n's!` {
+		t.Errorf("lineBreaks = %q", value)
+	}
+	if value := root.MustKey("hexadecimal").MustNumeric(); value != 0xdecaf {
+		t.Errorf("hexadecimal = %v", value)
+	}
+}
+
+func TestUnmarshalJSON5(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		// region Strict JSON
+		{name: "null", input: `null`, expected: `null`},
+		{name: "bool", input: `[true, false]`, expected: `[true,false]`},
+		{name: "numeric", input: `[0, -1, 1.5, 1e10, 1E-2, -0.5e+3]`, expected: `[0,-1,1.5,1e10,1E-2,-0.5e+3]`},
+		{name: "string", input: `"foo \"bar\" é \/"`, expected: `"foo \"bar\" é /"`},
+		{name: "object", input: `{"a": {"b": [{}]}}`, expected: `{"a":{"b":[{}]}}`},
+		// endregion
+		// region Objects
+		{name: "unquoted key", input: `{a: 1}`, expected: `{"a":1}`},
+		{name: "identifier key", input: `{$_a1: 1, _: 2, $: 3}`, expected: `{"$_a1":1,"_":2,"$":3}`},
+		{name: "unicode key", input: `{ключ: 1, ümlaut: 2}`, expected: `{"ключ":1,"ümlaut":2}`},
+		{name: "escaped key", input: `{\u0061b: 1}`, expected: `{"ab":1}`},
+		{name: "reserved word key", input: `{null: 1, true: 2, if: 3}`, expected: `{"null":1,"true":2,"if":3}`},
+		{name: "single quoted key", input: `{'a b': 1}`, expected: `{"a b":1}`},
+		{name: "trailing comma object", input: `{a: 1, b: 2,}`, expected: `{"a":1,"b":2}`},
+		// endregion
+		// region Arrays
+		{name: "trailing comma array", input: `[1, 2, ]`, expected: `[1,2]`},
+		{name: "nested trailing comma", input: `[[1,],{a:[],},]`, expected: `[[1],{"a":[]}]`},
+		// endregion
+		// region Strings
+		{name: "single quotes", input: `'foo'`, expected: `"foo"`},
+		{name: "single quotes with double", input: `'"foo"'`, expected: `"\"foo\""`},
+		{name: "double quotes with single", input: `"'foo'"`, expected: `"'foo'"`},
+		{name: "escaped single quote", input: `'\'foo\''`, expected: `"'foo'"`},
+		{name: "line continuation LF", input: "'foo\\\nbar'", expected: `"foobar"`},
+		{name: "line continuation CRLF", input: "'foo\\\r\nbar'", expected: `"foobar"`},
+		{name: "line continuation CR", input: "'foo\\\rbar'", expected: `"foobar"`},
+		{name: "line continuation U+2028", input: "'foo\\\u2028bar'", expected: `"foobar"`},
+		{name: "escapes", input: `'\b\f\n\r\t\v\0'`, expected: `"\u0008\u000c\n\r\t\u000b\u0000"`},
+		{name: "hex escape", input: `'\x41\xe9'`, expected: `"Aé"`},
+		{name: "surrogate pair", input: `'\ud83d\ude00'`, expected: `"😀"`},
+		{name: "non escape character", input: `'\a\c\d'`, expected: `"acd"`},
+		{name: "raw tab", input: "'a\tb'", expected: `"a\tb"`},
+		{name: "raw U+2028", input: "'a\u2028b'", expected: "\"a\\u2028b\""},
+		// endregion
+		// region Numbers
+		{name: "hex", input: `[0x0, 0xFF, 0Xa, -0x10, +0x10]`, expected: `[0,255,10,-16,16]`},
+		{name: "big hex", input: `0xFFFFFFFFFFFFFFFFFF`, expected: `4722366482869645213695`},
+		{name: "leading decimal point", input: `[.5, -.5, +.5e1]`, expected: `[0.5,-0.5,0.5e1]`},
+		{name: "trailing decimal point", input: `[5., -5., 5.e1]`, expected: `[5,-5,5e1]`},
+		{name: "positive sign", input: `[+1, +1.5, +0]`, expected: `[1,1.5,0]`},
+		// endregion
+		// region Comments and whitespaces
+		{name: "line comment", input: "// comment\n[1, // one\n2]// end", expected: `[1,2]`},
+		{name: "block comment", input: "/* comment */[1, /* multi\nline */ 2]/**/", expected: `[1,2]`},
+		{name: "comment in object", input: "{/* a */a/* b */:/* c */1/* d */,/* e */}", expected: `{"a":1}`},
+		{name: "whitespaces", input: "\v\f\u00a0\ufeff\u2028\u2029\u3000[1]", expected: `[1]`},
+		{name: "CR comment", input: "[1,// comment\r2]", expected: `[1,2]`},
+		// endregion
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := UnmarshalJSON5([]byte(test.input))
+			if err != nil {
+				t.Fatalf("UnmarshalJSON5() unexpected error: %s", err)
+			}
+			result, err := Marshal(root)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("Marshal() = %s, expected %s", result, test.expected)
+			}
+			if _, err = Unmarshal(result); err != nil {
+				t.Errorf("Unmarshal() result is not a strict JSON: %s", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON5_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "empty", input: ``, err: "unexpected end of file"},
+		{name: "comment only", input: `// comment`, err: "unexpected end of file"},
+		{name: "unclosed comment", input: `/* comment`, err: "unexpected end of file"},
+		{name: "wrong comment", input: `/ comment`, err: "wrong symbol ' ' at 1"},
+		{name: "unclosed object", input: `{a: 1`, err: "unexpected end of file"},
+		{name: "unclosed array", input: `[1,`, err: "unexpected end of file"},
+		{name: "double comma", input: `[1,,]`, err: "wrong symbol ',' at 3"},
+		{name: "leading comma", input: `[,1]`, err: "wrong symbol ',' at 1"},
+		{name: "empty object comma", input: `{,}`, err: "wrong symbol ',' at 1"},
+		{name: "missing colon", input: `{a 1}`, err: "wrong symbol '1' at 3"},
+		{name: "missing comma", input: `[1 2]`, err: "wrong symbol '2' at 3"},
+		{name: "wrong key", input: `{1a: 1}`, err: "wrong symbol '1' at 1"},
+		{name: "wrong key escape", input: `{\u0031: 1}`, err: "wrong symbol '\\' at 1"},
+		{name: "unclosed string", input: `'foo`, err: "unexpected end of file"},
+		{name: "new line in string", input: "'foo\nbar'", err: "wrong symbol '\n' at 4"},
+		{name: "octal escape", input: `'\1'`, err: "wrong symbol '1' at 2"},
+		{name: "zero digit escape", input: `'\01'`, err: "wrong symbol '1' at 3"},
+		{name: "wrong hex escape", input: `'\xZZ'`, err: "wrong symbol 'Z' at 3"},
+		{name: "wrong unicode escape", input: `'\u12'`, err: "wrong symbol ''' at 5"},
+		{name: "leading zero", input: `01`, err: "wrong symbol '1' at 1"},
+		{name: "wrong hex", input: `0x`, err: "unexpected end of file"},
+		{name: "single dot", input: `.`, err: "unexpected end of file"},
+		{name: "sign only", input: `[+]`, err: "wrong symbol ']' at 2"},
+		{name: "wrong exponent", input: `1e`, err: "unexpected end of file"},
+		{name: "Infinity", input: `[-Infinity]`, err: "wrong request: unsupported numeric value '-Infinity'"},
+		{name: "NaN", input: `NaN`, err: "wrong request: unsupported numeric value 'NaN'"},
+		{name: "wrong word", input: `nul`, err: "unexpected end of file"},
+		{name: "wrong literal", input: `undefined`, err: "wrong symbol 'u' at 0"},
+		{name: "trailing data", input: `[1] 2`, err: "wrong symbol '2' at 4"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := UnmarshalJSON5([]byte(test.input))
+			if err == nil {
+				t.Fatalf("UnmarshalJSON5() expected error, got %s", root)
+			}
+			if err.Error() != test.err {
+				t.Errorf("UnmarshalJSON5() error = %q, expected %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_relaxedNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // result of UnmarshalJSON5
+		json5Err string
+		err      string // error of the strict Unmarshal
+		index    int    // Error.Index of the strict Unmarshal
+	}{
+		{input: `+5`, expected: `5`, err: "wrong symbol '+' at 0", index: 0},
+		{input: `[+5]`, expected: `[5]`, err: "wrong symbol '+' at 1", index: 1},
+		{input: `+0.5e1`, expected: `0.5e1`, err: "wrong symbol '+' at 0", index: 0},
+		{input: `0x1F`, expected: `31`, err: "wrong symbol 'x' at 1", index: 1},
+		{input: `0X1f`, expected: `31`, err: "wrong symbol 'X' at 1", index: 1},
+		{input: `-0x10`, expected: `-16`, err: "wrong symbol 'x' at 2", index: 2},
+		{input: `+0x10`, expected: `16`, err: "wrong symbol '+' at 0", index: 0},
+		{input: `.5`, expected: `0.5`, err: "wrong symbol '.' at 0", index: 0},
+		{input: `-.5`, expected: `-0.5`, err: "wrong symbol '.' at 1", index: 1},
+		{input: `+.5`, expected: `0.5`, err: "wrong symbol '+' at 0", index: 0},
+		{input: `5.`, expected: `5`, err: "wrong symbol '5' at 0", index: 0},
+		{input: `[5.]`, expected: `[5]`, err: "wrong symbol ']' at 3", index: 3},
+		{input: `-5.e2`, expected: `-5e2`, err: "wrong symbol 'e' at 3", index: 3},
+		{input: `+`, json5Err: "unexpected end of file", err: "wrong symbol '+' at 0", index: 0},
+		{input: `++1`, json5Err: "wrong symbol '+' at 1", err: "wrong symbol '+' at 0", index: 0},
+		{input: `0x`, json5Err: "unexpected end of file", err: "wrong symbol 'x' at 1", index: 1},
+		{input: `0x1.5`, json5Err: "wrong symbol '.' at 3", err: "wrong symbol 'x' at 1", index: 1},
+		{input: `0xG`, json5Err: "wrong symbol 'G' at 2", err: "wrong symbol 'x' at 1", index: 1},
+		{input: `.`, json5Err: "unexpected end of file", err: "wrong symbol '.' at 0", index: 0},
+		{input: `1e`, json5Err: "unexpected end of file", err: "wrong symbol '1' at 0", index: 0},
+		{input: `[1e+]`, json5Err: "wrong symbol ']' at 4", err: "wrong symbol ']' at 4", index: 4},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			if root, err := Unmarshal([]byte(test.input)); err == nil {
+				t.Errorf("Unmarshal() expected error, got %s", root)
+			} else if err.Error() != test.err {
+				t.Errorf("Unmarshal() error = %q, expected %q", err, test.err)
+			} else if typed, ok := err.(Error); !ok || typed.Index != test.index || typed.Offset != test.index {
+				t.Errorf("Unmarshal() error = %#v, expected Index %d", err, test.index)
+			}
+			root, err := UnmarshalJSON5([]byte(test.input))
+			if test.json5Err != "" {
+				if err == nil || err.Error() != test.json5Err {
+					t.Errorf("UnmarshalJSON5() error = %v, expected %q", err, test.json5Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalJSON5() unexpected error: %s", err)
+			}
+			result, err := Marshal(root)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("Marshal() = %s, expected %s", result, test.expected)
+			}
+		})
+	}
+}
@@ -46,6 +46,18 @@ import (
 //	?()     applies a filter (script) expression.
 //	()      script expression, using the underlying script engine.
 //
+// Inside the script expressions the identifier @index can be used to get the index of the current element, i.e.
+//
+//	$.store.book[?(@index % 2 == 0)].title
+//
+// It is only meaningful for the children of an Array, for any other element @index will be null.
+//
+// The JSONPath can be applied to any node of the tree, not only to the root: in this case $ refers to the given node,
+// so the recursive descent, the wildcards and the $ inside the script expressions are scoped to its subtree, i.e.
+//
+//	books := root.MustKey("store").MustKey("book")
+//	result, _ := books.JSONPath(`$..[?(@.price < $[0].price)].title`)
+//
 // # JSONPath Script engine
 //
 // # Predefined constant
@@ -113,6 +125,11 @@ import (
 //
 // Package has several predefined functions. You are free to add new one with AddFunction
 //
+// Function could be called for the path in the method style, i.e. `@.price.isNumber()` is the same as `isNumber(@.price)`.
+//
+// String values could be filtered by the methods with the string literal argument: `startsWith`, `endsWith` and
+// `contains`, i.e. `@.name.startsWith('At')`. The result is false for non-string values.
+//
 //	abs          math.Abs          integers, floats
 //	acos         math.Acos         integers, floats
 //	acosh        math.Acosh        integers, floats
@@ -135,6 +152,12 @@ import (
 //	factorial    N!                unsigned integer
 //	floor        math.Floor        integers, floats
 //	gamma        math.Gamma        integers, floats
+//	isArray      type check        any
+//	isBool       type check        any
+//	isNull       type check        any
+//	isNumber     type check        any
+//	isObject     type check        any
+//	isString     type check        any
 //	j0           math.J0           integers, floats
 //	j1           math.J1           integers, floats
 //	length       len               array
@@ -155,7 +178,7 @@ import (
 //	trunc        math.Trunc        integers, floats
 //	y0           math.Y0           integers, floats
 //	y1           math.Y1           integers, floats
-func JSONPath(data []byte, path string) (result []*Node, err error) {
+func JSONPath(data []byte, path string, options ...JSONPathOption) (result []*Node, err error) {
 	commands, err := ParseJSONPath(path)
 	if err != nil {
 		return nil, err
@@ -164,7 +187,7 @@ func JSONPath(data []byte, path string) (result []*Node, err error) {
 	if err != nil {
 		return nil, err
 	}
-	return ApplyJSONPath(node, commands)
+	return ApplyJSONPath(node, commands, options...)
 }
 
 // Paths returns calculated paths of underlying nodes
@@ -305,18 +328,122 @@ func ParseJSONPath(path string) (result []string, err error) {
 	return
 }
 
+// JSONPathOption is a functional option to configure the evaluation of the JSONPath
+type JSONPathOption func(options *jsonPathOptions)
+
+type jsonPathOptions struct {
+	decimalComparison bool
+}
+
+// DecimalComparison makes the JSONPath script operations ==, !=, <, <=, >, >= to compare the numeric values exactly,
+// as decimals, instead of float64 values. It's set per query, so queries with and without it could run concurrently.
+//
+// Numeric operands are compared as decimals parsed from their source, if the node was parsed and wasn't changed,
+// e.g. values from the JSON document and numeric literals of the script. Calculated values (like results of
+// the arithmetic operations and functions) are compared by their shortest decimal representation of float64, so
+// `0.1 + 0.2 == 0.3` is still false. Operations replaced with AddOperation are not used for the numeric operands.
+func DecimalComparison() JSONPathOption {
+	return func(options *jsonPathOptions) {
+		options.decimalComparison = true
+	}
+}
+
+// newJSONPathOptions applies the options to the default configuration
+func newJSONPathOptions(options []JSONPathOption) *jsonPathOptions {
+	opts := new(jsonPathOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
 // ApplyJSONPath function applies commands sequence parse from JSONPath.
 // Example:
 //
 //	commands := []string{"$", "store", "book", "?(@.price < 10)", "title"}
 //	result, _ := ApplyJSONPath(node, commands)
-func ApplyJSONPath(node *Node, commands []string) (result []*Node, err error) {
+//
+// The first command "$" refers to the given node, even if it is not the root of the document.
+//
+// If nothing was found, the result is an empty slice with nil error. Any error during evaluation of the commands
+// (wrong request, wrong type or broken value of the node) is returned as a non-nil error.
+func ApplyJSONPath(node *Node, commands []string, options ...JSONPathOption) (result []*Node, err error) {
+	return applyJSONPath(node, commands, newJSONPathOptions(options))
+}
+
+func applyJSONPath(node *Node, commands []string, options *jsonPathOptions) (result []*Node, err error) {
 	if node == nil {
 		return nil, nil
 	}
 	result = make([]*Node, 0)
+	var temporary []*Node
+	for i, cmd := range commands {
+		temporary = make([]*Node, 0)
+		err = applyCommand(node, i, cmd, result, options, func(value *Node) bool {
+			temporary = append(temporary, value)
+			return true
+		})
+		if err != nil {
+			return
+		}
+		result = temporary
+	}
+	return
+}
+
+// CompiledPath is a parsed JSONPath, which can be applied to any node many times without parsing it again
+type CompiledPath []string
+
+// CompileJSONPath parses the JSONPath to be applied later
+func CompileJSONPath(path string) (CompiledPath, error) {
+	commands, err := ParseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// Apply returns slice of founded elements for the given node, same as ApplyJSONPath
+func (c CompiledPath) Apply(node *Node, options ...JSONPathOption) ([]*Node, error) {
+	return ApplyJSONPath(node, c, options...)
+}
+
+// Each calls fn for every element found for the given node, until fn returns false.
+//
+// Final elements are passed to fn as soon as they are found, without collecting them into the slice, so only
+// the intermediate results are kept in memory. Evaluation stops as soon as one of the intermediate steps has found nothing.
+func (c CompiledPath) Each(node *Node, fn func(node *Node) bool, options ...JSONPathOption) error {
+	return eachJSONPath(node, c, newJSONPathOptions(options), fn)
+}
+
+// eachJSONPath applies commands sequence and passes each element of the last command result to the emit function
+func eachJSONPath(node *Node, commands []string, options *jsonPathOptions, emit func(value *Node) bool) (err error) {
+	if node == nil || len(commands) == 0 {
+		return nil
+	}
+	last := len(commands) - 1
+	result := make([]*Node, 0)
+	for i, cmd := range commands[:last] {
+		temporary := make([]*Node, 0)
+		err = applyCommand(node, i, cmd, result, options, func(value *Node) bool {
+			temporary = append(temporary, value)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if len(temporary) == 0 {
+			return nil
+		}
+		result = temporary
+	}
+	return applyCommand(node, last, commands[last], result, options, emit)
+}
+
+// applyCommand applies a single command of the JSONPath to the result of the previous commands,
+// each found element is passed to the emit function, evaluation stops if emit returns false.
+func applyCommand(node *Node, i int, cmd string, result []*Node, options *jsonPathOptions, emit func(value *Node) bool) (err error) {
 	var (
-		temporary   []*Node
 		keys        []string
 		ikeys       [3]int
 		fkeys       [3]float64
@@ -328,263 +455,363 @@ func ApplyJSONPath(node *Node, commands []string) (result []*Node, err error) {
 		tokens      tokens
 		expr        rpn
 	)
-	for i, cmd := range commands {
-		tokens, err = newBuffer([]byte(cmd)).tokenize()
-		if err != nil {
-			return
-		}
-		switch {
-		case cmd == "$": // root element
-			if i == 0 {
-				result = append(result, node.root())
-			}
-		case cmd == "@": // current element
-			if i == 0 {
-				result = append(result, node)
+	tokens, err = newBuffer([]byte(cmd)).tokenize()
+	if err != nil {
+		return
+	}
+	switch {
+	case cmd == "$": // root element
+		if i == 0 {
+			if !emit(node) {
+				return nil
 			}
-		case cmd == "..": // recursive descent
-			temporary = make([]*Node, 0)
+		} else {
 			for _, element := range result {
-				temporary = append(temporary, recursiveChildren(element)...)
+				if !emit(element) {
+					return nil
+				}
+			}
+		}
+	case cmd == "@": // current element
+		if i == 0 {
+			if !emit(node) {
+				return nil
 			}
-			result = append(result, temporary...)
-		case cmd == "*": // wildcard
-			temporary = make([]*Node, 0)
+		} else {
 			for _, element := range result {
-				temporary = append(temporary, element.Inheritors()...)
+				if !emit(element) {
+					return nil
+				}
 			}
-			result = temporary
-		case tokens.exists(":"): // array slice operator
-			if tokens.count(":") > 3 {
-				return nil, errorRequest("slice must contains no more than 2 colons, got '%s'", cmd)
+		}
+	case cmd == "..": // recursive descent
+		for _, element := range result {
+			if !emit(element) {
+				return nil
+			}
+		}
+		for _, element := range result {
+			for _, temp = range recursiveChildren(element) {
+				if !emit(temp) {
+					return nil
+				}
+			}
+		}
+	case cmd == "*": // wildcard
+		for _, element := range result {
+			for _, temp = range element.Inheritors() {
+				if !emit(temp) {
+					return nil
+				}
 			}
-			keys = tokens.slice(":")
+		}
+	case tokens.exists(":"): // array slice operator
+		if tokens.count(":") > 3 {
+			return errorRequest("slice must contains no more than 2 colons, got '%s'", cmd)
+		}
+		keys = tokens.slice(":")
 
-			temporary = make([]*Node, 0)
-			for _, element := range result {
-				if element.IsArray() && element.Size() > 0 {
-					if fkeys[0], err = getNumberIndex(element, keys[0], math.NaN()); err != nil {
-						return nil, errorRequest("wrong request: %s", cmd)
-					}
-					if fkeys[1], err = getNumberIndex(element, keys[1], math.NaN()); err != nil {
-						return nil, errorRequest("wrong request: %s", cmd)
-					}
-					if len(keys) < 3 {
-						fkeys[2] = 1
-					} else if fkeys[2], err = getNumberIndex(element, keys[2], 1); err != nil {
-						return nil, errorRequest("wrong request: %s", cmd)
-					}
+		for _, element := range result {
+			if element.IsArray() && element.Size() > 0 {
+				if fkeys[0], err = getNumberIndex(element, node, keys[0], math.NaN(), options); err != nil {
+					return errorRequest("wrong request: %s", cmd)
+				}
+				if fkeys[1], err = getNumberIndex(element, node, keys[1], math.NaN(), options); err != nil {
+					return errorRequest("wrong request: %s", cmd)
+				}
+				if len(keys) < 3 {
+					fkeys[2] = 1
+				} else if fkeys[2], err = getNumberIndex(element, node, keys[2], 1, options); err != nil {
+					return errorRequest("wrong request: %s", cmd)
+				}
 
-					ikeys[2] = int(fkeys[2])
-					if ikeys[2] == 0 {
-						return nil, errorRequest("wrong request: %s", cmd)
-					}
+				ikeys[2] = int(fkeys[2])
+				if ikeys[2] == 0 {
+					return errorRequest("wrong request: %s", cmd)
+				}
 
-					if math.IsNaN(fkeys[0]) {
-						if ikeys[2] > 0 {
-							ikeys[0] = 0
-						} else {
-							ikeys[0] = element.Size() - 1
-						}
+				if math.IsNaN(fkeys[0]) {
+					if ikeys[2] > 0 {
+						ikeys[0] = 0
 					} else {
-						ikeys[0] = getPositiveIndex(int(fkeys[0]), element.Size())
+						ikeys[0] = element.Size() - 1
 					}
-					if math.IsNaN(fkeys[1]) {
-						if ikeys[2] > 0 {
-							ikeys[1] = element.Size()
-						} else {
-							ikeys[1] = -1
-						}
+				} else {
+					ikeys[0] = getPositiveIndex(int(fkeys[0]), element.Size())
+				}
+				if math.IsNaN(fkeys[1]) {
+					if ikeys[2] > 0 {
+						ikeys[1] = element.Size()
 					} else {
-						ikeys[1] = getPositiveIndex(int(fkeys[1]), element.Size())
+						ikeys[1] = -1
 					}
+				} else {
+					ikeys[1] = getPositiveIndex(int(fkeys[1]), element.Size())
+				}
 
-					if ikeys[2] > 0 {
-						if ikeys[0] < 0 {
-							ikeys[0] = 0
-						}
-						if ikeys[1] > element.Size() {
-							ikeys[1] = element.Size()
-						}
+				if ikeys[2] > 0 {
+					if ikeys[0] < 0 {
+						ikeys[0] = 0
+					}
+					if ikeys[1] > element.Size() {
+						ikeys[1] = element.Size()
+					}
 
-						for i := ikeys[0]; i < ikeys[1]; i += ikeys[2] {
-							value, ok := element.children[strconv.Itoa(i)]
-							if ok {
-								temporary = append(temporary, value)
+					for i := ikeys[0]; i < ikeys[1]; i += ikeys[2] {
+						value, ok := element.children[strconv.Itoa(i)]
+						if ok {
+							if !emit(value) {
+								return nil
 							}
 						}
-					} else if ikeys[2] < 0 {
-						if ikeys[0] > element.Size() {
-							ikeys[0] = element.Size()
-						}
-						if ikeys[1] < -1 {
-							ikeys[1] = -1
-						}
+					}
+				} else if ikeys[2] < 0 {
+					if ikeys[0] > element.Size() {
+						ikeys[0] = element.Size()
+					}
+					if ikeys[1] < -1 {
+						ikeys[1] = -1
+					}
 
-						for i := ikeys[0]; i > ikeys[1]; i += ikeys[2] {
-							value, ok := element.children[strconv.Itoa(i)]
-							if ok {
-								temporary = append(temporary, value)
+					for i := ikeys[0]; i > ikeys[1]; i += ikeys[2] {
+						value, ok := element.children[strconv.Itoa(i)]
+						if ok {
+							if !emit(value) {
+								return nil
 							}
 						}
 					}
 				}
 			}
-			result = temporary
-		case strings.HasPrefix(cmd, "?(") && strings.HasSuffix(cmd, ")"): // applies a filter (script) expression
-			expr, err = newBuffer([]byte(cmd[2 : len(cmd)-1])).rpn()
-			if err != nil {
-				return nil, errorRequest("wrong request: %s", cmd)
-			}
-			temporary = make([]*Node, 0)
-			for _, element := range result {
-				if element.isContainer() {
-					for _, temp = range element.Inheritors() {
-						value, err = eval(temp, expr, cmd)
+		}
+	case strings.HasPrefix(cmd, "?(") && strings.HasSuffix(cmd, ")"): // applies a filter (script) expression
+		expr, err = newBuffer([]byte(cmd[2 : len(cmd)-1])).rpn()
+		if err != nil {
+			return errorRequest("wrong request: %s", cmd)
+		}
+		for _, element := range result {
+			if element.isContainer() {
+				for _, temp = range element.Inheritors() {
+					value, err = eval(temp, node, expr, cmd, options)
+					if err != nil {
+						return errorRequest("wrong request: %s", cmd)
+					}
+					if value != nil {
+						ok, err = boolean(value)
 						if err != nil {
-							return nil, errorRequest("wrong request: %s", cmd)
+							return errorRequest("wrong request: %s", cmd)
 						}
-						if value != nil {
-							ok, err = boolean(value)
-							if err != nil || !ok {
-								continue
-							}
-							temporary = append(temporary, temp)
+						if !ok {
+							continue
+						}
+						if !emit(temp) {
+							return nil
 						}
 					}
 				}
 			}
-			result = temporary
-		case strings.HasPrefix(cmd, "(") && strings.HasSuffix(cmd, ")"): // script expression, using the underlying script engine
-			expr, err = newBuffer([]byte(cmd[1 : len(cmd)-1])).rpn()
+		}
+	case strings.HasPrefix(cmd, "(") && strings.HasSuffix(cmd, ")"): // script expression, using the underlying script engine
+		expr, err = newBuffer([]byte(cmd[1 : len(cmd)-1])).rpn()
+		if err != nil {
+			return errorRequest("wrong request: %s", cmd)
+		}
+		for _, element := range result {
+			if !element.isContainer() {
+				continue
+			}
+			temp, err = eval(element, node, expr, cmd, options)
 			if err != nil {
-				return nil, errorRequest("wrong request: %s", cmd)
+				return errorRequest("wrong request: %s", cmd)
 			}
-			temporary = make([]*Node, 0)
-			for _, element := range result {
-				if !element.isContainer() {
-					continue
-				}
-				temp, err = eval(element, expr, cmd)
-				if err != nil {
-					return nil, errorRequest("wrong request: %s", cmd)
-				}
-				if temp != nil {
-					value = nil
-					switch temp.Type() {
-					case String:
-						key, err = temp.GetString()
-						if err != nil {
-							return nil, errorRequest("wrong type convert: %s", err.Error())
-						}
-						value = element.children[key]
-					case Numeric:
-						num, err = temp.getInteger()
-						if err == nil { // INTEGER
-							if num < 0 {
-								key = strconv.Itoa(element.Size() - num)
-							} else {
-								key = strconv.Itoa(num)
-							}
+			if temp != nil {
+				value = nil
+				switch temp.Type() {
+				case String:
+					key, err = temp.GetString()
+					if err != nil {
+						return errorRequest("wrong type convert: %s", err.Error())
+					}
+					value = element.children[key]
+				case Numeric:
+					num, err = temp.getInteger()
+					if err == nil { // INTEGER
+						if num < 0 {
+							key = strconv.Itoa(element.Size() - num)
 						} else {
-							float, err = temp.GetNumeric()
-							if err != nil {
-								return nil, errorRequest("wrong type convert: %s", err.Error())
-							}
-							key = strconv.FormatFloat(float, 'g', -1, 64)
+							key = strconv.Itoa(num)
 						}
-						value = element.children[key]
-					case Bool:
-						ok, err = temp.GetBool()
+					} else {
+						float, err = temp.GetNumeric()
 						if err != nil {
-							return nil, errorRequest("wrong type convert: %s", err.Error())
+							return errorRequest("wrong type convert: %s", err.Error())
 						}
-						if ok {
-							temporary = append(temporary, element.Inheritors()...)
+						key = strconv.FormatFloat(float, 'g', -1, 64)
+					}
+					value = element.children[key]
+				case Bool:
+					ok, err = temp.GetBool()
+					if err != nil {
+						return errorRequest("wrong type convert: %s", err.Error())
+					}
+					if ok {
+						for _, temp = range element.Inheritors() {
+							if !emit(temp) {
+								return nil
+							}
 						}
-						continue
-						// case Array: // get all keys from element via array values
 					}
-					if value != nil {
-						temporary = append(temporary, value)
+					continue
+					// case Array: // get all keys from element via array values
+				}
+				if value != nil {
+					if !emit(value) {
+						return nil
 					}
 				}
 			}
-			result = temporary
-		default: // try to get by key & Union
-			if tokens.exists(",") {
-				keys = tokens.slice(",")
-				if len(keys) == 0 {
-					return nil, errorRequest("wrong request: %s", cmd)
-				}
-			} else {
-				keys = []string{cmd}
+		}
+	default: // try to get by key & Union
+		if tokens.exists(",") {
+			keys = tokens.slice(",")
+			if len(keys) == 0 {
+				return errorRequest("wrong request: %s", cmd)
 			}
+		} else {
+			keys = []string{cmd}
+		}
 
-			temporary = make([]*Node, 0)
-			for _, key = range keys { // fixme
-				for _, element := range result {
-					if element.IsArray() {
-						if key == "length" || key == "'length'" || key == "\"length\"" {
-							value, err = functions["length"](element)
-							if err != nil {
-								return
-							}
-							ok = true
-						} else if strings.HasPrefix(key, "(") && strings.HasSuffix(key, ")") {
-							fkeys[0], err = getNumberIndex(element, key, math.NaN())
-							if err != nil {
-								return nil, err
-							}
-							if math.IsNaN(fkeys[0]) {
-								return nil, errorRequest("wrong request: %s", cmd)
-							}
-							if element.Size() == 0 {
-								ok = false
-							} else {
-								num = getPositiveIndex(int(fkeys[0]), element.Size())
-								key = strconv.Itoa(num)
-								value, ok = element.children[key]
-							}
+		for _, key = range keys { // fixme
+			for _, element := range result {
+				if element.IsArray() {
+					if key == "length" || key == "'length'" || key == "\"length\"" {
+						value, err = functions["length"](element)
+						if err != nil {
+							return
+						}
+						ok = true
+					} else if strings.HasPrefix(key, "(") && strings.HasSuffix(key, ")") {
+						fkeys[0], err = getNumberIndex(element, node, key, math.NaN(), options)
+						if err != nil {
+							return err
+						}
+						if math.IsNaN(fkeys[0]) {
+							return errorRequest("wrong request: %s", cmd)
+						}
+						if element.Size() == 0 {
+							ok = false
 						} else {
-							key, _ = str(key)
-							num, err = strconv.Atoi(key)
-							if err != nil || element.Size() == 0 {
-								ok = false
-								err = nil
-							} else {
-								num = getPositiveIndex(num, element.Size())
-								key = strconv.Itoa(num)
-								value, ok = element.children[key]
-							}
+							num = getPositiveIndex(int(fkeys[0]), element.Size())
+							key = strconv.Itoa(num)
+							value, ok = element.children[key]
+						}
+					} else {
+						if key, ok = str(key); !ok {
+							return errorRequest("wrong request: %s", cmd)
+						}
+						num, err = strconv.Atoi(key)
+						if err != nil || element.Size() == 0 {
+							ok = false
+							err = nil
+						} else {
+							num = getPositiveIndex(num, element.Size())
+							key = strconv.Itoa(num)
+							value, ok = element.children[key]
 						}
+					}
 
-					} else if element.IsObject() {
-						key, _ = str(key)
-						value, ok = element.children[key]
+				} else if element.IsObject() {
+					if key, ok = str(key); !ok {
+						return errorRequest("wrong request: %s", cmd)
 					}
-					if ok {
-						temporary = append(temporary, value)
-						ok = false
+					value, ok = element.children[key]
+				}
+				if ok {
+					if !emit(value) {
+						return nil
 					}
+					ok = false
 				}
 			}
-			result = temporary
 		}
 	}
 	return
 }
 
 // Eval evaluate expression `@.price == 19.95 && @.color == 'red'` to the result value i.e. Bool(true), Numeric(3.14), etc.
-func Eval(node *Node, cmd string) (result *Node, err error) {
+// Both `@` and `$` in the expression refer to the given node, as `$` does for Node.JSONPath, even if it is not the root
+// of the document.
+func Eval(node *Node, cmd string, options ...JSONPathOption) (result *Node, err error) {
 	calc, err := newBuffer([]byte(cmd)).rpn()
 	if err != nil {
 		return nil, err
 	}
-	return eval(node, calc, cmd)
+	return eval(node, node, calc, cmd, newJSONPathOptions(options))
+}
+
+// methodFunction returns the Function, if the last command of the path is a method-style call of it,
+// example: `@.value.isNumber()` is the same as `isNumber(@.value)`.
+func methodFunction(commands []string) Function {
+	if len(commands) < 2 {
+		return nil
+	}
+	last := commands[len(commands)-1]
+	if !strings.HasSuffix(last, "()") {
+		return nil
+	}
+	return functions[strings.ToLower(strings.TrimSuffix(last, "()"))]
+}
+
+// stringMethods are the method-style functions with the string literal argument, the result is false
+// for non-string value, example: `@.name.startsWith('At')`.
+var stringMethods = map[string]func(value, argument string) bool{
+	"startswith": strings.HasPrefix,
+	"endswith":   strings.HasSuffix,
+	"contains":   strings.Contains,
 }
 
-func eval(node *Node, expression rpn, cmd string) (result *Node, err error) {
+// stringMethod splits the path with the call of one of stringMethods at the end into the path and the Function,
+// method is nil if the path has no such call.
+func stringMethod(exp string) (path string, method Function) {
+	if !strings.HasSuffix(exp, ")") {
+		return exp, nil
+	}
+	lower := strings.ToLower(exp)
+	start := len(exp)
+	for name, fn := range stringMethods {
+		i := strings.Index(lower, "."+name+"(")
+		if i < 0 || i >= start {
+			continue
+		}
+		raw := exp[i+len(name)+2 : len(exp)-1]
+		if len(raw) < 2 || (raw[0] != quote && raw[0] != quotes) || raw[len(raw)-1] != raw[0] {
+			continue
+		}
+		argument, ok := unquote([]byte(raw), raw[0])
+		if !ok {
+			continue
+		}
+		start = i
+		name, fn := name, fn
+		method = func(node *Node) (result *Node, err error) {
+			if node == nil || !node.IsString() {
+				return valueNode(nil, name, Bool, false), nil
+			}
+			value, err := node.GetString()
+			if err != nil {
+				return nil, err
+			}
+			return valueNode(nil, name, Bool, fn(value, argument)), nil
+		}
+	}
+	return exp[:start], method
+}
+
+// indexIdentifier is the identifier of the current element index in the script expressions
+const indexIdentifier = "@index"
+
+// eval evaluates expression for the current element node, `$` in the expression refers to the root node
+func eval(node, root *Node, expression rpn, cmd string, options *jsonPathOptions) (result *Node, err error) {
 	if node == nil {
 		return nil, nil
 	}
@@ -613,28 +840,62 @@ func eval(node *Node, expression rpn, cmd string) (result *Node, err error) {
 			if size < 2 {
 				return nil, errorRequest("wrong request: %s", cmd)
 			}
+			if options.decimalComparison {
+				if temp, ok, err = decimalOperation(exp, stack[size-2], stack[size-1]); ok {
+					if err != nil {
+						return
+					}
+					stack[size-2] = temp
+					stack = stack[:size-1]
+					continue
+				}
+			}
 			stack[size-2], err = op(stack[size-2], stack[size-1])
 			if err != nil {
 				return
 			}
 			stack = stack[:size-1]
 		} else if len(exp) > 0 {
-			if exp[0] == dollar || exp[0] == at {
-				commands, err = ParseJSONPath(exp)
+			if exp == indexIdentifier {
+				if node.parent.IsArray() {
+					stack = append(stack, valueNode(nil, "index", Numeric, float64(node.Index())))
+				} else {
+					stack = append(stack, valueNode(nil, "index", Null, nil))
+				}
+			} else if exp[0] == dollar || exp[0] == at {
+				path, method := stringMethod(exp)
+				commands, err = ParseJSONPath(path)
 				if err != nil {
 					return
 				}
-				slice, err = ApplyJSONPath(node, commands)
+				if method == nil {
+					method = methodFunction(commands)
+					if method != nil {
+						commands = commands[:len(commands)-1]
+					}
+				}
+				if exp[0] == dollar {
+					slice, err = applyJSONPath(root, commands, options)
+				} else {
+					slice, err = applyJSONPath(node, commands, options)
+				}
 				if err != nil {
 					return
 				}
 				if len(slice) > 1 { // array given
-					stack = append(stack, ArrayNode("", slice))
+					temp = ArrayNode("", slice)
 				} else if len(slice) == 1 {
-					stack = append(stack, slice[0])
+					temp = slice[0]
 				} else { // no data found
-					stack = append(stack, nil)
+					temp = nil
 				}
+				if method != nil {
+					temp, err = method(temp)
+					if err != nil {
+						return
+					}
+				}
+				stack = append(stack, temp)
 			} else if constant, ok := constants[strings.ToLower(exp)]; ok {
 				stack = append(stack, constant)
 			} else {
@@ -670,7 +931,7 @@ func eval(node *Node, expression rpn, cmd string) (result *Node, err error) {
 	return nil, errorRequest("wrong request: %s", cmd)
 }
 
-func getNumberIndex(element *Node, input string, Default float64) (result float64, err error) {
+func getNumberIndex(element, root *Node, input string, Default float64, options *jsonPathOptions) (result float64, err error) {
 	var integer int
 	if input == "" {
 		result = Default
@@ -683,7 +944,7 @@ func getNumberIndex(element *Node, input string, Default float64) (result float6
 		if err != nil {
 			return 0, err
 		}
-		temp, err = eval(element, expr, input)
+		temp, err = eval(element, root, expr, input, options)
 		if err != nil {
 			return
 		}
@@ -0,0 +1,17 @@
+//go:build go1.23
+// +build go1.23
+
+package ajson
+
+import "iter"
+
+// Iter returns an iterator over the elements found for the given node, see CompiledPath.Each for details.
+// Requires Go 1.23 or later, for the older versions use CompiledPath.Each instead.
+//
+// The iterator can't report an error, so the iteration just stops on the evaluation error;
+// use CompiledPath.Each, if errors matter.
+func (c CompiledPath) Iter(node *Node, options ...JSONPathOption) iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		_ = c.Each(node, yield, options...)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build go1.23
+// +build go1.23
+
+package ajson
+
+import "testing"
+
+func TestCompiledPath_Iter(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	path, err := CompileJSONPath("$..price")
+	if err != nil {
+		t.Errorf("CompileJSONPath() unexpected error: %s", err)
+		return
+	}
+	result := make([]*Node, 0)
+	for node := range path.Iter(root) {
+		result = append(result, node)
+		if len(result) == 3 {
+			break
+		}
+	}
+	expected := "[$['store']['bicycle']['price'], $['store']['book'][0]['price'], $['store']['book'][1]['price']]"
+	if fullPath(result) != expected {
+		t.Errorf("Iter() = %s, expected %s", fullPath(result), expected)
+	}
+}
@@ -190,6 +190,20 @@ func TestJsonPath(t *testing.T) {
 		{name: "calculated 11", path: "$.store.bicycle.price[?(@ > 0)]", expected: `[]`},
 		{name: "calculated 12", path: "$.store.book[?(@.price * 0 = 0)]", wantErr: true},
 
+		{name: "index even", path: "$.store.book[?(@index % 2 == 0)]", expected: "[$['store']['book'][0], $['store']['book'][2]]"},
+		{name: "index and value", path: "$.store.book[?(@index > 0 && @.price < 10)].title", expected: "[$['store']['book'][2]['title']]"},
+		{name: "index in object", path: "$.store[?(@index == null)]", expected: "[$['store']['bicycle'], $['store']['book']]"},
+		{name: "index key", path: "$..[?(@.index)]", expected: "[]"},
+
+		{name: "type function", path: "$.store.book[?(isNumber(@.price))].title", expected: "[$['store']['book'][0]['title'], $['store']['book'][1]['title'], $['store']['book'][2]['title'], $['store']['book'][3]['title']]"},
+		{name: "type method", path: "$..[?(@.isbn.isString())]", expected: "[$['store']['book'][2], $['store']['book'][3]]"},
+		{name: "type method root", path: "$..[?(@.isObject())]", expected: "[$['store'], $['store']['bicycle'], $['store']['book'][0], $['store']['book'][1], $['store']['book'][2], $['store']['book'][3]]"},
+		{name: "type method array", path: "$.*[?(@.isArray())]", expected: "[$['store']['book']]"},
+		{name: "type method missing", path: "$..[?(@.missing.isNull())]", expected: "[]"},
+		{name: "type method negation", path: "$.store.book[?(not(@.isbn.isString()))]", expected: "[$['store']['book'][0], $['store']['book'][1]]"},
+		{name: "type method case", path: "$.store.book[?(@.price.ISNUMBER() && @.price > 10)]", expected: "[$['store']['book'][1], $['store']['book'][3]]"},
+		{name: "method other function", path: "$.store.book[?(@.price.floor() == 8)]", expected: "[$['store']['book'][0], $['store']['book'][2]]"},
+
 		{name: "$.store.book[*].author", path: "$.store.book[*].author", expected: "[$['store']['book'][0]['author'], $['store']['book'][1]['author'], $['store']['book'][2]['author'], $['store']['book'][3]['author']]"},
 		{name: "$..author", path: "$..author", expected: "[$['store']['book'][0]['author'], $['store']['book'][1]['author'], $['store']['book'][2]['author'], $['store']['book'][3]['author']]"},
 		{name: "$.store..price", path: "$.store..price", expected: "[$['store']['bicycle']['price'], $['store']['book'][0]['price'], $['store']['book'][1]['price'], $['store']['book'][2]['price'], $['store']['book'][3]['price']]"},
@@ -211,6 +225,46 @@ func TestJsonPath(t *testing.T) {
 	}
 }
 
+func TestJSONPath_stringMethods(t *testing.T) {
+	data := []byte(`[
+		{"latitude":1,"longitude":2,"name":"At [1, 2]"},
+		{"other":"value"},
+		null,
+		{"internal":{"name":"At [11, 22]","longitude":22,"latitude":11}},
+		{"name":"unknown"},
+		{"name":["At"]},
+		{"name":12}
+	]`)
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "startsWith", path: "$..[?(@.name.startsWith('At'))]", expected: "[$[0], $[3]['internal']]"},
+		{name: "endsWith", path: `$..[?(@.name.endsWith("22]"))]`, expected: "[$[3]['internal']]"},
+		{name: "contains", path: "$..[?(@.name.contains('n'))]", expected: "[$[4]]"},
+		{name: "contains dot", path: "$..[?(@.name.contains(', 2'))]", expected: "[$[0], $[3]['internal']]"},
+		{name: "contains brackets", path: "$..[?(@.name.contains('[1, 2]'))]", expected: "[$[0]]"},
+		{name: "empty", path: "$[*][?(@.contains(''))]", expected: "[$[0]['name'], $[1]['other'], $[4]['name']]"},
+		{name: "case", path: "$..[?(@.name.STARTSWITH('At') && @.latitude > 1)]", expected: "[$[3]['internal']]"},
+		{name: "negation", path: "$[?(@.name && not(@.name.contains('At')))]", expected: "[$[4], $[5], $[6]]"},
+		{name: "root", path: "$[?($[0].name.startsWith('At'))]", expected: "[$[0], $[1], $[2], $[3], $[4], $[5], $[6]]"},
+		{name: "missing", path: "$..[?(@.missing.startsWith(''))]", expected: "[]"},
+		{name: "escaped", path: `$..[?(@.name.endsWith(']'))]`, expected: "[$[0], $[3]['internal']]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := JSONPath(data, test.path)
+			if err != nil {
+				t.Fatalf("JSONPath() unexpected error: %s", err)
+			}
+			if value := fullPath(result); value != test.expected {
+				t.Errorf("JSONPath(%s) = %s, expected %s", test.path, value, test.expected)
+			}
+		})
+	}
+}
+
 func TestJsonPath_value(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -480,6 +534,35 @@ func TestJSONPath_suite(t *testing.T) {
 	}
 }
 
+func TestJSONPath_null(t *testing.T) {
+	input := []byte(`{"items": [{"a": null}, {"b": 2}, {"a": {}}, {"a": 0}, {"a": false}, {"a": ""}, {"a": "null"}, {"a": {"b": null}}, null]}`)
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "null value", path: `$.items[?(@.a == null)]`, expected: `[$['items'][0]]`},
+		{name: "null value reversed", path: `$.items[?(null == @.a)]`, expected: `[$['items'][0]]`},
+		{name: "not null value", path: `$.items[?(@.a != null)]`, expected: `[$['items'][2], $['items'][3], $['items'][4], $['items'][5], $['items'][6], $['items'][7]]`},
+		{name: "nested null value", path: `$.items[?(@.a.b == null)]`, expected: `[$['items'][7]]`},
+		{name: "missing key", path: `$.items[?(@.c == null)]`, expected: `[]`},
+		{name: "missing key not null", path: `$.items[?(@.c != null)]`, expected: `[]`},
+		{name: "null element", path: `$.items[?(@ == null)]`, expected: `[$['items'][8]]`},
+		{name: "recursive descent", path: `$..[?(@.b == null)]`, expected: `[$['items'][7]['a']]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := JSONPath(input, test.path)
+			if err != nil {
+				t.Fatalf("JSONPath() unexpected error: %s", err)
+			}
+			if value := fullPath(result); value != test.expected {
+				t.Errorf("JSONPath() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
+
 func ExampleJSONPath() {
 	json := []byte(`{ "store": {
     "book": [ 
@@ -1492,6 +1575,129 @@ func TestApplyJSONPath(t *testing.T) {
 	}
 }
 
+func TestApplyJSONPath_subNode(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	books := root.MustKey("store").MustKey("book")
+	bicycle := root.MustKey("store").MustKey("bicycle")
+	tests := []struct {
+		name     string
+		node     *Node
+		path     string
+		expected []string
+	}{
+		{name: "root", node: books, path: "$", expected: []string{
+			"$['store']['book']",
+		}},
+		{name: "current", node: books, path: "@[1].title", expected: []string{
+			"$['store']['book'][1]['title']",
+		}},
+		{name: "child", node: books, path: "$[0].title", expected: []string{
+			"$['store']['book'][0]['title']",
+		}},
+		{name: "wildcard", node: bicycle, path: "$.*", expected: []string{
+			"$['store']['bicycle']['color']",
+			"$['store']['bicycle']['price']",
+		}},
+		{name: "recursive descent", node: books, path: "$..price", expected: []string{
+			"$['store']['book'][0]['price']",
+			"$['store']['book'][1]['price']",
+			"$['store']['book'][2]['price']",
+			"$['store']['book'][3]['price']",
+		}},
+		{name: "recursive descent of the leaf", node: bicycle, path: "$..price", expected: []string{
+			"$['store']['bicycle']['price']",
+		}},
+		{name: "key of the document root", node: books, path: "$.store", expected: []string{}},
+		{name: "script", node: books, path: "$[(@.length-1)].title", expected: []string{
+			"$['store']['book'][3]['title']",
+		}},
+		{name: "filter with root", node: books, path: "$[?(@.price > $[2].price)].title", expected: []string{
+			"$['store']['book'][1]['title']",
+			"$['store']['book'][3]['title']",
+		}},
+		{name: "slice with root", node: books, path: "$[($.length - 2):]", expected: []string{
+			"$['store']['book'][2]",
+			"$['store']['book'][3]",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.node.JSONPath(test.path)
+			if err != nil {
+				t.Fatalf("JSONPath() unexpected error: %s", err)
+			}
+			if paths := Paths(result); !sliceEqual(paths, test.expected) {
+				t.Errorf("JSONPath() = %s, expected %s", sliceString(paths), sliceString(test.expected))
+			}
+			compiled, err := CompileJSONPath(test.path)
+			if err != nil {
+				t.Fatalf("CompileJSONPath() unexpected error: %s", err)
+			}
+			count := 0
+			err = compiled.Each(test.node, func(*Node) bool {
+				count++
+				return true
+			})
+			if err != nil {
+				t.Fatalf("Each() unexpected error: %s", err)
+			}
+			if count != len(test.expected) {
+				t.Errorf("Each() count = %d, expected %d", count, len(test.expected))
+			}
+		})
+	}
+}
+
+func TestApplyJSONPath_chain(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	matches, err := root.JSONPath("$.store.book[?(@.isbn)]")
+	if err != nil {
+		t.Fatalf("JSONPath() unexpected error: %s", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("JSONPath() = %s, expected 2 elements", fullPath(matches))
+	}
+	expected := []string{"Moby Dick", "The Lord of the Rings"}
+	for i, match := range matches {
+		result, err := match.JSONPath("$..title")
+		if err != nil {
+			t.Fatalf("JSONPath() unexpected error: %s", err)
+		}
+		if len(result) != 1 {
+			t.Fatalf("JSONPath() = %s, expected 1 element", fullPath(result))
+		}
+		if value := result[0].MustString(); value != expected[i] {
+			t.Errorf("JSONPath() = %s, expected %s", value, expected[i])
+		}
+	}
+}
+
+func TestEval_subNode(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	books := root.MustKey("store").MustKey("book")
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{expression: "$.length", expected: "4"},
+		{expression: "@.length == $.length", expected: "true"},
+		{expression: "$[0].price < $[1].price", expected: "true"},
+		{expression: "$.store", expected: "null"},
+		{expression: "$[0].price == @[0].price", expected: "true"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			result, err := Eval(books, test.expression)
+			if err != nil {
+				t.Fatalf("Eval() unexpected error: %s", err)
+			}
+			if value := result.String(); value != test.expected {
+				t.Errorf("Eval() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
+
 func ExampleApplyJSONPath() {
 	json := `[
 		[0, 1, 2, 3, 4, 5, 6, 7, 8, 9],
@@ -1664,3 +1870,121 @@ func TestEval_issue_69(t *testing.T) {
 		})
 	}
 }
+
+func TestCompileJSONPath(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	path, err := CompileJSONPath("$..book[?(@.price < 10)].title")
+	if err != nil {
+		t.Errorf("CompileJSONPath() unexpected error: %s", err)
+		return
+	}
+	expected := "[$['store']['book'][0]['title'], $['store']['book'][2]['title']]"
+	for i := 0; i < 2; i++ {
+		result, err := path.Apply(root)
+		if err != nil {
+			t.Errorf("Apply() unexpected error: %s", err)
+		} else if fullPath(result) != expected {
+			t.Errorf("Apply() = %s, expected %s", fullPath(result), expected)
+		}
+	}
+
+	if _, err = CompileJSONPath("XXX"); err == nil {
+		t.Errorf("CompileJSONPath() expected error")
+	}
+}
+
+func TestCompiledPath_Each(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	tests := []struct {
+		name     string
+		path     string
+		limit    int
+		expected string
+		wantErr  bool
+	}{
+		{name: "all", path: "$..price", limit: -1, expected: "[$['store']['bicycle']['price'], $['store']['book'][0]['price'], $['store']['book'][1]['price'], $['store']['book'][2]['price'], $['store']['book'][3]['price']]"},
+		{name: "limited", path: "$..price", limit: 2, expected: "[$['store']['bicycle']['price'], $['store']['book'][0]['price']]"},
+		{name: "limited filter", path: "$.store.book[?(@.price > 10)]", limit: 1, expected: "[$['store']['book'][1]]"},
+		{name: "limited slice", path: "$.store.book[1:]", limit: 1, expected: "[$['store']['book'][1]]"},
+		{name: "limited union", path: "$.store.book[3,2,1]", limit: 2, expected: "[$['store']['book'][3], $['store']['book'][2]]"},
+		{name: "limited recursive", path: "$..", limit: 3, expected: "[$, $['store'], $['store']['bicycle']]"},
+		{name: "empty", path: "$.store.unknown.*", limit: -1, expected: "[]"},
+		{name: "error", path: "$.store.book[?(@.price / 0)]", limit: -1, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := CompileJSONPath(test.path)
+			if err != nil {
+				t.Errorf("CompileJSONPath() unexpected error: %s", err)
+				return
+			}
+			result := make([]*Node, 0)
+			err = path.Each(root, func(node *Node) bool {
+				result = append(result, node)
+				return len(result) != test.limit
+			})
+			if (err != nil) != test.wantErr {
+				t.Errorf("Each() error = %v, wantErr %v", err, test.wantErr)
+				return
+			}
+			if !test.wantErr && fullPath(result) != test.expected {
+				t.Errorf("Each() = %s, expected %s", fullPath(result), test.expected)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPath_emptyOrError(t *testing.T) {
+	broken := func() *Node {
+		root := Must(Unmarshal([]byte(`[{"price":10},{}]`)))
+		_ = root.MustIndex(1).AppendObject("price", valueNode(nil, "", Numeric, "foo"))
+		return root
+	}
+	tests := []struct {
+		name    string
+		node    *Node
+		path    string
+		wantErr bool
+	}{
+		{name: "child", node: Must(Unmarshal([]byte(`{}`))), path: "$.foo"},
+		{name: "child of scalar", node: Must(Unmarshal([]byte(`1`))), path: "$.foo"},
+		{name: "index", node: Must(Unmarshal([]byte(`[]`))), path: "$[0]"},
+		{name: "index of object", node: Must(Unmarshal([]byte(`{"a":1}`))), path: "$[0]"},
+		{name: "key of array", node: Must(Unmarshal([]byte(`[1]`))), path: "$.foo"},
+		{name: "wildcard", node: Must(Unmarshal([]byte(`[]`))), path: "$.*"},
+		{name: "recursive", node: Must(Unmarshal([]byte(`1`))), path: "$..foo"},
+		{name: "slice", node: Must(Unmarshal([]byte(`[1]`))), path: "$[5:10]"},
+		{name: "union", node: Must(Unmarshal([]byte(`{"a":1}`))), path: "$['b','c']"},
+		{name: "filter", node: Must(Unmarshal([]byte(`[1,2]`))), path: "$[?(@ > 5)]"},
+		{name: "script", node: Must(Unmarshal([]byte(`[1,2]`))), path: "$[(@.length)]"},
+		{name: "length of object", node: Must(Unmarshal([]byte(`{}`))), path: "$.length"},
+
+		{name: "slice zero step", node: Must(Unmarshal([]byte(`[1]`))), path: "$[::0]", wantErr: true},
+		{name: "slice wrong index", node: Must(Unmarshal([]byte(`[1]`))), path: "$[(@.foo):]", wantErr: true},
+		{name: "filter wrong expression", node: Must(Unmarshal([]byte(`[1]`))), path: "$[?(@ >)]", wantErr: true},
+		{name: "filter evaluation error", node: Must(Unmarshal([]byte(`[1]`))), path: "$[?(@ / 0)]", wantErr: true},
+		{name: "filter broken value", node: broken(), path: "$[?(@.price)]", wantErr: true},
+		{name: "filter broken value comparison", node: broken(), path: "$[?(@.price > 5)]", wantErr: true},
+		{name: "script wrong expression", node: Must(Unmarshal([]byte(`[1]`))), path: "$[(@ +)]", wantErr: true},
+		{name: "union wrong key", node: Must(Unmarshal([]byte(`{"a":1}`))), path: `$['a','\x']`, wantErr: true},
+		{name: "index wrong key", node: Must(Unmarshal([]byte(`[1]`))), path: `$['\x']`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.node.JSONPath(test.path)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("JSONPath() expected error, got %v", fullPath(result))
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("JSONPath() unexpected error: %s", err)
+			} else if result == nil {
+				t.Errorf("JSONPath() result is nil")
+			} else if len(result) != 0 {
+				t.Errorf("JSONPath() = %v, expected empty", fullPath(result))
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package ajson
+
+import (
+	"io"
+
+	. "github.com/spyzhov/ajson/internal"
+)
+
+// TokenType is a kind of the lexical token of JSON
+type TokenType int
+
+const (
+	// TokenObjectStart is the `{` symbol
+	TokenObjectStart TokenType = iota
+	// TokenObjectEnd is the `}` symbol
+	TokenObjectEnd
+	// TokenArrayStart is the `[` symbol
+	TokenArrayStart
+	// TokenArrayEnd is the `]` symbol
+	TokenArrayEnd
+	// TokenKey is the quoted key of the Object
+	TokenKey
+	// TokenColon is the `:` symbol
+	TokenColon
+	// TokenComma is the `,` symbol
+	TokenComma
+	// TokenString is the quoted String value
+	TokenString
+	// TokenNumeric is the Numeric value
+	TokenNumeric
+	// TokenBool is the `true` or `false` value
+	TokenBool
+	// TokenNull is the `null` value
+	TokenNull
+)
+
+// Token is the lexical token of JSON: its type, source bytes and the offset of the first byte in the data
+type Token struct {
+	Type   TokenType
+	Value  []byte
+	Offset int
+}
+
+// Lexer reads the JSON data token by token, without building the Node tree.
+//
+// Lexer uses the same scanner as Unmarshal, so it returns the same errors for the same invalid data.
+type Lexer struct {
+	buf     *buffer
+	stack   []TokenType
+	key     bool
+	started bool
+	err     error
+}
+
+// NewLexer creates a Lexer for the JSON data. The UTF-8 byte order mark at the beginning of the data is skipped, as
+// in Unmarshal.
+func NewLexer(data []byte) *Lexer {
+	buf := newBuffer(data)
+	buf.skipBOM()
+	return &Lexer{
+		buf:   buf,
+		stack: make([]TokenType, 0),
+	}
+}
+
+// Next returns the next token, or io.EOF error after the last token of the valid JSON.
+// Any other error means that data is not a valid JSON, all subsequent calls will return the same error.
+func (l *Lexer) Next() (token Token, err error) {
+	if l.err != nil {
+		return token, l.err
+	}
+	token, err = l.next()
+	if err != nil {
+		l.err = err
+	}
+	return token, err
+}
+
+func (l *Lexer) next() (token Token, err error) {
+	buf := l.buf
+	if l.started {
+		if buf.step() != nil {
+			return token, l.end()
+		}
+	}
+	l.started = true
+	if _, err = buf.first(); err != nil {
+		return token, l.end()
+	}
+
+	token.Offset = buf.index
+	state := buf.getState()
+	if state == __ {
+		return token, buf.errorSymbol()
+	}
+
+	if state >= GO {
+		switch buf.state {
+		case ST:
+			if l.top() == TokenObjectStart && !l.key {
+				token.Type = TokenKey
+				l.key = true
+				err = buf.string(quotes, false)
+				buf.state = CO
+			} else {
+				token.Type = TokenString
+				l.key = false
+				err = buf.string(quotes, false)
+				buf.state = OK
+			}
+			token.Value = buf.data[token.Offset : buf.index+1]
+		case MI, ZE, IN:
+			token.Type = TokenNumeric
+			l.key = false
+			err = buf.numeric(false)
+			token.Value = buf.data[token.Offset:buf.index]
+			buf.index -= 1
+			buf.state = OK
+		case T1, F1:
+			token.Type = TokenBool
+			l.key = false
+			if buf.state == T1 {
+				err = buf.true()
+			} else {
+				err = buf.false()
+			}
+			buf.state = OK
+		case N1:
+			token.Type = TokenNull
+			l.key = false
+			err = buf.null()
+			buf.state = OK
+		default:
+			err = buf.errorSymbol()
+		}
+		if err == nil && token.Value == nil {
+			token.Value = buf.data[token.Offset : buf.index+1]
+		}
+		return token, err
+	}
+
+	token.Value = buf.data[token.Offset : buf.index+1]
+	switch state {
+	case ec: /* empty } */
+		if l.key {
+			return token, buf.errorSymbol()
+		}
+		fallthrough
+	case cc: /* } */
+		token.Type = TokenObjectEnd
+		err = l.pop(TokenObjectStart)
+		buf.state = OK
+	case bc: /* ] */
+		token.Type = TokenArrayEnd
+		err = l.pop(TokenArrayStart)
+		buf.state = OK
+	case co: /* { */
+		token.Type = TokenObjectStart
+		l.key = false
+		l.stack = append(l.stack, TokenObjectStart)
+		buf.state = OB
+	case bo: /* [ */
+		token.Type = TokenArrayStart
+		l.key = false
+		l.stack = append(l.stack, TokenArrayStart)
+		buf.state = AR
+	case cm: /* , */
+		token.Type = TokenComma
+		switch l.top() {
+		case TokenObjectStart:
+			buf.state = KE
+		case TokenArrayStart:
+			buf.state = VA
+		default:
+			err = buf.errorSymbol()
+		}
+	case cl: /* : */
+		token.Type = TokenColon
+		if l.top() != TokenObjectStart || !l.key {
+			err = buf.errorSymbol()
+		} else {
+			buf.state = VA
+		}
+	default: /* syntax error */
+		err = buf.errorSymbol()
+	}
+	return token, err
+}
+
+// top returns the type of the current container, or -1 for the root
+func (l *Lexer) top() TokenType {
+	if len(l.stack) == 0 {
+		return -1
+	}
+	return l.stack[len(l.stack)-1]
+}
+
+// pop closes the current container of the given type
+func (l *Lexer) pop(_type TokenType) error {
+	if l.top() != _type {
+		return l.buf.errorSymbol()
+	}
+	l.stack = l.stack[:len(l.stack)-1]
+	return nil
+}
+
+// end checks that data ended with the complete JSON value
+func (l *Lexer) end() error {
+	if len(l.stack) != 0 || l.buf.state != OK {
+		return l.buf.errorEOF()
+	}
+	return io.EOF
+}
@@ -0,0 +1,167 @@
+package ajson
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func ExampleNewLexer() {
+	lexer := NewLexer([]byte(`{"id": 1, "tags": ["foo", null]}`))
+	for {
+		token, err := lexer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d: %s\n", token.Offset, token.Value)
+	}
+	// Output:
+	// 0: {
+	// 1: "id"
+	// 5: :
+	// 7: 1
+	// 8: ,
+	// 10: "tags"
+	// 16: :
+	// 18: [
+	// 19: "foo"
+	// 24: ,
+	// 26: null
+	// 30: ]
+	// 31: }
+}
+
+func TestLexer_Next(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []TokenType
+	}{
+		{name: "null", input: `null`, expected: []TokenType{TokenNull}},
+		{name: "true", input: ` true `, expected: []TokenType{TokenBool}},
+		{name: "false", input: "\nfalse", expected: []TokenType{TokenBool}},
+		{name: "numeric", input: `-1.5e+10`, expected: []TokenType{TokenNumeric}},
+		{name: "string", input: `"foo \"bar\""`, expected: []TokenType{TokenString}},
+		{name: "empty array", input: `[ ]`, expected: []TokenType{TokenArrayStart, TokenArrayEnd}},
+		{name: "empty object", input: `{ }`, expected: []TokenType{TokenObjectStart, TokenObjectEnd}},
+		{name: "BOM", input: "\xEF\xBB\xBF[1]", expected: []TokenType{TokenArrayStart, TokenNumeric, TokenArrayEnd}},
+		{
+			name:  "array",
+			input: `[1, "1", [true]]`,
+			expected: []TokenType{
+				TokenArrayStart, TokenNumeric, TokenComma, TokenString, TokenComma,
+				TokenArrayStart, TokenBool, TokenArrayEnd, TokenArrayEnd,
+			},
+		},
+		{
+			name:  "object",
+			input: `{"a": "b", "c": {"d": [{}]}}`,
+			expected: []TokenType{
+				TokenObjectStart, TokenKey, TokenColon, TokenString, TokenComma,
+				TokenKey, TokenColon, TokenObjectStart, TokenKey, TokenColon,
+				TokenArrayStart, TokenObjectStart, TokenObjectEnd, TokenArrayEnd,
+				TokenObjectEnd, TokenObjectEnd,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lexer := NewLexer([]byte(test.input))
+			for i, expected := range test.expected {
+				token, err := lexer.Next()
+				if err != nil {
+					t.Fatalf("Next() #%d unexpected error: %s", i, err)
+				}
+				if token.Type != expected {
+					t.Errorf("Next() #%d type = %d, expected %d", i, token.Type, expected)
+				}
+				if value := test.input[token.Offset : token.Offset+len(token.Value)]; value != string(token.Value) {
+					t.Errorf("Next() #%d value = %s, expected %s", i, token.Value, value)
+				}
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := lexer.Next(); err != io.EOF {
+					t.Errorf("Next() error = %v, expected io.EOF", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLexer_errors(t *testing.T) {
+	tests := []string{
+		``,
+		`   `,
+		`nul`,
+		`tru`,
+		`-`,
+		`01`,
+		`1.`,
+		`"foo`,
+		`'foo'`,
+		`[`,
+		`]`,
+		`[1,]`,
+		`[1 2]`,
+		`[1}`,
+		`{"a"}`,
+		`{"a":}`,
+		`{"a":1,}`,
+		`{"a":1]`,
+		`{1:2}`,
+		`{"a" "b"}`,
+		`{,}`,
+		`1 2`,
+		`{} {}`,
+		`[1]]`,
+		`"\x"`,
+		"\xEF\xBB\xBF",
+		"\xEF\xBB\xBF{\"a\":}",
+		"\xEF\xBB\xBF\xEF\xBB\xBF1",
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, expected := Unmarshal([]byte(input))
+			if expected == nil {
+				t.Fatalf("Unmarshal() expected error")
+			}
+			lexer := NewLexer([]byte(input))
+			var err error
+			for i := 0; i < 100 && err == nil; i++ {
+				_, err = lexer.Next()
+			}
+			if err == io.EOF || err == nil {
+				t.Fatalf("Next() error = %v, expected %s", err, expected)
+			}
+			if err.Error() != expected.Error() {
+				t.Errorf("Next() error = %s, expected %s", err, expected)
+			}
+			if _, next := lexer.Next(); next != err {
+				t.Errorf("Next() error after failure = %v, expected %v", next, err)
+			}
+		})
+	}
+}
+
+func TestLexer_Next_example(t *testing.T) {
+	lexer := NewLexer(jsonPathTestData)
+	count := 0
+	for {
+		token, err := lexer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %s", err)
+		}
+		if token.Type == TokenNumeric {
+			count++
+		}
+	}
+	if count != 5 {
+		t.Errorf("Next() numeric tokens = %d, expected 5", count)
+	}
+}
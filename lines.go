@@ -0,0 +1,66 @@
+package ajson
+
+import (
+	"bytes"
+)
+
+// UnmarshalLines parses the JSON Lines (NDJSON) data: each non-blank line is parsed into its own independent root Node.
+//
+// Parse errors are reported with the number of the line, starting from 1.
+func UnmarshalLines(data []byte) (roots []*Node, err error) {
+	roots = make([]*Node, 0)
+	for line := 1; len(data) > 0; line++ {
+		var current []byte
+		if i := bytes.IndexByte(data, skipN); i >= 0 {
+			current, data = data[:i], data[i+1:]
+		} else {
+			current, data = data, nil
+		}
+		if len(bytes.TrimSpace(current)) == 0 {
+			continue
+		}
+		root, err := Unmarshal(current)
+		if err != nil {
+			return nil, errorLine(line, err)
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// MarshalLines returns the JSON Lines (NDJSON) representation of the nodes: one compact JSON value per line.
+func MarshalLines(nodes []*Node) (result []byte, err error) {
+	result = make([]byte, 0)
+	for _, node := range nodes {
+		value, err := Marshal(node)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, compact(value)...)
+		result = append(result, skipN)
+	}
+	return result, nil
+}
+
+// compact returns the JSON data without insignificant whitespaces
+func compact(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	str := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if str {
+			if c == backslash && i+1 < len(data) {
+				result = append(result, c, data[i+1])
+				i++
+				continue
+			}
+			str = c != quotes
+		} else if c == quotes {
+			str = true
+		} else if c == skipS || c == skipN || c == skipR || c == skipT {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package ajson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnmarshalLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		err      string
+	}{
+		{name: "empty", input: "", expected: []string{}},
+		{name: "single", input: `{"a":1}`, expected: []string{`{"a":1}`}},
+		{name: "trailing newline", input: "{\"a\":1}\n[2]\n", expected: []string{`{"a":1}`, `[2]`}},
+		{name: "blank lines", input: "\n1\n  \r\n\"foo\"\n\t\nnull", expected: []string{`1`, `"foo"`, `null`}},
+		{name: "crlf", input: "{\"a\":1}\r\n{\"b\":2}\r\n", expected: []string{`{"a":1}`, `{"b":2}`}},
+		{name: "error", input: "{\"a\":1}\n\n{\"b\":}\n", err: "wrong request: line 3: wrong symbol '}' at 5"},
+		{name: "multiline value", input: "{\n\"a\":1}", err: "wrong request: line 1: unexpected end of file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			roots, err := UnmarshalLines([]byte(test.input))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("UnmarshalLines() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalLines() unexpected error: %s", err)
+			}
+			if len(roots) != len(test.expected) {
+				t.Fatalf("UnmarshalLines() length = %d, expected %d", len(roots), len(test.expected))
+			}
+			for i, root := range roots {
+				if root.Parent() != nil {
+					t.Errorf("UnmarshalLines() node #%d is not a root", i)
+				}
+				if string(root.Source()) != test.expected[i] {
+					t.Errorf("UnmarshalLines() node #%d = %s, expected %s", i, root.Source(), test.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalLines_cause(t *testing.T) {
+	_, err := UnmarshalLines([]byte("1\n2\n{"))
+	var cause Error
+	if !errors.As(errors.Unwrap(err), &cause) {
+		t.Fatalf("UnmarshalLines() error = %v, expected to wrap the parse error", err)
+	}
+	if cause.Type != UnexpectedEOF {
+		t.Errorf("UnmarshalLines() cause = %v, expected UnexpectedEOF", cause)
+	}
+}
+
+func TestMarshalLines(t *testing.T) {
+	edited := Must(Unmarshal([]byte(`[1, 2]`)))
+	if err := edited.AppendArray(StringNode("", "foo bar")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		nodes    []*Node
+		expected string
+		err      bool
+	}{
+		{name: "empty", nodes: nil, expected: ""},
+		{name: "compact", nodes: []*Node{Must(Unmarshal([]byte(`{"a": 1}`)))}, expected: "{\"a\":1}\n"},
+		{
+			name: "pretty source",
+			nodes: []*Node{
+				Must(Unmarshal([]byte("{\n\t\"a b\" : [ 1 , \"c \\\" d\" ]\r\n}"))),
+				Must(Unmarshal([]byte(` null `))),
+			},
+			expected: "{\"a b\":[1,\"c \\\" d\"]}\nnull\n",
+		},
+		{name: "edited", nodes: []*Node{edited}, expected: "[1,2,\"foo bar\"]\n"},
+		{name: "nil", nodes: []*Node{nil}, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := MarshalLines(test.nodes)
+			if test.err {
+				if err == nil {
+					t.Errorf("MarshalLines() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MarshalLines() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("MarshalLines() = %q, expected %q", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalLines_roundTrip(t *testing.T) {
+	data := []byte("{\"id\":1,\"msg\":\"start\"}\n{\"id\":2,\"msg\":\"stop\"}\n")
+	roots, err := UnmarshalLines(data)
+	if err != nil {
+		t.Fatalf("UnmarshalLines() unexpected error: %s", err)
+	}
+	result, err := MarshalLines(roots)
+	if err != nil {
+		t.Fatalf("MarshalLines() unexpected error: %s", err)
+	}
+	if string(result) != string(data) {
+		t.Errorf("MarshalLines() = %q, expected %q", result, data)
+	}
+}
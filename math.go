@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 // Function - internal left function of JSONPath
@@ -370,7 +371,7 @@ var (
 				if res, err := node.GetString(); err != nil {
 					return nil, err
 				} else {
-					return valueNode(nil, "length", Numeric, float64(len(res))), nil
+					return valueNode(nil, "length", Numeric, float64(utf8.RuneCountInString(res))), nil
 				}
 			}
 			return valueNode(nil, "length", Numeric, float64(1)), nil
@@ -561,6 +562,12 @@ var (
 			}
 			return valueNode(nil, "root", Null, nil), nil
 		},
+		"isarray":  typeFunction("isArray", Array),
+		"isbool":   typeFunction("isBool", Bool),
+		"isnull":   typeFunction("isNull", Null),
+		"isnumber": typeFunction("isNumber", Numeric),
+		"isobject": typeFunction("isObject", Object),
+		"isstring": typeFunction("isString", String),
 		"key": func(node *Node) (result *Node, err error) {
 			if node == nil {
 				return valueNode(nil, "key", Null, nil), nil
@@ -629,6 +636,38 @@ func numericFunction(name string, fn func(float float64) float64) Function {
 	}
 }
 
+// typeFunction returns the Function, that checks the type of the node. Missing node is not of any type.
+func typeFunction(name string, _type NodeType) Function {
+	return func(node *Node) (result *Node, err error) {
+		return valueNode(nil, name, Bool, node != nil && node.Type() == _type), nil
+	}
+}
+
+// decimalOperations are the comparison operations, which compare the numeric operands as decimals with the
+// DecimalComparison option, by the result of big.Rat.Cmp
+var decimalOperations = map[string]func(cmp int) bool{
+	"==": func(cmp int) bool { return cmp == 0 },
+	"!=": func(cmp int) bool { return cmp != 0 },
+	"<":  func(cmp int) bool { return cmp < 0 },
+	"<=": func(cmp int) bool { return cmp <= 0 },
+	">":  func(cmp int) bool { return cmp > 0 },
+	">=": func(cmp int) bool { return cmp >= 0 },
+}
+
+// decimalOperation compares numeric nodes as decimals, ok is false if the operation isn't a comparison or the nodes
+// aren't numeric, so the regular operation should be used
+func decimalOperation(name string, left, right *Node) (result *Node, ok bool, err error) {
+	compare, ok := decimalOperations[name]
+	if !ok || !left.IsNumeric() || !right.IsNumeric() {
+		return nil, false, nil
+	}
+	lnum, rnum, err := _decimals(left, right)
+	if err != nil {
+		return nil, true, err
+	}
+	return valueNode(nil, name, Bool, compare(lnum.Cmp(rnum))), true, nil
+}
+
 func mathFactorial(x uint) uint {
 	if x == 0 {
 		return 1
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sync"
 	"testing"
 )
 
@@ -236,6 +237,93 @@ func TestOperations(t *testing.T) {
 	}
 }
 
+func TestDecimalComparison(t *testing.T) {
+	data := []byte(`[0.1, 0.10000000000000001, 12345678901234567890, 12345678901234567891, 1e-1]`)
+	tests := []struct {
+		path    string
+		float   string
+		decimal string
+	}{
+		{path: "$[?(@ == 0.1)]", float: "[$[0], $[1], $[4]]", decimal: "[$[0], $[4]]"},
+		{path: "$[?(@ != 0.1)]", float: "[$[2], $[3]]", decimal: "[$[1], $[2], $[3]]"},
+		{path: "$[?(@ > 0.1 && @ < 1)]", float: "[]", decimal: "[$[1]]"},
+		{path: "$[?(@ >= 12345678901234567891)]", float: "[$[2], $[3]]", decimal: "[$[3]]"},
+		{path: "$[?(@ <= 12345678901234567890 && @ > 1)]", float: "[$[2], $[3]]", decimal: "[$[2]]"},
+		{path: "$[?(@ == 0.05 * 2)]", float: "[$[0], $[1], $[4]]", decimal: "[$[0], $[4]]"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			result, err := JSONPath(data, test.path)
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err.Error())
+			} else if fullPath(result) != test.float {
+				t.Errorf("Wrong float result: %s, expected %s", fullPath(result), test.float)
+			}
+
+			result, err = JSONPath(data, test.path, DecimalComparison())
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err.Error())
+			} else if fullPath(result) != test.decimal {
+				t.Errorf("Wrong decimal result: %s, expected %s", fullPath(result), test.decimal)
+			}
+		})
+	}
+}
+
+func TestDecimalComparison_entries(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":[0.1, 0.10000000000000001],"b":0.10000000000000001}`)))
+	path, err := CompileJSONPath("$.a[?(@ == $.b)]")
+	if err != nil {
+		t.Fatalf("CompileJSONPath() unexpected error: %s", err)
+	}
+	if result, err := path.Apply(root, DecimalComparison()); err != nil || fullPath(result) != "[$['a'][1]]" {
+		t.Errorf("Apply() = %s, %v", fullPath(result), err)
+	}
+	var found []*Node
+	err = path.Each(root, func(node *Node) bool {
+		found = append(found, node)
+		return true
+	}, DecimalComparison())
+	if err != nil || fullPath(found) != "[$['a'][1]]" {
+		t.Errorf("Each() = %s, %v", fullPath(found), err)
+	}
+	if result, err := root.JSONPath("$.a[?(@ == 0.1)]", DecimalComparison()); err != nil || fullPath(result) != "[$['a'][0]]" {
+		t.Errorf("JSONPath() = %s, %v", fullPath(result), err)
+	}
+	if result, err := Eval(root, "$.a[0] == $.b", DecimalComparison()); err != nil || result.MustBool() {
+		t.Errorf("Eval() = %v, %v, expected false", result, err)
+	}
+	if result, err := Eval(root, "$.a[0] == $.b"); err != nil || !result.MustBool() {
+		t.Errorf("Eval() = %v, %v, expected true", result, err)
+	}
+}
+
+func TestDecimalComparison_concurrent(t *testing.T) {
+	root := Must(Unmarshal([]byte(`[0.1, 0.10000000000000001]`)))
+	var wg sync.WaitGroup
+	errs := make(chan string, 200)
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if result, err := root.JSONPath("$[?(@ == 0.1)]"); err != nil || len(result) != 2 {
+				errs <- fmt.Sprintf("float: %s, %v", fullPath(result), err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if result, err := root.JSONPath("$[?(@ == 0.1)]", DecimalComparison()); err != nil || len(result) != 1 {
+				errs <- fmt.Sprintf("decimal: %s, %v", fullPath(result), err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("JSONPath() = %s", err)
+	}
+}
+
 func TestAddConstant(t *testing.T) {
 	name := "new_constant_name"
 	if _, ok := constants[name]; ok {
@@ -429,6 +517,7 @@ func TestFunctions2(t *testing.T) {
 			"bar": NumericNode("bar", 1),
 		}), result: NumericNode("", 1)},
 		{name: "length string", fname: "length", value: StringNode("", "foo_bar"), result: NumericNode("", 7)},
+		{name: "length unicode string", fname: "length", value: StringNode("", "UTF-8: 😹"), result: NumericNode("", 8)},
 		{name: "length string error", fname: "length", value: _s, fail: true},
 		{name: "length numeric", fname: "length", value: NumericNode("", 123), result: NumericNode("", 1)},
 		{name: "length bool", fname: "length", value: BoolNode("", false), result: NumericNode("", 1)},
@@ -565,6 +654,19 @@ func TestFunctions2(t *testing.T) {
 		{name: "key", fname: "key", value: key, result: StringNode("", "t"), fail: false},
 		{name: "key: none", fname: "key", value: StringNode("", "value"), result: NullNode(""), fail: false},
 		{name: "key nil", fname: "key", value: nil, result: NullNode("")},
+
+		{name: "isArray", fname: "isarray", value: ArrayNode("", nil), result: BoolNode("", true)},
+		{name: "isArray: object", fname: "isarray", value: object, result: BoolNode("", false)},
+		{name: "isBool", fname: "isbool", value: BoolNode("", false), result: BoolNode("", true)},
+		{name: "isBool: string", fname: "isbool", value: StringNode("", "true"), result: BoolNode("", false)},
+		{name: "isNull", fname: "isnull", value: NullNode(""), result: BoolNode("", true)},
+		{name: "isNull: nil", fname: "isnull", value: nil, result: BoolNode("", false)},
+		{name: "isNumber", fname: "isnumber", value: NumericNode("", 0), result: BoolNode("", true)},
+		{name: "isNumber: string", fname: "isnumber", value: StringNode("", "1"), result: BoolNode("", false)},
+		{name: "isObject", fname: "isobject", value: object, result: BoolNode("", true)},
+		{name: "isObject: nil", fname: "isobject", value: nil, result: BoolNode("", false)},
+		{name: "isString", fname: "isstring", value: key, result: BoolNode("", true)},
+		{name: "isString: null", fname: "isstring", value: NullNode(""), result: BoolNode("", false)},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
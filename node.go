@@ -1,10 +1,17 @@
 package ajson
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"hash/fnv"
+	"io"
 	"math"
+	"math/big"
 	"sort"
 	"strconv"
 	"sync/atomic"
+	"unicode/utf8"
 )
 
 // Node is a main struct, presents any type of JSON node.
@@ -29,8 +36,18 @@ type Node struct {
 	_type    NodeType
 	data     *[]byte
 	borders  [2]int
-	value    atomic.Value
-	dirty    bool
+	// keyBorders are the borders of the key of the Object member in the source, including quotes, see KeyRanges
+	keyBorders *[2]int
+	value      atomic.Value
+	dirty      bool
+	// duplicates are all occurrences of the repeated keys of the Object in the source order, see DuplicateKeys
+	duplicates map[string][]*Node
+	// frozen node could not be changed, see Freeze
+	frozen bool
+	// readOnly node doesn't copy the decoded strings from the source, see ReadOnly
+	readOnly bool
+	// exact is the value of the Numeric node, which couldn't be restored from float64, see BigNumbers
+	exact *big.Rat
 }
 
 // NodeType is a kind of reflection of JSON type to a type of golang
@@ -60,6 +77,23 @@ const (
 	Object
 )
 
+var typeNames = [...]string{
+	Null:    "Null",
+	Numeric: "Numeric",
+	String:  "String",
+	Bool:    "Bool",
+	Array:   "Array",
+	Object:  "Object",
+}
+
+// String returns the name of the type, same as the name of its constant
+func (t NodeType) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return "NodeType(" + strconv.Itoa(int(t)) + ")"
+	}
+	return typeNames[t]
+}
+
 // NullNode is constructor for Node with Null value
 func NullNode(key string) *Node {
 	return &Node{
@@ -123,23 +157,22 @@ func ArrayNode(key string, value []*Node) (current *Node) {
 	return
 }
 
-// ObjectNode is constructor for Node with an Object value
+// ObjectNode is constructor for Node with an Object value. The map is copied and the keys of the children are replaced
+// with the keys of the map, so the later changes of the map don't affect the node. Keys are not validated, as there is
+// no way to return an error: use Validate, SetObject or NewObject to reject the invalid ones.
 func ObjectNode(key string, value map[string]*Node) (current *Node) {
 	current = &Node{
 		_type:    Object,
 		key:      &key,
-		children: value,
+		children: make(map[string]*Node, len(value)),
 		dirty:    true,
 	}
-	if value != nil {
-		for key, val := range value {
-			vkey := key
-			val.parent = current
-			val.key = &vkey
-		}
-		current.value.Store(value)
-	} else {
-		current.children = make(map[string]*Node)
+	for key, val := range value {
+		vkey := key
+		val.parent = current
+		val.key = &vkey
+		val.index = nil
+		current.children[vkey] = val
 	}
 	return
 }
@@ -165,6 +198,12 @@ func newNode(parent *Node, buf *buffer, _type NodeType, key **string) (current *
 			if *key == nil {
 				err = errorSymbol(buf)
 			} else {
+				if old, ok := parent.children[**key]; ok && parent.duplicates != nil {
+					if _, ok = parent.duplicates[**key]; !ok {
+						parent.duplicates[**key] = []*Node{old}
+					}
+					parent.duplicates[**key] = append(parent.duplicates[**key], current)
+				}
 				parent.children[**key] = current
 			}
 		} else {
@@ -197,6 +236,36 @@ func (n *Node) Parent() *Node {
 	return n.parent
 }
 
+// Ancestor returns the closest node up the tree, starting with the current one, for which pred returns true,
+// or nil if there is no such node
+func (n *Node) Ancestor(pred func(node *Node) bool) *Node {
+	for node := n; node != nil; node = node.parent {
+		if pred(node) {
+			return node
+		}
+	}
+	return nil
+}
+
+// CommonAncestor returns the deepest node, which is an ancestor of both nodes, or nil if they are in different trees.
+// Like for the Ancestor, the node itself is counted, so for the node and any of its children the node is returned.
+func CommonAncestor(a, b *Node) *Node {
+	if a == nil || b == nil {
+		return nil
+	}
+	left, right := a.level(), b.level()
+	for ; left > right; left-- {
+		a = a.parent
+	}
+	for ; right > left; right-- {
+		b = b.parent
+	}
+	for a != b {
+		a, b = a.parent, b.parent
+	}
+	return a
+}
+
 // Source returns slice of bytes, which was identified to be current node
 func (n *Node) Source() []byte {
 	if n == nil {
@@ -208,6 +277,142 @@ func (n *Node) Source() []byte {
 	return nil
 }
 
+// Range returns the byte span of the node within the original source data, so that
+// data[start:end] is the same as node.Source(). For the constructed and modified nodes ok is false, as well as for
+// the nodes with the source other than the source of the root, e.g. after Compact of the node or SetNode.
+func (n *Node) Range() (start, end int, ok bool) {
+	if n == nil || !n.ready() || n.dirty || n.data == nil || n.data != n.root().data {
+		return 0, 0, false
+	}
+	return n.borders[0], n.borders[1], true
+}
+
+// KeyRange returns the byte span of the key of current node within the original source data, including quotes, so
+// that data[start:end] is the key as it's written in the source, e.g. `"name"`. It's useful to rewrite the keys in
+// place. The spans are kept only with KeyRanges or DuplicateKeys option of UnmarshalWithOptions, ok is false without
+// them. ok is false as well for the elements of the arrays, the root, the constructed nodes and the nodes, which were added
+// to the object or moved with a mutation method, and the members of the objects with the source other than the source
+// of the root (see Range). Changing the value of the node keeps the span of the key.
+func (n *Node) KeyRange() (start, end int, ok bool) {
+	if n == nil || n.parent == nil || n.parent._type != Object || n.keyBorders == nil ||
+		n.parent.data != n.root().data {
+		return 0, 0, false
+	}
+	return n.keyBorders[0], n.keyBorders[1], true
+}
+
+// sourceBefore reports whether the member left of the Object goes before the member right in the document order: the
+// members from the source in the order of their keys in it, the members added after parsing after them, sorted by keys
+func sourceBefore(left, right *Node) bool {
+	leftPosition, leftSource := left.sourcePosition()
+	rightPosition, rightSource := right.sourcePosition()
+	if leftSource && rightSource {
+		return leftPosition < rightPosition
+	}
+	if leftSource != rightSource {
+		return leftSource
+	}
+	return *left.key < *right.key
+}
+
+// sourcePosition returns the position of the member of the Object in the source of its parent: the start of its key,
+// if the borders of the keys are kept, or the start of its value, if it's still a part of the source of the parent.
+// ok is false for the members added after parsing and the ones with the replaced values without the kept key borders.
+func (n *Node) sourcePosition() (position int, ok bool) {
+	if n.keyBorders != nil {
+		return n.keyBorders[0], true
+	}
+	if n.parent != nil && n.data != nil && n.data == n.parent.data && n.ready() {
+		return n.borders[0], true
+	}
+	return 0, false
+}
+
+// RawMessages returns the members of current Object node as json.RawMessage values, to pass them to the code based on
+// encoding/json. Unmodified members are returned as their Source, without copying, so the values must not be changed,
+// as well as the data. Modified and constructed members are marshaled. For non Object node WrongType error will be
+// returned.
+func (n *Node) RawMessages() (map[string]json.RawMessage, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if n._type != Object {
+		return nil, errorType()
+	}
+	result := make(map[string]json.RawMessage, len(n.children))
+	for key, child := range n.children {
+		value := child.Source()
+		if value == nil {
+			var err error
+			if value, err = Marshal(child); err != nil {
+				return nil, err
+			}
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// FromRawMessages returns an Object node with the members parsed from the json.RawMessage values. Values are parsed
+// with Unmarshal, so they are not copied and must not be changed while the node is in use.
+func FromRawMessages(values map[string]json.RawMessage) (*Node, error) {
+	result := ObjectNode("", nil)
+	for key, value := range values {
+		child, err := Unmarshal(value)
+		if err != nil {
+			return nil, errorRequest("key '%s': %s", key, err)
+		}
+		if err = result.AppendObject(key, child); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// CompactSource drops the source data of the node and all of its children, so they become the same as the nodes
+// created with constructors: values are parsed from the source and kept, Source returns nil and Marshal encodes the values.
+//
+// It lets the garbage collector free the source of a big document, if only a small part of it is kept for a long time.
+// The source is freed only when none of the nodes refers to it, so CompactSource should be called for the root, or for
+// the part detached from the document. Parents of the node are marked as changed. The trade-off is that Marshal
+// becomes slower, as values are encoded again instead of copying the source, and Numeric values lose
+// their original format, i.e. `1.50` becomes `1.5`.
+func (n *Node) CompactSource() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if err := n.writableTree(); err != nil {
+		return err
+	}
+	if err := n.compactSource(); err != nil {
+		return err
+	}
+	if n.parent != nil {
+		n.parent.mark()
+	}
+	return nil
+}
+
+func (n *Node) compactSource() error {
+	for _, child := range n.children {
+		if err := child.compactSource(); err != nil {
+			return err
+		}
+	}
+	if n.exact != nil {
+		value, _ := n.exact.Float64()
+		n.value.Store(value)
+	} else if !n.isContainer() {
+		if _, err := n.getValue(); err != nil {
+			return err
+		}
+	}
+	n.data = nil
+	n.borders = [2]int{}
+	n.dirty = true
+	return nil
+}
+
 // String is implementation of Stringer interface, returns string based on source part
 func (n *Node) String() string {
 	if n == nil {
@@ -373,7 +578,13 @@ func (n *Node) getValue() (value interface{}, err error) {
 			n.value.Store(value)
 		case String:
 			var ok bool
-			value, ok = unquote(n.Source(), quotes)
+			if n.readOnly {
+				var raw []byte
+				raw, ok = unquoteBytes(n.Source(), quotes)
+				value = viewString(raw)
+			} else {
+				value, ok = unquote(n.Source(), quotes)
+			}
 			if !ok {
 				return "", errorAt(n.borders[0], (*n.data)[n.borders[0]])
 			}
@@ -434,6 +645,32 @@ func (n *Node) GetNumeric() (value float64, err error) {
 	return value, nil
 }
 
+// BigInt returns the exact integer value of current Numeric node, ok is false for non-integer or non-Numeric node.
+// The value is calculated from the source, if it's available, so big integers (like IDs) are returned without loss of
+// precision. See BigNumbers to keep the exact values after the source is dropped.
+func (n *Node) BigInt() (value *big.Int, ok bool) {
+	decimal, err := n.getDecimal()
+	if err != nil || !decimal.IsInt() {
+		return nil, false
+	}
+	return new(big.Int).Set(decimal.Num()), true
+}
+
+// BigFloat returns the value of current Numeric node as big.Float, ok is false for non-Numeric node. Like BigInt,
+// the value is calculated from the source, with the precision enough to keep all digits of the integers and to restore
+// the decimal value of the fractions.
+func (n *Node) BigFloat() (value *big.Float, ok bool) {
+	decimal, err := n.getDecimal()
+	if err != nil {
+		return nil, false
+	}
+	prec := uint(decimal.Num().BitLen() + decimal.Denom().BitLen())
+	if prec < 64 {
+		prec = 64
+	}
+	return new(big.Float).SetPrec(prec).SetRat(decimal), true
+}
+
 // GetString returns string, if current type is String, else: WrongType error
 func (n *Node) GetString() (value string, err error) {
 	if n == nil {
@@ -472,6 +709,54 @@ func (n *Node) GetBool() (value bool, err error) {
 	return value, nil
 }
 
+// StringBytes returns decoded bytes of the string value, if current type is String, else: WrongType error.
+//
+// For the parsed and unchanged node, it's calculated from the source without allocation, if the string has no escaped
+// symbols. In that case the result shares memory with the source data, so it shouldn't be modified.
+func (n *Node) StringBytes() ([]byte, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if n._type != String {
+		return nil, errorType()
+	}
+	if source := n.Source(); source != nil {
+		value, ok := unquoteBytes(source, quotes)
+		if !ok {
+			return nil, errorAt(n.borders[0], (*n.data)[n.borders[0]])
+		}
+		return value, nil
+	}
+	value, err := n.GetString()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// RuneLen returns count of unicode code points of the string value, if current type is String, else: WrongType error
+func (n *Node) RuneLen() (int, error) {
+	value, err := n.GetString()
+	if err != nil {
+		return 0, err
+	}
+	return utf8.RuneCountInString(value), nil
+}
+
+// Substring returns new String node with the part of the string value from start to end (not included), counting in
+// unicode code points. If current type is not String, will return WrongType error.
+func (n *Node) Substring(start, end int) (*Node, error) {
+	value, err := n.GetString()
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(value)
+	if start < 0 || end > len(runes) || start > end {
+		return nil, errorOutOfRange("out of range [%d:%d] with length %d", start, end, len(runes))
+	}
+	return StringNode("", string(runes[start:end])), nil
+}
+
 // GetArray returns []*Node, if current type is Array, else: WrongType error
 func (n *Node) GetArray() (value []*Node, err error) {
 	if n == nil {
@@ -564,6 +849,46 @@ func (n *Node) MustObject() (value map[string]*Node) {
 	return
 }
 
+// AsString returns the textual representation of current scalar node, to be used for logging or display:
+//   - String: the value itself, as GetString does;
+//   - Numeric: the number as it was in the source, if it's available, else the shortest representation of the value,
+//     like `1.5` or `1e+21`;
+//   - Bool: `true` or `false`;
+//   - Null: empty string.
+//
+// For Array and Object nodes WrongType error will be returned. Use MustString or GetString to get the value
+// of String node only.
+func (n *Node) AsString() (string, error) {
+	if n == nil {
+		return "", errorUnparsed()
+	}
+	switch n._type {
+	case Null:
+		return "", nil
+	case String:
+		return n.GetString()
+	case Numeric:
+		if source := n.Source(); source != nil {
+			return string(source), nil
+		}
+		if n.exact != nil {
+			return decimalString(n.exact), nil
+		}
+		value, err := n.GetNumeric()
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(value, 'g', -1, 64), nil
+	case Bool:
+		value, err := n.GetBool()
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(value), nil
+	}
+	return "", errorType()
+}
+
 // Unpack will produce current node to it's interface, recursively with all underlying nodes (in contrast to Node.Value).
 func (n *Node) Unpack() (value interface{}, err error) {
 	if n == nil {
@@ -623,7 +948,7 @@ func (n *Node) GetIndex(index int) (*Node, error) {
 	}
 	child, ok := n.children[strconv.Itoa(index)]
 	if !ok {
-		return nil, errorRequest("out of index %d", index)
+		return nil, errorOutOfRange("out of index %d", index)
 	}
 	return child, nil
 }
@@ -647,11 +972,26 @@ func (n *Node) GetKey(key string) (*Node, error) {
 	}
 	value, ok := n.children[key]
 	if !ok {
-		return nil, errorRequest("wrong key '%s'", key)
+		return nil, errorKeyNotFound(key)
 	}
 	return value, nil
 }
 
+// KeyAll returns all occurrences of the key of current Object node in the source order. Repeated keys are kept only
+// with DuplicateKeys option, otherwise it's the same as GetKey. Returns nil, if node is not an Object, or has no such key.
+func (n *Node) KeyAll(key string) []*Node {
+	if n == nil || n._type != Object {
+		return nil
+	}
+	if list, ok := n.duplicates[key]; ok {
+		return append([]*Node(nil), list...)
+	}
+	if value, ok := n.children[key]; ok {
+		return []*Node{value}
+	}
+	return nil
+}
+
 // MustKey will return child node of current object node. If current node is not Object, or key is unavailable, raise a panic
 func (n *Node) MustKey(key string) (value *Node) {
 	value, err := n.GetKey(key)
@@ -661,6 +1001,126 @@ func (n *Node) MustKey(key string) (value *Node) {
 	return
 }
 
+// Dig will return the node found by the sequence of keys of the Object nodes and indexes of the Array nodes,
+// without parsing the JSONPath, i.e. node.Dig("store", "book", "0", "title") is the same as
+// node.JSONPath("@.store.book[0].title"), but much faster. Negative indexes are counted from the end of the Array.
+func (n *Node) Dig(keys ...string) (node *Node, err error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	node = n
+	for _, key := range keys {
+		if node, err = node.child(key); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// GetOption is a functional option for Get
+type GetOption func(options *getOptions)
+
+type getOptions struct {
+	unwrap bool
+}
+
+// UnwrapSingle makes Get to see through the Array nodes with exactly one element, for the data of the producers,
+// which wrap the values into arrays inconsistently. It's an opt-in, because the real arrays of one element are
+// unwrapped as well. Array is unwrapped (repeatedly, for the nested ones) only in two cases:
+//   - the next key is not an integer, so it could be only the key of the Object, e.g. "name" for `[{"name":"foo"}]`;
+//   - it's the found node itself, e.g. `["foo"]` is returned as `"foo"`.
+//
+// Integer keys are applied to the Array as indexes, so Get([]string{"tags", "0"}, UnwrapSingle()) for
+// `{"tags":["foo"]}` returns `"foo"` as well as without the option.
+func UnwrapSingle() GetOption {
+	return func(options *getOptions) {
+		options.unwrap = true
+	}
+}
+
+// Get will return the node found by the sequence of keys of the Object nodes and indexes of the Array nodes, the
+// same as Dig does, with the given options. Use UnwrapSingle to unwrap the single-element arrays on the way.
+//
+//	name, err := root.Get([]string{"data", "user", "name"}, ajson.UnwrapSingle())
+//	// the same "Bob" for `{"data":{"user":{"name":"Bob"}}}` and `{"data":[{"user":[{"name":["Bob"]}]}]}`
+func (n *Node) Get(keys []string, options ...GetOption) (node *Node, err error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	opts := new(getOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	node = n
+	for _, key := range keys {
+		if opts.unwrap {
+			if _, cerr := strconv.Atoi(key); cerr != nil {
+				node = node.unwrapSingle()
+			}
+		}
+		if node, err = node.child(key); err != nil {
+			return nil, err
+		}
+	}
+	if opts.unwrap {
+		node = node.unwrapSingle()
+	}
+	return node, nil
+}
+
+// unwrapSingle returns the element of current Array node with exactly one element, repeatedly for the nested arrays,
+// or current node for any other one
+func (n *Node) unwrapSingle() *Node {
+	node := n
+	for node._type == Array && len(node.children) == 1 {
+		node = node.children["0"]
+	}
+	return node
+}
+
+// child returns the child node of current container by the key of the Object or the index of the Array
+func (n *Node) child(key string) (*Node, error) {
+	switch n._type {
+	case Object:
+		return n.GetKey(key)
+	case Array:
+		if child, ok := n.children[key]; ok {
+			return child, nil
+		}
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, errorRequest("wrong index '%s'", key)
+		}
+		return n.GetIndex(index)
+	}
+	return nil, errorType()
+}
+
+// At will return the node found by the sequence of parts: string values are the keys of the Object nodes and
+// int values are the indexes of the Array nodes, i.e. node.At("store", "book", 0, "title").
+//
+// Error contains the position and the value of the part, that failed.
+func (n *Node) At(parts ...interface{}) (node *Node, err error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	node = n
+	for i, part := range parts {
+		switch value := part.(type) {
+		case string:
+			node, err = node.GetKey(value)
+		case int:
+			node, err = node.GetIndex(value)
+		default:
+			err = unsupportedType(part)
+		}
+		if err != nil {
+			return nil, errorPart(i, part, err)
+		}
+	}
+	return node, nil
+}
+
 // HasKey will return boolean value, if current object node has custom key
 func (n *Node) HasKey(key string) bool {
 	if n == nil {
@@ -678,7 +1138,7 @@ func (n *Node) Empty() bool {
 	return len(n.children) == 0
 }
 
-// Path returns full JsonPath of current Node
+// Path returns full JsonPath of current Node, in the bracket-notation with escaped keys, so it finds the same node with JSONPath
 func (n *Node) Path() string {
 	if n == nil {
 		return ""
@@ -686,10 +1146,49 @@ func (n *Node) Path() string {
 	if n.parent == nil {
 		return "$"
 	}
+	return n.parent.Path() + n.pathSegment()
+}
+
+// pathSegment returns the last segment of the JsonPath of current Node, relative to it's parent.
+// The key is escaped, so the segment can be parsed back by ParseJSONPath.
+func (n *Node) pathSegment() string {
 	if n.key != nil {
-		return n.parent.Path() + "['" + n.Key() + "']"
+		return "['" + escapeKey(n.Key()) + "']"
+	}
+	return "[" + strconv.Itoa(n.Index()) + "]"
+}
+
+// escapeKey escapes backslashes, single quotes and control characters of the key for the bracket-notation of the JsonPath
+func escapeKey(key string) string {
+	var result []byte
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 0x20 && c != backslash && c != quote {
+			if result != nil {
+				result = append(result, c)
+			}
+			continue
+		}
+		if result == nil {
+			result = append(make([]byte, 0, len(key)+2), key[:i]...)
+		}
+		switch c {
+		case backslash, quote:
+			result = append(result, backslash, c)
+		case '\n':
+			result = append(result, backslash, 'n')
+		case '\r':
+			result = append(result, backslash, 'r')
+		case '\t':
+			result = append(result, backslash, 't')
+		default:
+			result = append(result, backslash, 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+	}
+	if result == nil {
+		return key
 	}
-	return n.parent.Path() + "[" + strconv.Itoa(n.Index()) + "]"
+	return string(result)
 }
 
 // Eq check if nodes value are the same
@@ -763,6 +1262,239 @@ func (n *Node) Eq(node *Node) (result bool, err error) {
 	return
 }
 
+// EqualOption is a functional option to configure EqualWithOptions behaviour
+type EqualOption func(options *equalOptions)
+
+type equalOptions struct {
+	ignore        []string
+	tolerance     float64
+	missingAsNull bool
+	ignored       map[*Node]bool // nodes found by the ignored paths in both trees
+}
+
+// IgnorePaths makes EqualWithOptions to skip the nodes found by the given JSONPath queries, e.g. IgnorePaths("$..id").
+// Paths are evaluated for both compared nodes, `$` refers to the compared node itself. Ignored members of the
+// objects are skipped even if they are missing on the other side, ignored elements of the arrays are equal to any
+// element on the same position.
+func IgnorePaths(paths ...string) EqualOption {
+	return func(options *equalOptions) {
+		options.ignore = append(options.ignore, paths...)
+	}
+}
+
+// Tolerance makes EqualWithOptions to treat numbers as equal, if the absolute difference between them is not greater than
+// the given delta.
+func Tolerance(delta float64) EqualOption {
+	return func(options *equalOptions) {
+		options.tolerance = delta
+	}
+}
+
+// MissingAsNull makes EqualWithOptions to treat a missing member of the object as equal to the member with the null value.
+func MissingAsNull() EqualOption {
+	return func(options *equalOptions) {
+		options.missingAsNull = true
+	}
+}
+
+// EqualOptions are the options of EqualWith, an alternative to the functional options of EqualWithOptions.
+// Zero value compares the nodes the same way as Eq does.
+type EqualOptions struct {
+	// IgnorePaths are the JSONPath queries of the nodes to skip, see IgnorePaths
+	IgnorePaths []string
+	// Tolerance is the maximal absolute difference of the equal numbers, see Tolerance
+	Tolerance float64
+	// MissingAsNull treats a missing member of the object as equal to the member with the null value, see MissingAsNull
+	MissingAsNull bool
+}
+
+// options returns the functional options, which are the same as current ones
+func (o EqualOptions) options() (result []EqualOption) {
+	if len(o.IgnorePaths) != 0 {
+		result = append(result, IgnorePaths(o.IgnorePaths...))
+	}
+	if o.Tolerance != 0 {
+		result = append(result, Tolerance(o.Tolerance))
+	}
+	if o.MissingAsNull {
+		result = append(result, MissingAsNull())
+	}
+	return result
+}
+
+// EqualWith checks if values of the nodes are the same, as EqualWithOptions does with the same options. Useful for the
+// snapshot tests, where some values (like timestamps or IDs) should be ignored:
+//
+//	if !actual.EqualWith(expected, ajson.EqualOptions{IgnorePaths: []string{"$..id"}, Tolerance: 1e-9}) {
+//
+// Returns false for any error, e.g. the nil node, the wrong path in IgnorePaths or the broken value, use
+// EqualWithOptions to get it.
+func (n *Node) EqualWith(other *Node, opts EqualOptions) bool {
+	result, err := n.EqualWithOptions(other, opts.options()...)
+	return err == nil && result
+}
+
+// EqualWithOptions checks if values of the nodes are the same, as Eq does, with the given options. Without options
+// it's the same as Eq.
+//
+//	equal, err := actual.EqualWithOptions(expected, ajson.IgnorePaths("$..id", "$.created_at"), ajson.Tolerance(1e-9))
+func (n *Node) EqualWithOptions(node *Node, options ...EqualOption) (bool, error) {
+	if n == nil || node == nil {
+		return false, errorUnparsed()
+	}
+	opts := new(equalOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	if len(opts.ignore) != 0 {
+		opts.ignored = make(map[*Node]bool)
+		for _, root := range []*Node{n, node} {
+			for _, path := range opts.ignore {
+				result, err := root.JSONPath(path)
+				if err != nil {
+					return false, err
+				}
+				for _, value := range result {
+					opts.ignored[value] = true
+				}
+			}
+		}
+	}
+	return n.equal(node, opts)
+}
+
+// equal compares the nodes with the options of EqualWithOptions
+func (n *Node) equal(node *Node, options *equalOptions) (bool, error) {
+	if options.ignored[n] || options.ignored[node] {
+		return true, nil
+	}
+	if n.Type() != node.Type() {
+		return false, nil
+	}
+	switch n.Type() {
+	case Numeric:
+		lnum, rnum, err := _floats(n, node)
+		if err != nil {
+			return false, err
+		}
+		return lnum == rnum || math.Abs(lnum-rnum) <= options.tolerance, nil
+	case Array:
+		lnum, rnum, err := _arrays(n, node)
+		if err != nil || len(lnum) != len(rnum) {
+			return false, err
+		}
+		for i := range lnum {
+			if result, err := lnum[i].equal(rnum[i], options); err != nil || !result {
+				return false, err
+			}
+		}
+		return true, nil
+	case Object:
+		lnum, rnum, err := _objects(n, node)
+		if err != nil {
+			return false, err
+		}
+		for key, left := range lnum {
+			right, ok := rnum[key]
+			if !ok {
+				if options.ignored[left] || (options.missingAsNull && left.IsNull()) {
+					continue
+				}
+				return false, nil
+			}
+			if result, err := left.equal(right, options); err != nil || !result {
+				return false, err
+			}
+		}
+		for key, right := range rnum {
+			if _, ok := lnum[key]; !ok && !options.ignored[right] && !(options.missingAsNull && right.IsNull()) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return n.Eq(node)
+}
+
+// Hash returns the 64-bit FNV-1a hash of the value of the node, it doesn't depend on the order of keys and formatting
+// of the source. Equal nodes have the same hash, as in Eq: i.e. `{"a": 1.0}` and `{"a":1}`. It's useful to cache or
+// deduplicate JSON fragments, but as any hash, it could be the same for different values.
+func (n *Node) Hash() (uint64, error) {
+	if n == nil {
+		return 0, errorUnparsed()
+	}
+	result := fnv.New64a()
+	if err := n.hash(result); err != nil {
+		return 0, err
+	}
+	return result.Sum64(), nil
+}
+
+// hash writes the type and the value of the node to w
+func (n *Node) hash(w hash.Hash64) error {
+	var buf [9]byte
+	buf[0] = byte(n._type)
+	switch n._type {
+	case Null:
+		_, _ = w.Write(buf[:1])
+	case Bool:
+		value, err := n.GetBool()
+		if err != nil {
+			return err
+		}
+		if value {
+			buf[1] = 1
+		}
+		_, _ = w.Write(buf[:2])
+	case Numeric:
+		value, err := n.GetNumeric()
+		if err != nil {
+			return err
+		}
+		if value == 0 {
+			value = 0 // -0 is equal to 0
+		}
+		binary.BigEndian.PutUint64(buf[1:], math.Float64bits(value))
+		_, _ = w.Write(buf[:])
+	case String:
+		value, err := n.GetString()
+		if err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint64(buf[1:], uint64(len(value)))
+		_, _ = w.Write(buf[:])
+		_, _ = io.WriteString(w, value)
+	case Array:
+		binary.BigEndian.PutUint64(buf[1:], uint64(len(n.children)))
+		_, _ = w.Write(buf[:])
+		for i := 0; i < len(n.children); i++ {
+			child, ok := n.children[strconv.Itoa(i)]
+			if !ok {
+				return errorRequest("wrong length of array")
+			}
+			if err := child.hash(w); err != nil {
+				return err
+			}
+		}
+	case Object:
+		keys := n.Keys()
+		sort.Strings(keys)
+		binary.BigEndian.PutUint64(buf[1:], uint64(len(keys)))
+		_, _ = w.Write(buf[:])
+		for _, key := range keys {
+			binary.BigEndian.PutUint64(buf[1:], uint64(len(key)))
+			_, _ = w.Write(buf[1:])
+			_, _ = io.WriteString(w, key)
+			if err := n.children[key].hash(w); err != nil {
+				return err
+			}
+		}
+	default:
+		return errorType()
+	}
+	return nil
+}
+
 // Neq check if nodes value are not the same
 func (n *Node) Neq(node *Node) (result bool, err error) {
 	result, err = n.Eq(node)
@@ -877,6 +1609,132 @@ func (n *Node) ready() bool {
 	return n.borders[1] != 0
 }
 
+// Validate checks current node and all of its children for the problems, which would fail Marshal or the getters
+// later: wrong links between the parents and the children, wrong keys and indexes of the children, broken borders
+// of the source data, missing and broken values. It's useful to check the tree, assembled or changed manually,
+// before the use. Returns the PathError with the path of the first broken node, or nil.
+func (n *Node) Validate() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if err := n.validateBorders(); err != nil {
+		return err
+	}
+	return n.walk(func(node *Node) error {
+		if node.isContainer() {
+			return nil
+		}
+		if node.dirty && node._type != Null && node.value.Load() == nil {
+			return PathError{Path: node.Path(), Err: errorUnparsed()}
+		}
+		if _, err := node.Value(); err != nil {
+			return PathError{Path: node.Path(), Err: err}
+		}
+		return nil
+	})
+}
+
+// validateChildren checks the type of current node and the links between it and its children, it's called before
+// the children are visited, as the wrong indexes break Inheritors
+func (n *Node) validateChildren() error {
+	if n._type < Null || n._type > Object {
+		return errorNode(n, "node has wrong type %s", n._type)
+	}
+	if !n.isContainer() && len(n.children) != 0 {
+		return errorNode(n, "node is %s, but has children", n._type)
+	}
+	for key, child := range n.children {
+		if child == nil {
+			return errorNode(n, "node has nil child '%s'", key)
+		}
+		if child.parent != n {
+			return errorNode(n, "node has child '%s' with wrong parent", key)
+		}
+		if n._type == Array {
+			if child.index == nil || *child.index < 0 || *child.index >= len(n.children) || strconv.Itoa(*child.index) != key {
+				return errorNode(n, "node has element '%s' with wrong index", key)
+			}
+		} else if child.key == nil || *child.key != key {
+			return errorNode(n, "node has member '%s' with wrong key", key)
+		} else if err := validateKey(key); err != nil {
+			return PathError{Path: n.Path(), Err: err}
+		}
+	}
+	return nil
+}
+
+// validateBorders checks that the borders of the node and all of its children are consistent with the source data,
+// returns the PathError with the path of the first broken node. Dirty nodes have no borders, so only their children
+// are checked. Links to the children are checked as well, before they are visited.
+func (n *Node) validateBorders() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	return n.walk(func(node *Node) error {
+		if err := node.validateChildren(); err != nil {
+			return err
+		}
+		return node.validateSource()
+	})
+}
+
+// validateSource checks the borders of current node and the borders of its children relative to it
+func (n *Node) validateSource() error {
+	if !n.dirty {
+		if !n.ready() {
+			return errorNode(n, "node is not dirty, but has no right border")
+		}
+		if n.data == nil {
+			return errorNode(n, "node is not dirty, but has no source data")
+		}
+		if n.borders[0] < 0 || n.borders[0] >= n.borders[1] || n.borders[1] > len(*n.data) {
+			return errorNode(n, "node has borders %v out of source data length %d", n.borders, len(*n.data))
+		}
+		if source := n.Source(); !validSource(n._type, source) {
+			return errorNode(n, "node has borders %v with wrong source: %s", n.borders, source)
+		}
+	}
+	var previous *Node
+	for _, child := range n.Inheritors() {
+		if child.parent != n {
+			return errorNode(child, "node has wrong parent")
+		}
+		if !n.dirty && !child.dirty {
+			if child.data != n.data {
+				return errorNode(child, "node has source data different from its parent")
+			}
+			if child.borders[0] <= n.borders[0] || child.borders[1] >= n.borders[1] {
+				return errorNode(child, "node has borders %v out of the parent borders %v", child.borders, n.borders)
+			}
+			if n._type == Array && previous != nil && child.borders[0] < previous.borders[1] {
+				return errorNode(child, "node has borders %v overlapped with the previous element borders %v", child.borders, previous.borders)
+			}
+		}
+		previous = child
+	}
+	return nil
+}
+
+// validSource checks the first and the last symbols of the source to be valid for the type
+func validSource(_type NodeType, source []byte) bool {
+	first, last := source[0], source[len(source)-1]
+	switch _type {
+	case Null:
+		return first == 'n' && last == 'l'
+	case Bool:
+		return (first == 't' || first == 'f') && last == 'e'
+	case String:
+		return len(source) > 1 && first == quotes && last == quotes
+	case Array:
+		return first == bracketL && last == bracketR
+	case Object:
+		return first == bracesL && last == bracesR
+	case Numeric:
+		return (first == minus || first >= '0' && first <= '9') && last >= '0' && last <= '9'
+	}
+	return false
+}
+
 func (n *Node) isContainer() bool {
 	return n._type == Array || n._type == Object
 }
@@ -895,6 +1753,90 @@ func (n *Node) getInteger() (int, error) {
 	return int(float), nil
 }
 
+// getDecimal returns exact decimal value of the numeric node, calculated from the source if it's available
+func (n *Node) getDecimal() (*big.Rat, error) {
+	if !n.IsNumeric() {
+		return nil, errorType()
+	}
+	if n.exact != nil {
+		return new(big.Rat).Set(n.exact), nil
+	}
+	source := n.Source()
+	if source == nil {
+		float, err := n.GetNumeric()
+		if err != nil {
+			return nil, err
+		}
+		source = []byte(strconv.FormatFloat(float, 'g', -1, 64))
+	}
+	value, ok := parseDecimal(source)
+	if !ok {
+		return nil, errorRequest("wrong decimal value '%s'", source)
+	}
+	return value, nil
+}
+
+// maxDecimalExponent limits the exponent of the numbers parsed as big.Rat, the value of `1e1000000000` would take
+// gigabytes of memory
+const maxDecimalExponent = 1 << 12
+
+// parseDecimal returns the exact value of the JSON number, ok is false for the wrong number or too big exponent
+func parseDecimal(source []byte) (value *big.Rat, ok bool) {
+	for i, c := range source {
+		if c == 'e' || c == 'E' {
+			exponent, err := strconv.Atoi(string(source[i+1:]))
+			if err != nil || exponent > maxDecimalExponent || exponent < -maxDecimalExponent {
+				return nil, false
+			}
+			break
+		}
+	}
+	return new(big.Rat).SetString(string(source))
+}
+
+// exactNumber returns the exact value of the JSON number, if float64 value doesn't restore it, else nil
+func exactNumber(source []byte) *big.Rat {
+	value, ok := parseDecimal(source)
+	if !ok {
+		return nil
+	}
+	float, err := strconv.ParseFloat(string(source), 64)
+	if err == nil {
+		if restored, ok := new(big.Rat).SetString(strconv.FormatFloat(float, 'g', -1, 64)); ok && restored.Cmp(value) == 0 {
+			return nil
+		}
+	}
+	return value
+}
+
+// decimalString returns the JSON number of the exact value, fractions of the decimal and binary numbers are finite
+func decimalString(value *big.Rat) string {
+	if value.IsInt() {
+		return value.Num().String()
+	}
+	denom := new(big.Int).Set(value.Denom())
+	twos := denom.TrailingZeroBits()
+	denom.Rsh(denom, twos)
+	fives := uint(0)
+	five, mod := big.NewInt(5), new(big.Int)
+	for {
+		quo, rem := new(big.Int).QuoRem(denom, five, mod)
+		if rem.Sign() != 0 {
+			break
+		}
+		denom = quo
+		fives++
+	}
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 { // infinite fraction, it's never set
+		digits = 64
+	}
+	return value.FloatString(int(digits))
+}
+
 func (n *Node) getUInteger() (uint, error) {
 	result, err := n.getInteger()
 	if err != nil {
@@ -930,19 +1872,203 @@ func (n *Node) Inheritors() (result []*Node) {
 	return
 }
 
-// JSONPath evaluate path for current node
-func (n *Node) JSONPath(path string) (result []*Node, err error) {
+// Reduce folds the children of current container node into one value: fn is called for each child in the order of
+// Inheritors, with the result of the previous call (init for the first one), the key of the Object member or the index
+// of the Array element as a string, and the child itself. Returns the result of the last call, or init for the empty
+// container. Iteration stops on the first error of fn. For non-container node WrongType error will be returned.
+//
+//	total, err := root.MustKey("items").Reduce(0.0, func(acc interface{}, _ string, item *ajson.Node) (interface{}, error) {
+//		price, err := item.MustKey("price").GetNumeric()
+//		return acc.(float64) + price, err
+//	})
+func (n *Node) Reduce(init interface{}, fn func(acc interface{}, key string, child *Node) (interface{}, error)) (interface{}, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if !n.isContainer() {
+		return nil, errorType()
+	}
+	var err error
+	acc := init
+	for i, child := range n.Inheritors() {
+		key := child.Key()
+		if n._type == Array {
+			key = strconv.Itoa(i)
+		}
+		if acc, err = fn(acc, key, child); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// JSONPath evaluate path for current node, `$` refers to the current node, even if it is not the root of the document
+func (n *Node) JSONPath(path string, options ...JSONPathOption) (result []*Node, err error) {
 	commands, err := ParseJSONPath(path)
 	if err != nil {
 		return nil, err
 	}
-	return ApplyJSONPath(n, commands)
+	return ApplyJSONPath(n, commands, options...)
+}
+
+// PathNode is the node found by the JSONPath query with its full path, as returned by Node.Path
+type PathNode struct {
+	Path string
+	Node *Node
+}
+
+// QueryWithPaths evaluate path for current node, same as JSONPath does, and returns every found node with its full
+// path. Paths always start from the root of the document, even if the query is evaluated for a nested node.
+func (n *Node) QueryWithPaths(path string) ([]PathNode, error) {
+	nodes, err := n.JSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PathNode, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, PathNode{Path: node.Path(), Node: node})
+	}
+	return result, nil
+}
+
+// Coalesce returns the first non-null node found by the given paths, checked in the given order.
+// Paths which are invalid or have found nothing are skipped. Returns nil, if nothing was found.
+func (n *Node) Coalesce(paths ...string) *Node {
+	if n == nil {
+		return nil
+	}
+	for _, path := range paths {
+		result, err := n.JSONPath(path)
+		if err != nil {
+			continue
+		}
+		for _, node := range result {
+			if node != nil && !node.IsNull() {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+// StringOr returns the string value of the first node found by the path, or def if nothing was found, or the value is not a String.
+// The path is a JSONPath, or a JSON Pointer if it's empty or starts with '/', e.g. "/user/name".
+func (n *Node) StringOr(path string, def string) string {
+	value, err := n.first(path).GetString()
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// NumericOr returns the numeric value of the first node found by the path, or def if nothing was found, or the value is not a Numeric.
+// See StringOr for the format of the path.
+func (n *Node) NumericOr(path string, def float64) float64 {
+	value, err := n.first(path).GetNumeric()
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// BoolOr returns the bool value of the first node found by the path, or def if nothing was found, or the value is not a Bool.
+// See StringOr for the format of the path.
+func (n *Node) BoolOr(path string, def bool) bool {
+	value, err := n.first(path).GetBool()
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// TypeAt returns the type of the node found by the path, without calculating its value.
+// The path is a JSONPath, or a JSON Pointer if it's empty or starts with '/'.
+// Error will be returned, if the path is wrong or found not exactly one node.
+func (n *Node) TypeAt(path string) (NodeType, error) {
+	node, err := n.single(path)
+	if err != nil {
+		return Null, err
+	}
+	return node.Type(), nil
+}
+
+// TypeHistogram returns the count of the nodes found by the path of each type, types without nodes are omitted.
+// It's useful to check the consistency of the semi-structured data, e.g. whether `$..id` is always a String.
+func (n *Node) TypeHistogram(path string) (map[NodeType]int, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	nodes, err := n.JSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[NodeType]int)
+	for _, node := range nodes {
+		result[node.Type()]++
+	}
+	return result, nil
+}
+
+// find returns the nodes found by the path: the JSON Pointer, if the path is empty or starts with '/', else the JSONPath
+func (n *Node) find(path string) ([]*Node, error) {
+	if path == "" || path[0] == '/' {
+		node, err := n.Pointer(path)
+		if err != nil {
+			return nil, err
+		}
+		return []*Node{node}, nil
+	}
+	return n.JSONPath(path)
+}
+
+// single returns the only node found by the path, or an error if the path found nothing or more than one node
+func (n *Node) single(path string) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	result, err := n.find(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, errorRequest("path '%s' found %d nodes, expected exactly one", path, len(result))
+	}
+	return result[0], nil
+}
+
+// first returns the first node found by the path, or nil
+func (n *Node) first(path string) *Node {
+	if n == nil {
+		return nil
+	}
+	result, err := n.find(path)
+	if err != nil || len(result) == 0 {
+		return nil
+	}
+	return result[0]
+}
+
+// CountJSONPath returns count of elements found by path for current node, without collecting the result slice.
+// Evaluation stops as soon as one of the intermediate steps has found nothing.
+func (n *Node) CountJSONPath(path string) (count int, err error) {
+	commands, err := ParseJSONPath(path)
+	if err != nil {
+		return 0, err
+	}
+	err = eachJSONPath(n, commands, new(jsonPathOptions), func(*Node) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
 // root returns the root node
 func (n *Node) root() (node *Node) {
 	node = n
-	for node.parent != nil {
+	for node != nil && node.parent != nil {
 		node = node.parent
 	}
 	return node
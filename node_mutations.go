@@ -1,8 +1,13 @@
 package ajson
 
 import (
+	"math"
+	"math/big"
+	"sort"
 	"strconv"
+	"strings"
 	"sync/atomic"
+	"unicode/utf8"
 )
 
 // IsDirty is the flag that shows, was node changed or not
@@ -10,6 +15,33 @@ func (n *Node) IsDirty() bool {
 	return n.dirty
 }
 
+// Freeze makes current node and all of its children read-only: every mutation method (Set*, Append*, Delete*, Pop*,
+// Move, Compact, etc.) returns the error of the ErrFrozen category instead of changing them, as well as attempts to
+// move or to remove the frozen node from the unfrozen parent. There is no way to unfreeze the node, use Clone to get
+// its changeable copy.
+//
+// Read methods don't change the tree, except the lazily decoded values, which are stored atomically, so the frozen tree
+// could be queried from many goroutines without locks: parse the data, freeze the root and share it.
+func (n *Node) Freeze() {
+	if n == nil {
+		return
+	}
+	n.frozen = true
+	for _, child := range n.children {
+		child.Freeze()
+	}
+	for _, list := range n.duplicates {
+		for _, child := range list {
+			child.Freeze()
+		}
+	}
+}
+
+// IsFrozen returns true, if current node was frozen with Freeze
+func (n *Node) IsFrozen() bool {
+	return n != nil && n.frozen
+}
+
 // Set updates current node value with the value of any type
 func (n *Node) Set(value interface{}) error {
 	if value == nil {
@@ -47,6 +79,35 @@ func (n *Node) SetNumeric(value float64) error {
 	return n.update(Numeric, value)
 }
 
+// SetBigInt updates current node value with the Numeric value, which is marshaled without loss of precision,
+// see BigNumbers. GetNumeric returns the closest float64 value.
+func (n *Node) SetBigInt(value *big.Int) error {
+	if value == nil {
+		return errorRequest("unsupported numeric value '<nil>'")
+	}
+	return n.setExact(new(big.Rat).SetInt(value))
+}
+
+// SetBigFloat updates current node value with the finite Numeric value, which is marshaled without loss of precision,
+// see BigNumbers. GetNumeric returns the closest float64 value.
+func (n *Node) SetBigFloat(value *big.Float) error {
+	if value == nil || value.IsInf() {
+		return errorRequest("unsupported numeric value '%v'", value)
+	}
+	exact, _ := value.Rat(nil)
+	return n.setExact(exact)
+}
+
+// setExact updates current node value with the Numeric value, keeping the exact one to marshal it
+func (n *Node) setExact(value *big.Rat) error {
+	float, _ := value.Float64()
+	if err := n.SetNumeric(float); err != nil {
+		return err
+	}
+	n.exact = value
+	return nil
+}
+
 // SetString updates current node value with String value
 func (n *Node) SetString(value string) error {
 	return n.update(String, value)
@@ -77,17 +138,129 @@ func (n *Node) SetNode(value *Node) error {
 	if n.isParentOrSelfNode(value) {
 		return errorRequest("attempt to create infinite loop")
 	}
+	if err := n.writable(); err != nil {
+		return err
+	}
 
 	node := value.Clone()
 	node.setReference(n.parent, n.key, n.index)
+	node.keyBorders = n.keyBorders
 	n.setReference(nil, nil, nil)
 	*n = *node
+	for _, child := range n.children {
+		child.parent = n
+	}
+	for _, list := range n.duplicates {
+		for _, child := range list {
+			child.parent = n
+		}
+	}
+	n.touch()
 	if n.parent != nil {
 		n.parent.mark()
 	}
 	return nil
 }
 
+// MapNumeric updates current Numeric node value with the result of fn, else: WrongType error
+func (n *Node) MapNumeric(fn func(value float64) float64) error {
+	value, err := n.GetNumeric()
+	if err != nil {
+		return err
+	}
+	return n.SetNumeric(fn(value))
+}
+
+// MapString updates current String node value with the result of fn, else: WrongType error
+func (n *Node) MapString(fn func(value string) string) error {
+	value, err := n.GetString()
+	if err != nil {
+		return err
+	}
+	return n.SetString(fn(value))
+}
+
+// CoerceNumeric updates current node value with the Numeric value, parsed from the String value, or 1 and 0 for
+// the Bool value. String should contain a valid JSON number, its source is preserved as is, so big integers
+// (like IDs) will be marshaled without loss of precision. Numeric node stays unchanged.
+func (n *Node) CoerceNumeric() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	switch n._type {
+	case Numeric:
+		return nil
+	case String:
+		value, err := n.GetString()
+		if err != nil {
+			return err
+		}
+		node, err := Unmarshal([]byte(value))
+		if err != nil || !node.IsNumeric() {
+			return errorRequest("cannot convert '%s' to numeric", value)
+		}
+		return n.SetNode(node)
+	case Bool:
+		value, err := n.GetBool()
+		if err != nil {
+			return err
+		}
+		if value {
+			return n.SetNumeric(1)
+		}
+		return n.SetNumeric(0)
+	}
+	return errorType()
+}
+
+// CoerceString updates current node value with the String value, made from the Numeric or Bool value.
+// Numeric value is converted as it was in the source, if it's available. String node stays unchanged.
+func (n *Node) CoerceString() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	switch n._type {
+	case String:
+		return nil
+	case Numeric, Bool:
+		value, err := n.AsString()
+		if err != nil {
+			return err
+		}
+		return n.SetString(value)
+	}
+	return errorType()
+}
+
+// CoerceBool updates current node value with the Bool value, parsed from the String value (as strconv.ParseBool
+// does), or made from the Numeric value: false for 0 and true for any other value. Bool node stays unchanged.
+func (n *Node) CoerceBool() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	switch n._type {
+	case Bool:
+		return nil
+	case String:
+		value, err := n.GetString()
+		if err != nil {
+			return err
+		}
+		result, err := strconv.ParseBool(value)
+		if err != nil {
+			return errorRequest("cannot convert '%s' to bool", value)
+		}
+		return n.SetBool(result)
+	case Numeric:
+		value, err := n.GetNumeric()
+		if err != nil {
+			return err
+		}
+		return n.SetBool(value != 0)
+	}
+	return errorType()
+}
+
 // AppendArray appends current Array node values with Node values
 func (n *Node) AppendArray(value ...*Node) error {
 	if !n.IsArray() {
@@ -102,11 +275,64 @@ func (n *Node) AppendArray(value ...*Node) error {
 	return nil
 }
 
-// AppendObject appends current Object node value with key:value
+// ForceObject updates current Null node value with an empty Object value. Object node stays unchanged.
+// For any other type WrongType error will be returned.
+func (n *Node) ForceObject() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	switch n._type {
+	case Object:
+		return nil
+	case Null:
+		return n.SetObject(map[string]*Node{})
+	}
+	return errorType()
+}
+
+// AppendObject appends current Object node value with key:value. If the key already exists, its value (all
+// occurrences of the repeated key) is replaced, use AppendObjectMode to change it.
+// Key should be a valid UTF-8 string without NUL characters. For non Object node (and nil) WrongType error will be
+// returned, use ForceObject to convert the Null node into the Object before.
 func (n *Node) AppendObject(key string, value *Node) error {
+	return n.AppendObjectMode(key, value, AppendReplace)
+}
+
+// AppendMode defines the behaviour of AppendObjectMode for the existing key
+type AppendMode int
+
+const (
+	// AppendReplace replaces the value of the existing key, as AppendObject does
+	AppendReplace AppendMode = iota
+	// AppendFail returns an error for the existing key, errors.Is(err, ErrKeyExists) is true for it
+	AppendFail
+	// AppendKeep keeps the value of the existing key, the given value is not appended
+	AppendKeep
+)
+
+// AppendObjectMode is the same as AppendObject, but the existing key is processed according to the mode: its value is
+// replaced, the error is returned, or the value is kept. It's useful to make the changes repeatable, e.g. to add
+// a default value only if the key is missing:
+//
+//	err := node.AppendObjectMode("name", ajson.StringNode("", "unknown"), ajson.AppendKeep)
+func (n *Node) AppendObjectMode(key string, value *Node, mode AppendMode) error {
 	if !n.IsObject() {
 		return errorType()
 	}
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if _, ok := n.children[key]; ok {
+		switch mode {
+		case AppendReplace:
+		case AppendFail:
+			return errorKeyExists(key)
+		case AppendKeep:
+			return nil
+		default:
+			return errorRequest("unsupported append mode %d", mode)
+		}
+	}
 	err := n.appendNode(&key, value)
 	if err != nil {
 		return err
@@ -115,6 +341,145 @@ func (n *Node) AppendObject(key string, value *Node) error {
 	return nil
 }
 
+// PatchMap sets the members of current Object node from the values of updates, existing members are replaced.
+// Values could be of the same types as for Builder.Set, *Node values are copied, as in SetNode. All values are
+// converted and checked before the first change, so on error current node stays untouched. For non Object node
+// WrongType error will be returned.
+//
+//	err := root.PatchMap(map[string]interface{}{"status": "ok", "code": 200, "error": nil})
+func (n *Node) PatchMap(updates map[string]interface{}) error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if !n.IsObject() {
+		return errorType()
+	}
+	if err := n.writable(); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(updates))
+	nodes := make(map[string]*Node, len(updates))
+	for key, value := range updates {
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		if node, ok := value.(*Node); ok && node != nil {
+			if n.isParentOrSelfNode(node) {
+				return errorRequest("attempt to create infinite loop")
+			}
+			value = node.Clone()
+		}
+		node, err := anyNode(key, value)
+		if err != nil {
+			return err
+		}
+		for _, old := range n.KeyAll(key) {
+			if err = old.writable(); err != nil {
+				return err
+			}
+		}
+		keys = append(keys, key)
+		nodes[key] = node
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := n.AppendObject(key, nodes[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeArrayByKey merges the elements of other Array node into current Array node, matching the objects by the value
+// of keyField member (upsert): members of the element of other are set to the matched element of current node,
+// other members of it are kept, and the element without a match is appended to the end. Values of keyField are
+// compared in the canonical form, so `1` matches `1.0`, but not `"1"`. If several elements of current node have the
+// same value of keyField, the first of them is updated.
+//
+// Elements without keyField member (including the non-object ones) are never matched: such elements of current node
+// stay untouched and such elements of other are appended. Elements of other are copied, other node stays untouched.
+// All matches are checked before the first change, so on error current node stays untouched.
+// For non Array nodes WrongType error will be returned.
+//
+//	root := ajson.Must(ajson.Unmarshal([]byte(`[{"id":1,"name":"foo"},{"id":2,"name":"bar"}]`)))
+//	err := root.MergeArrayByKey(ajson.Must(ajson.Unmarshal([]byte(`[{"id":2,"name":"baz"},{"id":3}]`))), "id")
+//	// [{"id":1,"name":"foo"},{"id":2,"name":"baz"},{"id":3}]
+func (n *Node) MergeArrayByKey(other *Node, keyField string) error {
+	if n == nil || other == nil {
+		return errorUnparsed()
+	}
+	if !n.IsArray() || !other.IsArray() {
+		return errorType()
+	}
+	if err := n.writable(); err != nil {
+		return err
+	}
+	index := make(map[string]*Node)
+	for _, element := range n.Inheritors() {
+		key, ok, err := element.mergeKey(keyField)
+		if err != nil {
+			return err
+		}
+		if _, exists := index[key]; ok && !exists {
+			index[key] = element
+		}
+	}
+	sources := other.Inheritors()
+	targets := make([]*Node, len(sources))
+	for i, element := range sources {
+		sources[i] = element.Clone()
+		key, ok, err := sources[i].mergeKey(keyField)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if target, exists := index[key]; exists {
+			targets[i] = target
+			if err = target.writable(); err != nil {
+				return err
+			}
+			for member := range sources[i].children {
+				for _, old := range target.KeyAll(member) {
+					if err = old.writable(); err != nil {
+						return err
+					}
+				}
+			}
+		} else {
+			index[key] = sources[i]
+		}
+	}
+	for i, source := range sources {
+		if targets[i] == nil {
+			if err := n.AppendArray(source); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, member := range source.Inheritors() {
+			if err := targets[i].AppendObject(member.Key(), member); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeKey returns the canonical JSON of the keyField member of current Object node, if it exists
+func (n *Node) mergeKey(keyField string) (string, bool, error) {
+	if !n.IsObject() {
+		return "", false, nil
+	}
+	member, ok := n.children[keyField]
+	if !ok {
+		return "", false, nil
+	}
+	value, err := MarshalWithOptions(member, Canonical())
+	return string(value), true, err
+}
+
 // DeleteNode removes element child
 func (n *Node) DeleteNode(value *Node) error {
 	return n.remove(value)
@@ -164,6 +529,297 @@ func (n *Node) Delete() error {
 	return n.parent.remove(n)
 }
 
+// ReplaceAll replaces every node found by the JSONPath with the node returned by fn.
+// If fn returns nil or the same node, it's treated as the node was changed in place.
+//
+// Nested nodes are processed before their parents, so fn for the parent node receives the already replaced children.
+func (n *Node) ReplaceAll(path string, fn func(node *Node) (*Node, error)) error {
+	result, err := n.JSONPath(path)
+	if err != nil {
+		return err
+	}
+	nodes := make([]*Node, 0, len(result))
+	levels := make(map[*Node]int, len(result))
+	for _, node := range result {
+		if _, ok := levels[node]; !ok {
+			levels[node] = node.level()
+			nodes = append(nodes, node)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return levels[nodes[i]] > levels[nodes[j]]
+	})
+	for _, node := range nodes {
+		value, err := fn(node)
+		if err != nil {
+			return err
+		}
+		if value == nil || value == node {
+			continue
+		}
+		if err = node.SetNode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NumerifyStrings replaces every String node in current node and below it, which value is a valid JSON number (like
+// "300" or "-1.5e3", without whitespaces), with the Numeric node of the same value, and returns the count of the
+// replaced nodes. The number keeps its source, so big numbers don't lose precision on marshaling. If paths are given,
+// only the nodes found by these JSONPath queries and their children are converted.
+//
+//	count, err := root.NumerifyStrings("$..amount") // {"amount":"300"} -> {"amount":300}
+func (n *Node) NumerifyStrings(paths ...string) (count int, err error) {
+	if n == nil {
+		return 0, errorUnparsed()
+	}
+	roots := []*Node{n}
+	if len(paths) != 0 {
+		roots = roots[:0]
+		for _, path := range paths {
+			result, err := n.JSONPath(path)
+			if err != nil {
+				return 0, err
+			}
+			roots = append(roots, result...)
+		}
+	}
+	var nodes []*Node
+	found := make(map[*Node]bool)
+	for _, root := range roots {
+		_ = root.walk(func(node *Node) error {
+			if node._type == String && !found[node] {
+				found[node] = true
+				nodes = append(nodes, node)
+			}
+			return nil
+		})
+	}
+	for _, node := range nodes {
+		value, err := node.GetString()
+		if err != nil {
+			return count, err
+		}
+		if value == "" || !validSource(Numeric, []byte(value)) {
+			continue
+		}
+		number, err := Unmarshal([]byte(value))
+		if err != nil || !number.IsNumeric() {
+			continue
+		}
+		if err = node.SetNode(number); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// SetRaw replaces the value of the only node found by the path with the value parsed from raw. The path is a JSONPath,
+// or a JSON Pointer if it's empty or starts with '/'. An error will be returned if the path found nothing or more than
+// one node, or if raw is not a valid JSON. Raw is parsed with Unmarshal, so it must not be changed while the node is
+// in use.
+//
+//	err := root.SetRaw("$.response.body", []byte(`{"status":"ok"}`))
+//	err = root.SetRaw("/response/body", []byte(`{"status":"ok"}`))
+func (n *Node) SetRaw(path string, raw []byte) error {
+	node, err := n.single(path)
+	if err != nil {
+		return err
+	}
+	value, err := Unmarshal(raw)
+	if err != nil {
+		return err
+	}
+	return node.SetNode(value)
+}
+
+// MoveOption is a functional option for Move
+type MoveOption func(options *moveOptions)
+
+type moveOptions struct {
+	parents bool
+}
+
+// CreateParents makes Move to create the missing Object nodes on the destination path
+func CreateParents() MoveOption {
+	return func(options *moveOptions) {
+		options.parents = true
+	}
+}
+
+// Move detaches the only node found by the JSONPath fromPath and attaches it by the JSONPath toPath, same as the
+// "move" operation of JSON Patch (RFC 6902). All steps of toPath should be keys or indexes. The last step is the key
+// of the Object member, which is replaced if it exists, or the index of the Array element to insert before: next
+// elements are shifted, index "-" appends the node. Indexes of the source Array are shifted as well.
+// Missing Objects on the destination path are created only with the CreateParents option.
+//
+// An error will be returned on attempt to move the root or to move the node into itself or its children.
+// On error current node stays untouched.
+//
+//	err := root.Move("$.user.address", "$.profile.address", ajson.CreateParents())
+func (n *Node) Move(fromPath, toPath string, options ...MoveOption) error {
+	opts := new(moveOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	node, err := n.single(fromPath)
+	if err != nil {
+		return err
+	}
+	if node == n {
+		return errorRequest("attempt to move the root node")
+	}
+	commands, err := ParseJSONPath(toPath)
+	if err != nil {
+		return err
+	}
+	if len(commands) < 2 || commands[0] != "$" {
+		return errorRequest("wrong destination path '%s'", toPath)
+	}
+	keys := make([]string, 0, len(commands)-1)
+	for _, cmd := range commands[1:] {
+		key, ok := pathKey(cmd)
+		if !ok {
+			return errorRequest("wrong destination path '%s': step '%s' is not a key or an index", toPath, cmd)
+		}
+		keys = append(keys, key)
+	}
+
+	parent, last := n, keys[len(keys)-1]
+	keys = keys[:len(keys)-1]
+	for len(keys) > 0 {
+		child, ok := parent.children[keys[0]]
+		if !ok || !parent.isContainer() {
+			break
+		}
+		parent, keys = child, keys[1:]
+	}
+	if parent.isParentOrSelfNode(node) {
+		return errorRequest("attempt to create infinite loop")
+	}
+	for _, check := range []*Node{node, node.parent, parent} {
+		if err = check.writable(); err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		if !opts.parents || !parent.IsObject() {
+			return errorRequest("destination path '%s' not found", toPath)
+		}
+		for _, key := range append(keys, last) {
+			if err = validateKey(key); err != nil {
+				return err
+			}
+		}
+		// missing parents are created detached, so the tree is untouched until the node is moved
+		top := ObjectNode("", nil)
+		target := top
+		for _, key := range keys[1:] {
+			child := ObjectNode("", nil)
+			_ = target.AppendObject(key, child)
+			target = child
+		}
+		if err = node.Delete(); err != nil {
+			return err
+		}
+		_ = target.AppendObject(last, node)
+		return parent.AppendObject(keys[0], top)
+	}
+
+	switch parent._type {
+	case Object:
+		if err = validateKey(last); err != nil {
+			return err
+		}
+		for _, old := range parent.KeyAll(last) {
+			if err = old.writable(); err != nil {
+				return err
+			}
+		}
+		if err = node.Delete(); err != nil {
+			return err
+		}
+		return parent.AppendObject(last, node)
+	case Array:
+		size := parent.Size()
+		if node.parent == parent {
+			size--
+		}
+		index := size
+		if last != "-" {
+			index, err = strconv.Atoi(last)
+			if err != nil || index < 0 || index > size {
+				return errorRequest("wrong destination index '%s'", last)
+			}
+		}
+		if err = node.Delete(); err != nil {
+			return err
+		}
+		return parent.insertNode(index, node)
+	}
+	return errorType()
+}
+
+// PruneOption is a functional option for PruneEmpty
+type PruneOption func(options *pruneOptions)
+
+type pruneOptions struct {
+	nulls bool
+}
+
+// PruneNulls makes PruneEmpty to remove null members and elements too
+func PruneNulls() PruneOption {
+	return func(options *pruneOptions) {
+		options.nulls = true
+	}
+}
+
+// PruneEmpty removes members of the objects and elements of the arrays, which are empty objects or arrays,
+// recursively. Children are pruned before their parents, so containers which became empty are removed too.
+// Current node itself is never removed. For non-container node WrongType error will be returned.
+func (n *Node) PruneEmpty(options ...PruneOption) error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if !n.isContainer() {
+		return errorType()
+	}
+	opts := new(pruneOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	return n.prune(opts)
+}
+
+// prune removes the empty children of current container, children of the Array are processed from the end,
+// so the indexes of the unprocessed elements stay the same
+func (n *Node) prune(options *pruneOptions) error {
+	var children []*Node
+	if n.IsArray() {
+		children = n.Inheritors()
+	} else {
+		for _, key := range n.Keys() {
+			children = append(children, n.KeyAll(key)...)
+		}
+	}
+	for i := len(children) - 1; i >= 0; i-- {
+		child := children[i]
+		if child.isContainer() {
+			if err := child.prune(options); err != nil {
+				return err
+			}
+		}
+		if (child.isContainer() && len(child.children) == 0) || (options.nulls && child.IsNull()) {
+			if err := n.remove(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Clone creates full copy of current Node. With all child, but without link to the parent.
 func (n *Node) Clone() *Node {
 	node := n.clone()
@@ -171,20 +827,54 @@ func (n *Node) Clone() *Node {
 	return node
 }
 
+// EnsureArray returns current node, if it is an Array, or a new Array node with the single element: the copy of
+// current node. Use IsArray to check, which of the cases it is. Returns nil for nil node.
+//
+//	for _, item := range root.MustKey("items").EnsureArray().MustArray() {
+//		// "items" could be an object or an array of objects
+//	}
+func (n *Node) EnsureArray() *Node {
+	if n == nil {
+		return nil
+	}
+	if n._type == Array {
+		return n
+	}
+	return ArrayNode("", []*Node{n.Clone()})
+}
+
 func (n *Node) clone() *Node {
 	node := &Node{
-		parent:   n.parent,
-		children: make(map[string]*Node, len(n.children)),
-		key:      cptrs(n.key),
-		index:    cptri(n.index),
-		_type:    n._type,
-		data:     n.data,
-		borders:  n.borders,
-		value:    n.value,
-		dirty:    n.dirty,
+		parent:     n.parent,
+		children:   make(map[string]*Node, len(n.children)),
+		key:        cptrs(n.key),
+		index:      cptri(n.index),
+		_type:      n._type,
+		data:       n.data,
+		borders:    n.borders,
+		value:      n.value,
+		keyBorders: n.keyBorders,
+		dirty:      n.dirty,
+		exact:      n.exact,
 	}
 	for key, value := range n.children {
 		node.children[key] = value.clone()
+		node.children[key].parent = node
+	}
+	if n.duplicates != nil {
+		node.duplicates = make(map[string][]*Node, len(n.duplicates))
+		for key, list := range n.duplicates {
+			clones := make([]*Node, len(list))
+			for i, value := range list {
+				if value == n.children[key] {
+					clones[i] = node.children[key]
+				} else {
+					clones[i] = value.clone()
+					clones[i].parent = node
+				}
+			}
+			node.duplicates[key] = clones
+		}
 	}
 	return node
 }
@@ -196,6 +886,14 @@ func (n *Node) update(_type NodeType, value interface{}) error {
 	if err != nil {
 		return err
 	}
+	if err = n.writable(); err != nil {
+		return err
+	}
+	for _, child := range n.children {
+		if err = child.writable(); err != nil {
+			return err
+		}
+	}
 	// update
 	n.mark()
 	n.clear()
@@ -253,20 +951,58 @@ func (n *Node) validate(_type NodeType, value interface{}) error {
 		}
 	case Array:
 		if value != nil {
-			if _, ok := value.([]*Node); !ok {
+			nodes, ok := value.([]*Node)
+			if !ok {
 				return errorType()
 			}
+			for _, node := range nodes {
+				if n.isParentOrSelfNode(node) {
+					return errorRequest("attempt to create infinite loop")
+				}
+			}
 		}
 	case Object:
 		if value != nil {
-			if _, ok := value.(map[string]*Node); !ok {
+			nodes, ok := value.(map[string]*Node)
+			if !ok {
 				return errorType()
 			}
+			for key, node := range nodes {
+				if err := validateKey(key); err != nil {
+					return err
+				}
+				if n.isParentOrSelfNode(node) {
+					return errorRequest("attempt to create infinite loop")
+				}
+			}
 		}
 	}
 	return nil
 }
 
+// validateKey checks that the key of the Object could be marshaled to JSON without loss:
+// it should be a valid UTF-8 string without NUL characters. Empty key is valid.
+func validateKey(key string) error {
+	if !utf8.ValidString(key) || strings.IndexByte(key, 0) >= 0 {
+		return errorRequest("invalid key %q", key)
+	}
+	return nil
+}
+
+// pathKey returns the key or the index from the step of the parsed JSONPath, ok is false for any other kind of step
+func pathKey(cmd string) (key string, ok bool) {
+	switch {
+	case cmd == "$", cmd == "@", cmd == "..", cmd == "*", cmd == "",
+		strings.HasPrefix(cmd, "("), strings.HasPrefix(cmd, "?("):
+		return "", false
+	}
+	key, ok = str(cmd)
+	if key == cmd && strings.ContainsAny(cmd, ",:") { // union or slice
+		return "", false
+	}
+	return key, ok
+}
+
 // remove method removes value from current container
 func (n *Node) remove(value *Node) error {
 	if !n.isContainer() {
@@ -275,10 +1011,21 @@ func (n *Node) remove(value *Node) error {
 	if value.parent != n {
 		return errorRequest("wrong parent")
 	}
+	if err := n.writable(); err != nil {
+		return err
+	}
+	if err := value.writable(); err != nil {
+		return err
+	}
 	n.mark()
 	if n.IsArray() {
+		if !n.contiguous() {
+			n.repairIndices()
+		}
 		delete(n.children, strconv.Itoa(*value.index))
 		n.dropindex(*value.index)
+	} else if _, ok := n.duplicates[*value.key]; ok {
+		n.dropduplicate(value)
 	} else {
 		delete(n.children, *value.key)
 	}
@@ -286,6 +1033,114 @@ func (n *Node) remove(value *Node) error {
 	return nil
 }
 
+// dropduplicate: internal method to remove one of the occurrences of the repeated key,
+// the last of the remaining occurrences becomes the value of the key
+func (n *Node) dropduplicate(value *Node) {
+	key := *value.key
+	list := make([]*Node, 0, len(n.duplicates[key]))
+	for _, node := range n.duplicates[key] {
+		if node != value {
+			list = append(list, node)
+		}
+	}
+	if len(list) > 1 {
+		n.duplicates[key] = list
+	} else {
+		delete(n.duplicates, key)
+	}
+	if len(list) == 0 {
+		delete(n.children, key)
+	} else {
+		n.children[key] = list[len(list)-1]
+	}
+}
+
+// RepairIndices renumbers the elements of current Array node in the order of their indexes, to close the gaps
+// between them, e.g. the elements with indexes 1 and 3 become 0 and 1. Mutation methods keep the indexes
+// contiguous, so the gaps could appear only if the children of the node were changed directly. Marshal returns an
+// error for the array with gaps. For non Array node WrongType error will be returned.
+func (n *Node) RepairIndices() error {
+	if n == nil {
+		return errorUnparsed()
+	}
+	if n._type != Array {
+		return errorType()
+	}
+	if n.contiguous() {
+		return nil
+	}
+	if err := n.writable(); err != nil {
+		return err
+	}
+	n.repairIndices()
+	n.mark()
+	return nil
+}
+
+// repairTree renumbers the elements of all arrays in current node and below it, see RepairIndices
+func (n *Node) repairTree() error {
+	if n._type == Array && !n.contiguous() {
+		if err := n.writable(); err != nil {
+			return err
+		}
+		n.repairIndices()
+		n.mark()
+	}
+	for _, child := range n.children {
+		if child == nil {
+			continue
+		}
+		if err := child.repairTree(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contiguous returns true, if the indexes of the array elements are 0, 1, ..., len - 1
+func (n *Node) contiguous() bool {
+	for i := 0; i < len(n.children); i++ {
+		if child, ok := n.children[strconv.Itoa(i)]; !ok || child == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// repairIndices: internal method to renumber the array elements in the order of their indexes, without gaps
+func (n *Node) repairIndices() {
+	type element struct {
+		index int
+		key   string
+		node  *Node
+	}
+	elements := make([]element, 0, len(n.children))
+	for key, child := range n.children {
+		if child == nil {
+			continue
+		}
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 {
+			index = math.MaxInt32
+		}
+		elements = append(elements, element{index: index, key: key, node: child})
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].index != elements[j].index {
+			return elements[i].index < elements[j].index
+		}
+		return elements[i].key < elements[j].key
+	})
+	n.children = make(map[string]*Node, len(elements))
+	for i, element := range elements {
+		index := i
+		element.node.index = &index
+		element.node.key = nil
+		element.node.parent = n
+		n.children[strconv.Itoa(i)] = element.node
+	}
+}
+
 // dropindex: internal method to reindexing current array value
 func (n *Node) dropindex(index int) {
 	for i := index + 1; i <= len(n.children); i++ {
@@ -303,6 +1158,19 @@ func (n *Node) appendNode(key *string, value *Node) error {
 	if n.isParentOrSelfNode(value) {
 		return errorRequest("attempt to create infinite loop")
 	}
+	if err := n.writable(); err != nil {
+		return err
+	}
+	if err := value.writable(); err != nil {
+		return err
+	}
+	if key != nil {
+		for _, old := range n.KeyAll(*key) {
+			if err := old.writable(); err != nil {
+				return err
+			}
+		}
+	}
 	if value.parent != nil {
 		if err := value.parent.remove(value); err != nil {
 			return err
@@ -310,7 +1178,17 @@ func (n *Node) appendNode(key *string, value *Node) error {
 	}
 	value.parent = n
 	value.key = key
+	value.keyBorders = nil
 	if key != nil {
+		if list, ok := n.duplicates[*key]; ok {
+			// all occurrences of the repeated key are replaced with the value
+			for _, old := range list {
+				old.parent = nil
+			}
+			value.parent = n
+			delete(n.duplicates, *key)
+			delete(n.children, *key)
+		}
 		if old, ok := n.children[*key]; ok {
 			if old != value {
 				if err := n.remove(old); err != nil {
@@ -321,14 +1199,65 @@ func (n *Node) appendNode(key *string, value *Node) error {
 		n.children[*key] = value
 	} else {
 		index := len(n.children)
+		if _, ok := n.children[strconv.Itoa(index-1)]; (index != 0 && !ok) || n.children[strconv.Itoa(index)] != nil {
+			// indexes have a gap, see RepairIndices
+			n.repairIndices()
+		}
 		value.index = &index
 		n.children[strconv.Itoa(index)] = value
 	}
 	return nil
 }
 
+// insertNode inserts the value into current array before the index, next elements are shifted
+func (n *Node) insertNode(index int, value *Node) error {
+	if err := n.appendNode(nil, value); err != nil {
+		return err
+	}
+	if !n.contiguous() {
+		n.repairIndices()
+	}
+	for i := len(n.children) - 1; i > index; i-- {
+		current, next := n.children[strconv.Itoa(i-1)], i
+		current.index = &next
+		n.children[strconv.Itoa(i)] = current
+	}
+	value.index = &index
+	n.children[strconv.Itoa(index)] = value
+	n.mark()
+	return nil
+}
+
+// writable returns an error, if current node is frozen
+func (n *Node) writable() error {
+	if n.frozen {
+		return errorFrozen()
+	}
+	return nil
+}
+
+// writableTree returns an error, if current node or any of its children is frozen. Children are visited as they are
+// stored, not with walk, as the arrays could have the gaps in the indexes (see RepairIndices).
+func (n *Node) writableTree() error {
+	stack := []*Node{n}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if err := node.writable(); err != nil {
+			return err
+		}
+		for _, child := range node.children {
+			if child != nil {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return nil
+}
+
 // mark node as dirty, with all parents (up the tree)
 func (n *Node) mark() {
+	n.touch()
 	node := n
 	for node != nil && !node.dirty {
 		node.dirty = true
@@ -343,7 +1272,14 @@ func (n *Node) clear() {
 	for key := range n.children {
 		n.children[key].parent = nil
 	}
+	for _, list := range n.duplicates {
+		for _, node := range list {
+			node.parent = nil
+		}
+	}
 	n.children = nil
+	n.duplicates = nil
+	n.exact = nil
 }
 
 // isParentOrSelfNode check if current node is the same as given one of parents
@@ -363,6 +1299,14 @@ func (n *Node) isParentNode(node *Node) bool {
 	return false
 }
 
+// level returns count of parents of current node
+func (n *Node) level() (level int) {
+	for node := n.parent; node != nil; node = node.parent {
+		level++
+	}
+	return
+}
+
 // setReference updates references of current node
 func (n *Node) setReference(parent *Node, key *string, index *int) {
 	n.parent = parent
@@ -1,8 +1,13 @@
 package ajson
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -583,6 +588,126 @@ func TestNode_mutations(t *testing.T) {
 	}
 }
 
+func TestNode_MapNumeric(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"celsius": 100, "name": "water"}`)))
+	node := root.MustKey("celsius")
+	if err := node.MapNumeric(func(value float64) float64 { return value*9/5 + 32 }); err != nil {
+		t.Fatalf("MapNumeric() unexpected error: %v", err)
+	}
+	if !node.IsDirty() || !root.IsDirty() {
+		t.Errorf("MapNumeric() node is not dirty")
+	}
+	if value := node.MustNumeric(); value != 212 {
+		t.Errorf("MapNumeric() value = %v, expected 212", value)
+	}
+	if value, err := Marshal(node); err != nil || string(value) != "212" {
+		t.Errorf("Marshal() = %s, %v, expected 212", value, err)
+	}
+	if err := root.MustKey("name").MapNumeric(math.Sqrt); err == nil {
+		t.Errorf("MapNumeric() expected error for String node")
+	}
+	if err := (*Node)(nil).MapNumeric(math.Sqrt); err == nil {
+		t.Errorf("MapNumeric() expected error for nil node")
+	}
+}
+
+func TestNode_MapString(t *testing.T) {
+	root := Must(Unmarshal([]byte(`["foo", 1]`)))
+	node := root.MustIndex(0)
+	if err := node.MapString(strings.ToUpper); err != nil {
+		t.Fatalf("MapString() unexpected error: %v", err)
+	}
+	if value, err := Marshal(root); err != nil || string(value) != `["FOO",1]` {
+		t.Errorf("Marshal() = %s, %v, expected [\"FOO\",1]", value, err)
+	}
+	if err := root.MustIndex(1).MapString(strings.ToUpper); err == nil {
+		t.Errorf("MapString() expected error for Numeric node")
+	}
+	if err := (*Node)(nil).MapString(strings.ToUpper); err == nil {
+		t.Errorf("MapString() expected error for nil node")
+	}
+}
+
+func TestNode_Coerce(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		coerce   func(node *Node) error
+		expected string
+		err      bool
+	}{
+		{name: "numeric: numeric", json: `1.50`, coerce: (*Node).CoerceNumeric, expected: `1.50`},
+		{name: "numeric: string", json: `"200"`, coerce: (*Node).CoerceNumeric, expected: `200`},
+		{name: "numeric: string float", json: `"-1.5e3"`, coerce: (*Node).CoerceNumeric, expected: `-1.5e3`},
+		{name: "numeric: string big", json: `"7301896625109403654"`, coerce: (*Node).CoerceNumeric, expected: `7301896625109403654`},
+		{name: "numeric: string spaces", json: `" 42 "`, coerce: (*Node).CoerceNumeric, expected: `42`},
+		{name: "numeric: string wrong", json: `"42a"`, coerce: (*Node).CoerceNumeric, err: true},
+		{name: "numeric: string hex", json: `"0x10"`, coerce: (*Node).CoerceNumeric, err: true},
+		{name: "numeric: string json", json: `"[1]"`, coerce: (*Node).CoerceNumeric, err: true},
+		{name: "numeric: string empty", json: `""`, coerce: (*Node).CoerceNumeric, err: true},
+		{name: "numeric: true", json: `true`, coerce: (*Node).CoerceNumeric, expected: `1`},
+		{name: "numeric: false", json: `false`, coerce: (*Node).CoerceNumeric, expected: `0`},
+		{name: "numeric: null", json: `null`, coerce: (*Node).CoerceNumeric, err: true},
+		{name: "numeric: array", json: `[1]`, coerce: (*Node).CoerceNumeric, err: true},
+
+		{name: "string: string", json: `"foo"`, coerce: (*Node).CoerceString, expected: `"foo"`},
+		{name: "string: numeric", json: `1.50`, coerce: (*Node).CoerceString, expected: `"1.50"`},
+		{name: "string: numeric big", json: `7301896625109403654`, coerce: (*Node).CoerceString, expected: `"7301896625109403654"`},
+		{name: "string: bool", json: `false`, coerce: (*Node).CoerceString, expected: `"false"`},
+		{name: "string: null", json: `null`, coerce: (*Node).CoerceString, err: true},
+		{name: "string: object", json: `{}`, coerce: (*Node).CoerceString, err: true},
+
+		{name: "bool: bool", json: `true`, coerce: (*Node).CoerceBool, expected: `true`},
+		{name: "bool: string", json: `"true"`, coerce: (*Node).CoerceBool, expected: `true`},
+		{name: "bool: string number", json: `"0"`, coerce: (*Node).CoerceBool, expected: `false`},
+		{name: "bool: string wrong", json: `"yes"`, coerce: (*Node).CoerceBool, err: true},
+		{name: "bool: numeric", json: `-0.5`, coerce: (*Node).CoerceBool, expected: `true`},
+		{name: "bool: numeric zero", json: `0`, coerce: (*Node).CoerceBool, expected: `false`},
+		{name: "bool: null", json: `null`, coerce: (*Node).CoerceBool, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(`{"value":` + test.json + `}`)))
+			err := test.coerce(root.MustKey("value"))
+			if test.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				if root.IsDirty() {
+					t.Errorf("node was changed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if value, err := Marshal(root); err != nil || string(value) != `{"value":`+test.expected+`}` {
+				t.Errorf("Marshal() = %s, %v, expected %s", value, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_Coerce_value(t *testing.T) {
+	node := StringNode("", "7301896625109403654")
+	if err := node.CoerceNumeric(); err != nil {
+		t.Fatalf("CoerceNumeric() unexpected error: %s", err)
+	}
+	if value := node.MustNumeric(); value != 7301896625109403654 {
+		t.Errorf("MustNumeric() = %v", value)
+	}
+	node = NumericNode("", 0.1)
+	if err := node.CoerceString(); err != nil {
+		t.Fatalf("CoerceString() unexpected error: %s", err)
+	}
+	if value := node.MustString(); value != "0.1" {
+		t.Errorf("MustString() = %v", value)
+	}
+	if err := (*Node)(nil).CoerceBool(); err == nil {
+		t.Errorf("CoerceBool() expected error for nil node")
+	}
+}
+
 func TestNode_AppendArray(t *testing.T) {
 	if err := Must(Unmarshal([]byte(`[{"foo":"bar"}]`))).AppendArray(NullNode("")); err != nil {
 		t.Errorf("AppendArray should return error")
@@ -720,6 +845,168 @@ func TestNode_AppendObject(t *testing.T) {
 	}
 }
 
+func TestNode_AppendObject_receiver(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"null": null, "string": "foo", "numeric": 1, "bool": true, "array": []}`)))
+	for _, key := range []string{"null", "string", "numeric", "bool", "array"} {
+		t.Run(key, func(t *testing.T) {
+			node := root.MustKey(key)
+			err := node.AppendObject("foo", NullNode(""))
+			if !errors.Is(err, ErrWrongType) {
+				t.Errorf("AppendObject() error = %v, expected %v", err, ErrWrongType)
+			}
+			if node.IsDirty() {
+				t.Errorf("AppendObject() changed the node")
+			}
+		})
+	}
+	if err := (*Node)(nil).AppendObject("foo", NullNode("")); !errors.Is(err, ErrWrongType) {
+		t.Errorf("AppendObject() error = %v, expected %v", err, ErrWrongType)
+	}
+}
+
+func TestNode_AppendObjectMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		mode     AppendMode
+		expected string
+		err      error
+	}{
+		{name: "replace", json: `{"foo":"bar"}`, mode: AppendReplace, expected: `{"foo":1}`},
+		{name: "replace duplicates", json: `{"foo":"bar","foo":"baz"}`, mode: AppendReplace, expected: `{"foo":1}`},
+		{name: "fail", json: `{"foo":"bar"}`, mode: AppendFail, expected: `{"foo":"bar"}`, err: ErrKeyExists},
+		{name: "keep", json: `{"foo":"bar"}`, mode: AppendKeep, expected: `{"foo":"bar"}`},
+		{name: "keep duplicates", json: `{"foo":"bar","foo":"baz"}`, mode: AppendKeep, expected: `{"foo":"bar","foo":"baz"}`},
+		{name: "missing fail", json: `{}`, mode: AppendFail, expected: `{"foo":1}`},
+		{name: "missing keep", json: `{}`, mode: AppendKeep, expected: `{"foo":1}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(UnmarshalWithOptions([]byte(test.json), DuplicateKeys()))
+			err := root.AppendObjectMode("foo", NumericNode("", 1), test.mode)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("AppendObjectMode() error = %v, expected %v", err, test.err)
+				}
+				if root.IsDirty() {
+					t.Errorf("AppendObjectMode() changed the node")
+				}
+			} else if err != nil {
+				t.Fatalf("AppendObjectMode() unexpected error: %s", err)
+			}
+			if value := root.String(); value != test.expected {
+				t.Errorf("AppendObjectMode() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+	if err := Must(Unmarshal([]byte(`{"foo":1}`))).AppendObjectMode("foo", NullNode(""), AppendMode(10)); err == nil {
+		t.Errorf("AppendObjectMode() expected error for unknown mode")
+	}
+	if err := Must(Unmarshal([]byte(`[]`))).AppendObjectMode("foo", NullNode(""), AppendKeep); !errors.Is(err, ErrWrongType) {
+		t.Errorf("AppendObjectMode() error = %v, expected %v", err, ErrWrongType)
+	}
+	if err := (*Node)(nil).AppendObjectMode("foo", NullNode(""), AppendKeep); !errors.Is(err, ErrWrongType) {
+		t.Errorf("AppendObjectMode() error = %v, expected %v", err, ErrWrongType)
+	}
+}
+
+func TestNode_ForceObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected string
+		err      error
+	}{
+		{name: "null", json: `null`, expected: `{"foo":1}`},
+		{name: "object", json: `{"bar":2}`, expected: `{"bar":2,"foo":1}`},
+		{name: "string", json: `"foo"`, err: ErrWrongType},
+		{name: "numeric", json: `1`, err: ErrWrongType},
+		{name: "array", json: `[]`, err: ErrWrongType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(`{"value":` + test.json + `}`)))
+			node := root.MustKey("value")
+			err := node.ForceObject()
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("ForceObject() error = %v, expected %v", err, test.err)
+				}
+				if value, _ := Marshal(node); string(value) != test.json {
+					t.Errorf("ForceObject() changed the node: %s", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ForceObject() unexpected error: %v", err)
+			}
+			if err = node.AppendObject("foo", NumericNode("", 1)); err != nil {
+				t.Fatalf("AppendObject() unexpected error: %v", err)
+			}
+			if value, err := MarshalWithOptions(root, SortKeys()); err != nil || string(value) != `{"value":`+test.expected+`}` {
+				t.Errorf("Marshal() = %s, %v, expected %s", value, err, test.expected)
+			}
+		})
+	}
+	if err := (*Node)(nil).ForceObject(); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("ForceObject() error = %v, expected %v", err, ErrNotParsed)
+	}
+}
+
+func TestNode_AppendObject_key(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		err  bool
+	}{
+		{name: "empty", key: ""},
+		{name: "ascii", key: "foo"},
+		{name: "unicode", key: "ключ 🔑"},
+		{name: "escaped", key: "\"\\\n"},
+		{name: "invalid UTF-8", key: "foo\xffbar", err: true},
+		{name: "truncated UTF-8", key: "\xd0", err: true},
+		{name: "NUL", key: "foo\x00", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := ObjectNode("", nil)
+			err := root.AppendObject(test.key, NullNode(""))
+			if (err != nil) != test.err {
+				t.Fatalf("AppendObject() error = %v, expected error %v", err, test.err)
+			}
+			if test.err {
+				if root.Size() != 0 {
+					t.Errorf("AppendObject() appended the invalid key")
+				}
+				return
+			}
+			value, err := Marshal(root)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if key := Must(Unmarshal(value)).Keys(); len(key) != 1 || key[0] != test.key {
+				t.Errorf("Marshal() key = %q, expected %q", key, test.key)
+			}
+		})
+	}
+}
+
+func TestNode_SetObject_key(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":"bar"}`)))
+	if err := root.SetObject(map[string]*Node{"\xff": NullNode("")}); err == nil {
+		t.Errorf("SetObject() expected error for invalid key")
+	}
+	if value, err := Marshal(root); err != nil || string(value) != `{"foo":"bar"}` {
+		t.Errorf("Marshal() = %s, %v, expected unchanged value", value, err)
+	}
+	if err := root.SetObject(map[string]*Node{"": NullNode("")}); err != nil {
+		t.Errorf("SetObject() unexpected error for empty key: %v", err)
+	}
+	if _, err := NewObject().Set("\x00", 1).Build(); err == nil {
+		t.Errorf("Builder.Set() expected error for NUL key")
+	}
+}
+
 func TestNode_AppendObject_self(t *testing.T) {
 	root := Must(Unmarshal([]byte(`{"foo":{"bar":"baz"},"fiz":null}`)))
 
@@ -776,6 +1063,48 @@ func TestNode_AppendObject_self(t *testing.T) {
 	}
 }
 
+func TestNode_cycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(root *Node) error
+	}{
+		{name: "AppendArray", mutate: func(root *Node) error {
+			return root.MustKey("array").MustIndex(0).AppendArray(root)
+		}},
+		{name: "AppendObject", mutate: func(root *Node) error {
+			return root.MustKey("object").AppendObject("root", root)
+		}},
+		{name: "SetArray", mutate: func(root *Node) error {
+			return root.MustKey("array").MustIndex(0).SetArray([]*Node{NullNode(""), root.MustKey("array")})
+		}},
+		{name: "SetObject", mutate: func(root *Node) error {
+			return root.MustKey("object").SetObject(map[string]*Node{"self": root.MustKey("object")})
+		}},
+		{name: "SetNode", mutate: func(root *Node) error {
+			return root.MustKey("object").MustKey("key").SetNode(root)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(`{"array":[[1]],"object":{"key":"value"}}`)))
+			err := test.mutate(root)
+			if err == nil {
+				t.Fatalf("%s() expected error", test.name)
+			}
+			if err.Error() != "wrong request: attempt to create infinite loop" {
+				t.Errorf("%s() error = %v, expected infinite loop", test.name, err)
+			}
+			value, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(value) != `{"array":[[1]],"object":{"key":"value"}}` {
+				t.Errorf("Marshal() = %s, expected unchanged value", value)
+			}
+		})
+	}
+}
+
 func TestNode_Delete(t *testing.T) {
 	root := Must(Unmarshal([]byte(`{"foo":"bar"}`)))
 	if err := root.Delete(); err != nil {
@@ -1789,3 +2118,861 @@ func TestNode_Set(t *testing.T) {
 		})
 	}
 }
+
+func TestNode_ReplaceAll(t *testing.T) {
+	round := func(node *Node) (*Node, error) {
+		value, err := node.GetNumeric()
+		if err != nil {
+			return nil, err
+		}
+		return NumericNode("", math.Round(value)), nil
+	}
+	tests := []struct {
+		name    string
+		json    string
+		path    string
+		fn      func(node *Node) (*Node, error)
+		result  string
+		wantErr bool
+	}{
+		{
+			name:   "round prices",
+			json:   `{"items":[{"price":1.25},{"price":2.75}],"price":0.4}`,
+			path:   "$..price",
+			fn:     round,
+			result: `{"items":[{"price":1},{"price":3}],"price":0}`,
+		},
+		{
+			name: "in place",
+			json: `[1,2,3]`,
+			path: "$[0,2]",
+			fn: func(node *Node) (*Node, error) {
+				return nil, node.SetNumeric(node.MustNumeric() * 10)
+			},
+			result: `[10,2,30]`,
+		},
+		{
+			name: "nested",
+			json: `{"a":{"a":{"a":1}}}`,
+			path: "$..a",
+			fn: func(node *Node) (*Node, error) {
+				return ArrayNode("", []*Node{node.Clone()}), nil
+			},
+			result: `{"a":[{"a":[{"a":[1]}]}]}`,
+		},
+		{
+			name: "root",
+			json: `{"a":1}`,
+			path: "$",
+			fn: func(node *Node) (*Node, error) {
+				return StringNode("", "replaced"), nil
+			},
+			result: `"replaced"`,
+		},
+		{
+			name:   "nothing found",
+			json:   `{"a":1}`,
+			path:   "$.b",
+			fn:     round,
+			result: `{"a":1}`,
+		},
+		{
+			name:    "fn error",
+			json:    `{"a":"1"}`,
+			path:    "$.a",
+			fn:      round,
+			wantErr: true,
+		},
+		{
+			name:    "path error",
+			json:    `{"a":1}`,
+			path:    "XXX",
+			fn:      round,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			err := root.ReplaceAll(test.path, test.fn)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ReplaceAll() error = %v, wantErr %v", err, test.wantErr)
+				return
+			}
+			if test.wantErr {
+				return
+			}
+			if value, err := MarshalWithOptions(root, SortKeys()); err != nil {
+				t.Errorf("Marshal() unexpected error: %s", err)
+			} else if string(value) != test.result {
+				t.Errorf("ReplaceAll() result = %s, expected %s", value, test.result)
+			}
+		})
+	}
+}
+
+func TestNode_PruneEmpty(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		nulls    bool
+		expected string
+		err      string
+	}{
+		{name: "empty", json: `{}`, expected: `{}`},
+		{name: "nothing to prune", json: `{"a":[1,{"b":null}]}`, expected: `{"a":[1,{"b":null}]}`},
+		{name: "members", json: `{"a":{},"b":[],"c":1}`, expected: `{"c":1}`},
+		{name: "elements", json: `[[],1,{},2,[]]`, expected: `[1,2]`},
+		{name: "bottom-up", json: `{"a":{"b":[{},[[]]]},"c":[{"d":{}},3]}`, expected: `{"c":[3]}`},
+		{name: "root becomes empty", json: `[[{}],{"a":[]}]`, expected: `[]`},
+		{name: "nulls kept", json: `[null,{"a":null}]`, expected: `[null,{"a":null}]`},
+		{name: "nulls", json: `[null,{"a":null},{"b":[null]},1]`, nulls: true, expected: `[1]`},
+		{name: "scalar", json: `1`, err: "wrong type of Node"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			var options []PruneOption
+			if test.nulls {
+				options = append(options, PruneNulls())
+			}
+			err := root.PruneEmpty(options...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("PruneEmpty() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PruneEmpty() unexpected error: %s", err)
+			}
+			result, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("PruneEmpty() = %s, expected %s", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_EnsureArray(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected string
+	}{
+		{json: `[]`, expected: `[]`},
+		{json: `[1,{"a":2}]`, expected: `[1,{"a":2}]`},
+		{json: `{"a":2}`, expected: `[{"a":2}]`},
+		{json: `"foo"`, expected: `["foo"]`},
+		{json: `null`, expected: `[null]`},
+	}
+	for _, test := range tests {
+		t.Run(test.json, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result := root.EnsureArray()
+			if value, err := Marshal(result); err != nil || string(value) != test.expected {
+				t.Errorf("EnsureArray() = %s, expected %s", value, test.expected)
+			}
+			if root.IsArray() != (result == root) {
+				t.Errorf("EnsureArray() returned wrong node")
+			}
+			if !root.IsArray() {
+				if err := result.MustIndex(0).SetNull(); err != nil {
+					t.Fatalf("SetNull() unexpected error: %s", err)
+				}
+				if root.String() != test.json || root.Parent() != nil {
+					t.Errorf("source was changed: %s", root)
+				}
+			}
+		})
+	}
+	var node *Node
+	if node.EnsureArray() != nil {
+		t.Errorf("EnsureArray() expected nil")
+	}
+}
+
+func TestNode_SetRaw(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		path     string
+		raw      string
+		expected string
+		err      string
+	}{
+		{name: "member", json: `{"a":{"b":1},"c":2}`, path: "$.a", raw: `[1, {"d": null}]`, expected: `{"a":[1,{"d":null}],"c":2}`},
+		{name: "element", json: `[1,2,3]`, path: "$[1]", raw: `"x"`, expected: `[1,"x",3]`},
+		{name: "root", json: `{"a":1}`, path: "$", raw: ` [true] `, expected: `[true]`},
+		{name: "filter", json: `[{"id":1},{"id":2}]`, path: "$[?(@.id == 2)]", raw: `{"id":3}`, expected: `[{"id":1},{"id":3}]`},
+		{name: "pointer", json: `{"a":{"b":1},"c":2}`, path: "/a/b", raw: `[2]`, expected: `{"a":{"b":[2]},"c":2}`},
+		{name: "pointer root", json: `{"a":1}`, path: "", raw: `null`, expected: `null`},
+		{name: "missing", json: `{"a":1}`, path: "$.b", raw: `1`, err: "wrong request: path '$.b' found 0 nodes, expected exactly one"},
+		{name: "many", json: `[1,2]`, path: "$[*]", raw: `1`, err: "wrong request: path '$[*]' found 2 nodes, expected exactly one"},
+		{name: "invalid path", json: `{}`, path: "$[", raw: `1`, err: "unexpected end of file"},
+		{name: "invalid raw", json: `{"a":1}`, path: "$.a", raw: `{"b":}`, err: "wrong symbol '}' at 5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			err := root.SetRaw(test.path, []byte(test.raw))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("SetRaw() error = %v, expected %s", err, test.err)
+				}
+				if root.String() != test.json {
+					t.Errorf("SetRaw() changed the node on error: %s", root)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetRaw() unexpected error: %s", err)
+			}
+			result, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("SetRaw() = %s, expected %s", result, test.expected)
+			}
+			_ = root.walk(func(node *Node) error {
+				for _, child := range node.Inheritors() {
+					if child.Parent() != node {
+						t.Errorf("SetRaw() wrong parent of %s", child.Path())
+					}
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestNode_PatchMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		updates  map[string]interface{}
+		expected string
+		err      string
+	}{
+		{
+			name:     "replace and add",
+			json:     `{"a":1,"b":{"c":2},"d":"e"}`,
+			updates:  map[string]interface{}{"a": "x", "b": nil, "f": []interface{}{1, true}, "g": map[string]interface{}{"h": 1.5}},
+			expected: `{"a":"x","b":null,"d":"e","f":[1,true],"g":{"h":1.5}}`,
+		},
+		{
+			name:     "node",
+			json:     `{"a":1}`,
+			updates:  map[string]interface{}{"a": NumericNode("", 2), "b": NewArray().Append("c")},
+			expected: `{"a":2,"b":["c"]}`,
+		},
+		{name: "empty", json: `{"a":1}`, updates: nil, expected: `{"a":1}`},
+		{name: "array", json: `[1]`, updates: map[string]interface{}{"a": 1}, err: "wrong type of Node"},
+		{
+			name:    "unsupported type",
+			json:    `{"a":1}`,
+			updates: map[string]interface{}{"a": 2, "b": struct{}{}},
+			err:     "unsupported type was given: 'struct {}'",
+		},
+		{
+			name:    "invalid key",
+			json:    `{"a":1}`,
+			updates: map[string]interface{}{"a": 2, "\x00": 1},
+			err:     `wrong request: invalid key "\x00"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			err := root.PatchMap(test.updates)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("PatchMap() error = %v, expected %s", err, test.err)
+				}
+				if root.String() != test.json || root.IsDirty() {
+					t.Errorf("PatchMap() changed the node on error: %s", root)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchMap() unexpected error: %s", err)
+			}
+			result, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("PatchMap() = %s, expected %s", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_PatchMap_loop(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":1}}`)))
+	child := root.MustKey("a")
+	if err := child.PatchMap(map[string]interface{}{"b": 2, "c": root}); err == nil {
+		t.Errorf("PatchMap() expected error")
+	}
+	if value := child.MustKey("b").MustNumeric(); value != 1 {
+		t.Errorf("PatchMap() changed the node on error: %v", value)
+	}
+}
+
+func TestNode_PatchMap_nodes(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"z":0,"a":1,"b":{"c":2}}`)))
+	other := Must(Unmarshal([]byte(`{"x":[3]}`)))
+	value := other.MustKey("x")
+	frozen := Must(Unmarshal([]byte(`{"y":4}`)))
+	frozen.Freeze()
+	err := root.PatchMap(map[string]interface{}{"a": value, "c": value, "d": frozen, "e": root.MustKey("b")})
+	if err != nil {
+		t.Fatalf("PatchMap() unexpected error: %s", err)
+	}
+	result, err := MarshalWithOptions(root, SortKeys())
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	if expected := `{"a":[3],"b":{"c":2},"c":[3],"d":{"y":4},"e":{"c":2},"z":0}`; string(result) != expected {
+		t.Errorf("PatchMap() = %s, expected %s", result, expected)
+	}
+	if value.Parent() != other || other.String() != `{"x":[3]}` {
+		t.Errorf("PatchMap() moved the value: %s", other)
+	}
+	if root.MustKey("a") == root.MustKey("c") || root.MustKey("d").IsFrozen() {
+		t.Errorf("PatchMap() didn't copy the values")
+	}
+}
+
+func TestNode_PatchMap_frozen(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"z":0,"a":1}`)))
+	root.MustKey("z").Freeze()
+	if err := root.PatchMap(map[string]interface{}{"z": 5, "a": 5}); err == nil || err.Error() != "wrong request: node is frozen" {
+		t.Errorf("PatchMap() error = %v", err)
+	}
+	if root.String() != `{"z":0,"a":1}` || root.IsDirty() {
+		t.Errorf("PatchMap() changed the node on error: %s", root)
+	}
+	root.Freeze()
+	if err := root.PatchMap(map[string]interface{}{"b": 1}); err == nil {
+		t.Errorf("PatchMap() expected error for the frozen node")
+	}
+}
+
+func TestNode_Move(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		from     string
+		to       string
+		options  []MoveOption
+		expected string
+		err      string
+	}{
+		{name: "member", json: `{"a":{"b":1},"c":{}}`, from: "$.a.b", to: "$.c.d", expected: `{"a":{},"c":{"d":1}}`},
+		{name: "replace", json: `{"a":1,"b":2}`, from: "$.a", to: "$.b", expected: `{"b":1}`},
+		{name: "rename", json: `{"a":[1]}`, from: "$.a", to: "$['b c']", expected: `{"b c":[1]}`},
+		{name: "same place", json: `{"a":1}`, from: "$.a", to: "$.a", expected: `{"a":1}`},
+		{name: "insert", json: `{"a":[1,2,3],"b":0}`, from: "$.b", to: "$.a[1]", expected: `{"a":[1,0,2,3]}`},
+		{name: "append", json: `{"a":[1,2],"b":0}`, from: "$.b", to: "$.a[-]", expected: `{"a":[1,2,0]}`},
+		{name: "append by index", json: `{"a":[1,2],"b":0}`, from: "$.b", to: "$.a[2]", expected: `{"a":[1,2,0]}`},
+		{name: "from array", json: `{"a":[1,2,3],"b":[]}`, from: "$.a[0]", to: "$.b[0]", expected: `{"a":[2,3],"b":[1]}`},
+		{name: "forward", json: `[0,1,2,3]`, from: "$[0]", to: "$[2]", expected: `[1,2,0,3]`},
+		{name: "backward", json: `[0,1,2,3]`, from: "$[3]", to: "$[0]", expected: `[3,0,1,2]`},
+		{name: "to end", json: `[0,1,2]`, from: "$[0]", to: "$[2]", expected: `[1,2,0]`},
+		{name: "nested array", json: `[[1],{"a":2}]`, from: "$[1].a", to: "$[0][0]", expected: `[[2,1],{}]`},
+		{name: "filter", json: `[{"id":1},{"id":2}]`, from: "$[?(@.id == 2)]", to: "$[0]", expected: `[{"id":2},{"id":1}]`},
+		{
+			name:     "create parents",
+			json:     `{"a":1,"b":{}}`,
+			from:     "$.a",
+			to:       "$.b.c.d.e",
+			options:  []MoveOption{CreateParents()},
+			expected: `{"b":{"c":{"d":{"e":1}}}}`,
+		},
+		{name: "missing parents", json: `{"a":1,"b":{}}`, from: "$.a", to: "$.b.c.d", err: "wrong request: destination path '$.b.c.d' not found"},
+		{
+			name:    "missing index",
+			json:    `{"a":1,"b":[]}`,
+			from:    "$.a",
+			to:      "$.b[0].c",
+			options: []MoveOption{CreateParents()},
+			err:     "wrong request: destination path '$.b[0].c' not found",
+		},
+		{name: "missing source", json: `{"a":1}`, from: "$.b", to: "$.c", err: "wrong request: path '$.b' found 0 nodes, expected exactly one"},
+		{name: "root", json: `{"a":1}`, from: "$", to: "$.a", err: "wrong request: attempt to move the root node"},
+		{name: "into itself", json: `{"a":{"b":{}}}`, from: "$.a", to: "$.a.b.c", err: "wrong request: attempt to create infinite loop"},
+		{
+			name:    "into itself with parents",
+			json:    `{"a":{"b":{}}}`,
+			from:    "$.a",
+			to:      "$.a.b.c.d",
+			options: []MoveOption{CreateParents()},
+			err:     "wrong request: attempt to create infinite loop",
+		},
+		{name: "wrong index", json: `{"a":[1],"b":0}`, from: "$.b", to: "$.a[2]", err: "wrong request: wrong destination index '2'"},
+		{name: "wrong index of the same array", json: `[0,1]`, from: "$[0]", to: "$[2]", err: "wrong request: wrong destination index '2'"},
+		{name: "negative index", json: `{"a":[1],"b":0}`, from: "$.b", to: "$.a[-1]", err: "wrong request: wrong destination index '-1'"},
+		{name: "scalar", json: `{"a":1,"b":0}`, from: "$.b", to: "$.a.c", err: "wrong type of Node"},
+		{name: "wildcard", json: `{"a":1,"b":{}}`, from: "$.a", to: "$.*.c", err: "wrong request: wrong destination path '$.*.c': step '*' is not a key or an index"},
+		{name: "slice", json: `{"a":1,"b":[]}`, from: "$.a", to: "$.b[0:1]", err: "wrong request: wrong destination path '$.b[0:1]': step '0:1' is not a key or an index"},
+		{name: "destination root", json: `{"a":1}`, from: "$.a", to: "$", err: "wrong request: wrong destination path '$'"},
+		{name: "invalid key", json: `{"a":1}`, from: "$.a", to: "$['\\u0000']", err: "wrong request: invalid key \"\\x00\""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			err := root.Move(test.from, test.to, test.options...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Move() error = %v, expected %s", err, test.err)
+				}
+				if root.String() != test.json {
+					t.Errorf("Move() changed the node on error: %s", root)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Move() unexpected error: %s", err)
+			}
+			result, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("Move() = %s, expected %s", result, test.expected)
+			}
+			_ = root.walk(func(node *Node) error {
+				for i, child := range node.Inheritors() {
+					if child.Parent() != node {
+						t.Errorf("Move() wrong parent of %s", child.Path())
+					}
+					if node.IsArray() && child.Index() != i {
+						t.Errorf("Move() wrong index of %s", child.Path())
+					}
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestNode_Freeze(t *testing.T) {
+	data := `{"a":{"b":[1,2]},"c":"d","e":null}`
+	tests := []struct {
+		name   string
+		path   string
+		mutate func(root, node *Node) error
+	}{
+		{name: "AppendObject", path: "$", mutate: func(_, node *Node) error { return node.AppendObject("x", NullNode("")) }},
+		{name: "AppendObject nested", path: "$.a", mutate: func(_, node *Node) error { return node.AppendObject("b", NullNode("")) }},
+		{name: "AppendArray", path: "$.a.b", mutate: func(_, node *Node) error { return node.AppendArray(NullNode("")) }},
+		{name: "SetNumeric", path: "$.a.b[0]", mutate: func(_, node *Node) error { return node.SetNumeric(3) }},
+		{name: "SetString", path: "$.c", mutate: func(_, node *Node) error { return node.SetString("x") }},
+		{name: "SetNull", path: "$.a", mutate: func(_, node *Node) error { return node.SetNull() }},
+		{name: "SetNode", path: "$.e", mutate: func(_, node *Node) error { return node.SetNode(BoolNode("", true)) }},
+		{name: "Set", path: "$.e", mutate: func(_, node *Node) error { return node.Set(1) }},
+		{name: "MapNumeric", path: "$.a.b[1]", mutate: func(_, node *Node) error { return node.MapNumeric(math.Abs) }},
+		{name: "ForceObject", path: "$.e", mutate: func(_, node *Node) error { return node.ForceObject() }},
+		{name: "DeleteKey", path: "$", mutate: func(_, node *Node) error { return node.DeleteKey("c") }},
+		{name: "DeleteIndex", path: "$.a.b", mutate: func(_, node *Node) error { return node.DeleteIndex(0) }},
+		{name: "PopKey", path: "$.a", mutate: func(_, node *Node) error { _, err := node.PopKey("b"); return err }},
+		{name: "PopIndex", path: "$.a.b", mutate: func(_, node *Node) error { _, err := node.PopIndex(1); return err }},
+		{name: "Delete", path: "$.c", mutate: func(_, node *Node) error { return node.Delete() }},
+		{name: "PatchMap", path: "$", mutate: func(_, node *Node) error { return node.PatchMap(map[string]interface{}{"c": 1}) }},
+		{name: "PruneEmpty", path: "$", mutate: func(_, node *Node) error { return node.PruneEmpty(PruneNulls()) }},
+		{name: "Move", path: "$", mutate: func(_, node *Node) error { return node.Move("$.c", "$.a.c") }},
+		{name: "SetRaw", path: "$", mutate: func(_, node *Node) error { return node.SetRaw("$.c", []byte(`1`)) }},
+		{name: "Compact", path: "$.a", mutate: func(_, node *Node) error { return node.Compact() }},
+		{name: "CompactSource", path: "$.a", mutate: func(_, node *Node) error { return node.CompactSource() }},
+		{
+			name: "append to other tree",
+			path: "$.a",
+			mutate: func(_, node *Node) error {
+				return Must(Unmarshal([]byte(`{}`))).AppendObject("a", node)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(data)))
+			root.Freeze()
+			node, err := root.single(test.path)
+			if err != nil {
+				t.Fatalf("single() unexpected error: %s", err)
+			}
+			err = test.mutate(root, node)
+			if !errors.Is(err, ErrFrozen) {
+				t.Errorf("error = %v, expected ErrFrozen", err)
+			}
+			if err != nil && err.Error() != "wrong request: node is frozen" {
+				t.Errorf("error = %s", err)
+			}
+			if root.String() != data || root.IsDirty() {
+				t.Errorf("frozen node was changed: %s", root)
+			}
+		})
+	}
+}
+
+func TestNode_Freeze_subtree(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":1},"c":[1]}`)))
+	a := root.MustKey("a")
+	a.Freeze()
+	if !a.IsFrozen() || !a.MustKey("b").IsFrozen() || root.IsFrozen() || root.MustKey("c").IsFrozen() {
+		t.Fatalf("Freeze() wrong frozen nodes")
+	}
+	if err := root.MustKey("c").AppendArray(NumericNode("", 2)); err != nil {
+		t.Errorf("AppendArray() unexpected error: %s", err)
+	}
+	for name, err := range map[string]error{
+		"DeleteKey":    root.DeleteKey("a"),
+		"AppendObject": root.AppendObject("a", NullNode("")),
+		"SetObject":    root.SetObject(map[string]*Node{}),
+		"AppendArray":  root.MustKey("c").AppendArray(a),
+	} {
+		if !errors.Is(err, ErrFrozen) {
+			t.Errorf("%s() error = %v, expected ErrFrozen", name, err)
+		}
+	}
+	if value, _ := MarshalWithOptions(root, SortKeys()); string(value) != `{"a":{"b":1},"c":[1,2]}` {
+		t.Errorf("root = %s", value)
+	}
+
+	clone := a.Clone()
+	if clone.IsFrozen() || clone.MustKey("b").IsFrozen() {
+		t.Errorf("Clone() is frozen")
+	}
+	if err := clone.AppendObject("c", NullNode("")); err != nil {
+		t.Errorf("AppendObject() unexpected error: %s", err)
+	}
+	if err := root.AppendObject("d", clone); err != nil {
+		t.Errorf("AppendObject() unexpected error: %s", err)
+	}
+	if err := root.MustKey("d").SetNode(a); err != nil {
+		t.Errorf("SetNode() unexpected error: %s", err)
+	}
+	if root.MustKey("d").IsFrozen() {
+		t.Errorf("SetNode() copied the frozen flag")
+	}
+	var node *Node
+	node.Freeze()
+	if node.IsFrozen() {
+		t.Errorf("IsFrozen() = true for nil")
+	}
+}
+
+func TestNode_Freeze_concurrent(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	root.Freeze()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				result, err := root.JSONPath("$..book[?(@.price > 10)].title")
+				if err != nil || len(result) != 2 {
+					t.Errorf("JSONPath() = %v, %v", result, err)
+					return
+				}
+				if _, err = result[0].GetString(); err != nil {
+					t.Errorf("GetString() unexpected error: %s", err)
+				}
+				if _, err = Marshal(root); err != nil {
+					t.Errorf("Marshal() unexpected error: %s", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNode_SetBigInt(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"id":1,"price":2}`)))
+	id, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if err := root.MustKey("id").SetBigInt(id); err != nil {
+		t.Fatalf("SetBigInt() unexpected error: %s", err)
+	}
+	id.SetInt64(0) // the value is copied
+	price, _, _ := big.ParseFloat("1234567890.123456789012345", 10, 128, big.ToNearestEven)
+	if err := root.MustKey("price").SetBigFloat(price); err != nil {
+		t.Fatalf("SetBigFloat() unexpected error: %s", err)
+	}
+	result, err := MarshalWithOptions(root, SortKeys())
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %s", err)
+	}
+	exact, _ := price.Rat(nil)
+	expected := `{"id":123456789012345678901234567890,"price":` + decimalString(exact) + `}`
+	if string(result) != expected {
+		t.Errorf("Marshal() = %s, expected %s", result, expected)
+	}
+	if value, _ := Must(Unmarshal(result)).MustKey("price").BigFloat(); value.SetPrec(128).Cmp(price) != 0 {
+		t.Errorf("BigFloat() = %s, expected %s", value.Text('g', 40), price.Text('g', 40))
+	}
+	if value, ok := root.MustKey("id").BigInt(); !ok || value.String() != "123456789012345678901234567890" {
+		t.Errorf("BigInt() = %v, %v", value, ok)
+	}
+	if value := root.MustKey("id").MustNumeric(); value != 1.2345678901234568e29 {
+		t.Errorf("MustNumeric() = %v", value)
+	}
+	if err = root.MustKey("id").SetNumeric(1); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if value, ok := root.MustKey("id").BigInt(); !ok || value.String() != "1" {
+		t.Errorf("BigInt() = %v, %v after SetNumeric", value, ok)
+	}
+	if err = root.MustKey("id").SetBigFloat(new(big.Float).SetInf(false)); err == nil {
+		t.Errorf("SetBigFloat() expected error for Inf")
+	}
+	if err = root.MustKey("id").SetBigInt(nil); err == nil {
+		t.Errorf("SetBigInt() expected error for nil")
+	}
+}
+
+func TestNode_NumerifyStrings(t *testing.T) {
+	data := `{"Amount":"300","RankStart":5,"list":["-1.5e3","1.","01","abc"," 2",""],"big":"12345678901234567890","sub":{"Amount":"7","id":"0"}}`
+	tests := []struct {
+		name     string
+		paths    []string
+		expected string
+		count    int
+		err      bool
+	}{
+		{
+			name:     "all",
+			expected: `{"Amount":300,"RankStart":5,"big":12345678901234567890,"list":[-1.5e3,"1.","01","abc"," 2",""],"sub":{"Amount":7,"id":0}}`,
+			count:    5,
+		},
+		{
+			name:     "paths",
+			paths:    []string{"$..Amount", "$.sub"},
+			expected: `{"Amount":300,"RankStart":5,"big":"12345678901234567890","list":["-1.5e3","1.","01","abc"," 2",""],"sub":{"Amount":7,"id":0}}`,
+			count:    3,
+		},
+		{
+			name:  "not found",
+			paths: []string{"$.none"},
+		},
+		{
+			name:  "wrong path",
+			paths: []string{"$["},
+			err:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(data)))
+			count, err := root.NumerifyStrings(test.paths...)
+			if test.err {
+				if err == nil {
+					t.Errorf("NumerifyStrings() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NumerifyStrings() unexpected error: %s", err)
+			}
+			if count != test.count {
+				t.Errorf("NumerifyStrings() = %d, expected %d", count, test.count)
+			}
+			value, err := MarshalWithOptions(root, SortKeys())
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if test.expected != "" && string(value) != test.expected {
+				t.Errorf("Marshal() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+
+	root := Must(Unmarshal([]byte(`["1"]`)))
+	root.MustIndex(0).Freeze()
+	if _, err := root.NumerifyStrings(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("NumerifyStrings() error = %v, expected ErrFrozen", err)
+	}
+	if number, err := Must(Unmarshal([]byte(`"2"`))).NumerifyStrings(); err != nil || number != 1 {
+		t.Errorf("NumerifyStrings() = %d, %v, expected 1 for the root", number, err)
+	}
+}
+
+func TestNode_RepairIndices(t *testing.T) {
+	gapped := func() *Node {
+		node := Must(Unmarshal([]byte(`["x","a","y","b"]`)))
+		delete(node.children, "0")
+		delete(node.children, "2")
+		node.children["10"] = StringNode("10", "c")
+		node.mark()
+		return node
+	}
+	tests := []struct {
+		name     string
+		modify   func(node *Node) error
+		expected string
+	}{
+		{name: "repair", modify: func(node *Node) error { return node.RepairIndices() }, expected: `["a","b","c"]`},
+		{name: "append", modify: func(node *Node) error { return node.AppendArray(NullNode("")) }, expected: `["a","b","c",null]`},
+		{
+			name: "insert",
+			modify: func(node *Node) error {
+				root := ObjectNode("", map[string]*Node{"x": StringNode("", "d")})
+				if err := root.AppendObject("list", node); err != nil {
+					return err
+				}
+				return root.Move("$.x", "$.list[1]")
+			},
+			expected: `["a","d","b","c"]`,
+		},
+		{name: "delete", modify: func(node *Node) error { return node.DeleteIndex(1) }, expected: `["b","c"]`},
+		{name: "compact", modify: func(node *Node) error { return node.Compact() }, expected: `["a","b","c"]`},
+		{name: "normalize", modify: func(node *Node) error { return node.Normalize() }, expected: `["a","b","c"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := gapped()
+			if _, err := Marshal(node); err == nil {
+				t.Fatalf("Marshal() expected error for the gapped array")
+			}
+			if err := test.modify(node); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			value, err := Marshal(node)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %s", err)
+			}
+			if string(value) != test.expected {
+				t.Errorf("Marshal() = %s, expected %s", value, test.expected)
+			}
+			for i, child := range node.Inheritors() {
+				if child.Index() != i || child.key != nil || child.parent != node {
+					t.Errorf("wrong reference of the element %d: %s", i, child.Path())
+				}
+			}
+		})
+	}
+
+	for name, method := range map[string]func(node *Node) error{"Compact": (*Node).Compact, "Normalize": (*Node).Normalize} {
+		node := gapped()
+		node.children["10"].Freeze()
+		if err := method(node); !errors.Is(err, ErrFrozen) {
+			t.Errorf("%s() error = %v, expected ErrFrozen", name, err)
+		}
+		if _, ok := node.children["10"]; !ok || node.children["1"].Index() != 1 {
+			t.Errorf("%s() has renumbered the elements of the frozen tree", name)
+		}
+	}
+
+	root := Must(Unmarshal([]byte(`{"b":[1,{"c":[]}]}`)))
+	nested := root.MustKey("b").MustIndex(1).MustKey("c")
+	nested.children["2"] = NumericNode("", 3)
+	if err := root.MustKey("b").RepairIndices(); err != nil || root.IsDirty() {
+		t.Errorf("RepairIndices() = %v, expected no changes for the contiguous array", err)
+	}
+	if err := root.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %s", err)
+	}
+	if value := root.String(); value != `{"b":[1,{"c":[3]}]}` {
+		t.Errorf("Compact() = %s", value)
+	}
+
+	frozen := gapped()
+	frozen.Freeze()
+	if err := frozen.RepairIndices(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("RepairIndices() error = %v, expected ErrFrozen", err)
+	}
+	if err := frozen.Compact(); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Compact() error = %v, expected ErrFrozen", err)
+	}
+	if err := ObjectNode("", nil).RepairIndices(); !errors.Is(err, ErrWrongType) {
+		t.Errorf("RepairIndices() error = %v, expected ErrWrongType", err)
+	}
+}
+
+func TestNode_MergeArrayByKey(t *testing.T) {
+	payout := `{"Tiers":[{"Tier":1,"Min":0,"Rate":0.1},{"Tier":2,"Min":1000,"Rate":0.15},{"Name":"bonus","Rate":0.01}]}`
+	tests := []struct {
+		name     string
+		other    string
+		key      string
+		expected string
+	}{
+		{
+			name:     "update and append",
+			other:    `[{"Tier":2,"Rate":0.2},{"Tier":3,"Min":5000,"Rate":0.25}]`,
+			key:      "Tier",
+			expected: `[{"Min":0,"Rate":0.1,"Tier":1},{"Min":1000,"Rate":0.2,"Tier":2},{"Name":"bonus","Rate":0.01},{"Min":5000,"Rate":0.25,"Tier":3}]`,
+		},
+		{
+			name:     "canonical key",
+			other:    `[{"Tier":1.0,"Max":999},{"Tier":"2","Min":1}]`,
+			key:      "Tier",
+			expected: `[{"Max":999,"Min":0,"Rate":0.1,"Tier":1.0},{"Min":1000,"Rate":0.15,"Tier":2},{"Name":"bonus","Rate":0.01},{"Min":1,"Tier":"2"}]`,
+		},
+		{
+			name:     "without key",
+			other:    `[{"Rate":0.5},{"Name":"bonus","Rate":0.02},null]`,
+			key:      "Tier",
+			expected: `[{"Min":0,"Rate":0.1,"Tier":1},{"Min":1000,"Rate":0.15,"Tier":2},{"Name":"bonus","Rate":0.01},{"Rate":0.5},{"Name":"bonus","Rate":0.02},null]`,
+		},
+		{
+			name:     "repeated in other",
+			other:    `[{"Tier":4,"Min":1},{"Tier":4,"Rate":0.3},{"Tier":1,"Min":10},{"Tier":1,"Min":20}]`,
+			key:      "Tier",
+			expected: `[{"Min":20,"Rate":0.1,"Tier":1},{"Min":1000,"Rate":0.15,"Tier":2},{"Name":"bonus","Rate":0.01},{"Min":1,"Rate":0.3,"Tier":4}]`,
+		},
+		{
+			name:     "other key",
+			other:    `[{"Name":"bonus","Rate":0.05}]`,
+			key:      "Name",
+			expected: `[{"Min":0,"Rate":0.1,"Tier":1},{"Min":1000,"Rate":0.15,"Tier":2},{"Name":"bonus","Rate":0.05}]`,
+		},
+		{
+			name:     "empty",
+			other:    `[]`,
+			key:      "Tier",
+			expected: `[{"Min":0,"Rate":0.1,"Tier":1},{"Min":1000,"Rate":0.15,"Tier":2},{"Name":"bonus","Rate":0.01}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(payout)))
+			other := Must(Unmarshal([]byte(test.other)))
+			if err := root.MustKey("Tiers").MergeArrayByKey(other, test.key); err != nil {
+				t.Fatalf("MergeArrayByKey() unexpected error: %s", err)
+			}
+			if value, err := MarshalWithOptions(root.MustKey("Tiers"), SortKeys()); err != nil || string(value) != test.expected {
+				t.Errorf("MergeArrayByKey() = %s, expected %s", value, test.expected)
+			}
+			if other.String() != test.other {
+				t.Errorf("other was changed: %s", other)
+			}
+			if err := root.Validate(); err != nil {
+				t.Errorf("Validate() unexpected error: %s", err)
+			}
+		})
+	}
+
+	root := Must(Unmarshal([]byte(payout)))
+	tiers := root.MustKey("Tiers")
+	tiers.MustIndex(1).MustKey("Rate").Freeze()
+	err := tiers.MergeArrayByKey(Must(Unmarshal([]byte(`[{"Tier":3},{"Tier":2,"Rate":0.2}]`))), "Tier")
+	if !errors.Is(err, ErrFrozen) {
+		t.Errorf("MergeArrayByKey() error = %v, expected %v", err, ErrFrozen)
+	}
+	if root.IsDirty() {
+		t.Errorf("MergeArrayByKey() changed the node on error")
+	}
+	if err = tiers.MergeArrayByKey(root, "Tier"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("MergeArrayByKey() error = %v, expected %v", err, ErrWrongType)
+	}
+	if err = root.MergeArrayByKey(tiers, "Tier"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("MergeArrayByKey() error = %v, expected %v", err, ErrWrongType)
+	}
+	if err = (*Node)(nil).MergeArrayByKey(tiers, "Tier"); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("MergeArrayByKey() error = %v, expected %v", err, ErrNotParsed)
+	}
+}
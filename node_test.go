@@ -3,8 +3,11 @@ package ajson
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"math"
+	"math/big"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -122,6 +125,569 @@ func TestNode_getValue(t *testing.T) {
 	}
 }
 
+func TestNode_Dig(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	tests := []struct {
+		name     string
+		node     *Node
+		keys     []string
+		expected string
+		err      error
+	}{
+		{name: "self", node: root, keys: nil, expected: "$"},
+		{name: "object", node: root, keys: []string{"store", "bicycle", "color"}, expected: "$['store']['bicycle']['color']"},
+		{name: "array", node: root, keys: []string{"store", "book", "1", "title"}, expected: "$['store']['book'][1]['title']"},
+		{name: "negative index", node: root, keys: []string{"store", "book", "-1"}, expected: "$['store']['book'][3]"},
+		{name: "relative", node: root.MustKey("store"), keys: []string{"book", "0"}, expected: "$['store']['book'][0]"},
+		{name: "missing key", node: root, keys: []string{"store", "car"}, err: ErrKeyNotFound},
+		{name: "missing index", node: root, keys: []string{"store", "book", "10"}, err: ErrIndexOutOfRange},
+		{name: "wrong index", node: root, keys: []string{"store", "book", "first"}, err: Error{Type: WrongRequest, Message: "wrong index 'first'"}},
+		{name: "scalar", node: root, keys: []string{"store", "bicycle", "color", "name"}, err: ErrWrongType},
+		{name: "nil", node: nil, keys: []string{"store"}, err: ErrNotParsed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.node.Dig(test.keys...)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("Dig() error = %v, expected %v", err, test.err)
+				}
+				if result != nil {
+					t.Errorf("Dig() result is not nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Dig() unexpected error: %s", err)
+			} else if result.Path() != test.expected {
+				t.Errorf("Dig() = %s, expected %s", result.Path(), test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_Get(t *testing.T) {
+	wrapped := Must(Unmarshal([]byte(`{"data":[{"user":[{"name":["Bob"],"tags":["a"],"roles":["admin","user"],"ids":[[[1]]]}]}]}`)))
+	plain := Must(Unmarshal([]byte(`{"data":{"user":{"name":"Bob","tags":["a"]}},"list":[{"a":1},{"a":2}],"empty":[]}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		keys     []string
+		options  []GetOption
+		expected string
+		err      error
+	}{
+		{name: "plain", node: plain, keys: []string{"data", "user", "name"}, expected: "$['data']['user']['name']"},
+		{name: "plain unwrap", node: plain, keys: []string{"data", "user", "name"}, options: []GetOption{UnwrapSingle()}, expected: "$['data']['user']['name']"},
+		{name: "wrapped", node: wrapped, keys: []string{"data", "user", "name"}, options: []GetOption{UnwrapSingle()}, expected: "$['data'][0]['user'][0]['name'][0]"},
+		{name: "wrapped without option", node: wrapped, keys: []string{"data", "user", "name"}, err: Error{Type: WrongRequest, Message: "wrong index 'user'"}},
+		{name: "result", node: plain, keys: []string{"data", "user", "tags"}, options: []GetOption{UnwrapSingle()}, expected: "$['data']['user']['tags'][0]"},
+		{name: "result without option", node: plain, keys: []string{"data", "user", "tags"}, expected: "$['data']['user']['tags']"},
+		{name: "index", node: plain, keys: []string{"data", "user", "tags", "0"}, options: []GetOption{UnwrapSingle()}, expected: "$['data']['user']['tags'][0]"},
+		{name: "index of wrapped", node: wrapped, keys: []string{"data", "0", "user", "0", "tags", "0"}, options: []GetOption{UnwrapSingle()}, expected: "$['data'][0]['user'][0]['tags'][0]"},
+		{name: "nested", node: wrapped, keys: []string{"data", "user", "ids"}, options: []GetOption{UnwrapSingle()}, expected: "$['data'][0]['user'][0]['ids'][0][0][0]"},
+		{name: "multi-element", node: wrapped, keys: []string{"data", "user", "roles"}, options: []GetOption{UnwrapSingle()}, expected: "$['data'][0]['user'][0]['roles']"},
+		{name: "multi-element key", node: plain, keys: []string{"list", "a"}, options: []GetOption{UnwrapSingle()}, err: Error{Type: WrongRequest, Message: "wrong index 'a'"}},
+		{name: "multi-element index", node: plain, keys: []string{"list", "1", "a"}, options: []GetOption{UnwrapSingle()}, expected: "$['list'][1]['a']"},
+		{name: "empty", node: plain, keys: []string{"empty"}, options: []GetOption{UnwrapSingle()}, expected: "$['empty']"},
+		{name: "empty key", node: plain, keys: []string{"empty", "a"}, options: []GetOption{UnwrapSingle()}, err: Error{Type: WrongRequest, Message: "wrong index 'a'"}},
+		{name: "missing", node: wrapped, keys: []string{"data", "group"}, options: []GetOption{UnwrapSingle()}, err: ErrKeyNotFound},
+		{name: "nil", node: nil, keys: []string{"data"}, options: []GetOption{UnwrapSingle()}, err: ErrNotParsed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.node.Get(test.keys, test.options...)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("Get() error = %v, expected %v", err, test.err)
+				}
+				if result != nil {
+					t.Errorf("Get() result is not nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Get() unexpected error: %s", err)
+			} else if result.Path() != test.expected {
+				t.Errorf("Get() = %s, expected %s", result.Path(), test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_At(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"field1": null, "field3": [{"sub_field": "value"}, 2]}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		parts    []interface{}
+		expected string
+		err      string
+		is       error
+	}{
+		{name: "self", node: root, parts: nil, expected: "$"},
+		{name: "mixed", node: root, parts: []interface{}{"field3", 0, "sub_field"}, expected: "$['field3'][0]['sub_field']"},
+		{name: "negative index", node: root, parts: []interface{}{"field3", -1}, expected: "$['field3'][1]"},
+		{name: "missing key", node: root, parts: []interface{}{"field3", 0, "field"}, err: `wrong request: part #2 ("field"): wrong request: wrong key 'field'`, is: ErrKeyNotFound},
+		{name: "missing index", node: root, parts: []interface{}{"field3", 5}, err: `wrong request: part #1 (5): wrong request: out of index 5`, is: ErrIndexOutOfRange},
+		{name: "key of array", node: root, parts: []interface{}{"field3", "0"}, err: `wrong request: part #1 ("0"): wrong type of Node`, is: ErrWrongType},
+		{name: "index of null", node: root, parts: []interface{}{"field1", 0}, err: `wrong request: part #1 (0): wrong type of Node`, is: ErrWrongType},
+		{name: "unsupported part", node: root, parts: []interface{}{"field3", 1.5}, err: `wrong request: part #1 (1.5): unsupported type was given: 'float64'`},
+		{name: "nil", node: nil, parts: []interface{}{"field3"}, err: "not parsed yet", is: ErrNotParsed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.node.At(test.parts...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("At() error = %v, expected %s", err, test.err)
+				}
+				if test.is != nil && !errors.Is(err, test.is) {
+					t.Errorf("At() error = %v, expected to be %v", err, test.is)
+				}
+				if result != nil {
+					t.Errorf("At() result is not nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("At() unexpected error: %s", err)
+			} else if result.Path() != test.expected {
+				t.Errorf("At() = %s, expected %s", result.Path(), test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_validateBorders(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		breaks func(root *Node)
+		err    string
+	}{
+		{name: "example", json: string(jsonPathTestData)},
+		{name: "scalar", json: ` -1.5e3 `},
+		{name: "empty containers", json: `[{}, [], ""]`},
+		{
+			name: "dirty parent",
+			json: `{"foo": [1, 2], "bar": {"baz": null}}`,
+			breaks: func(root *Node) {
+				_ = root.AppendObject("new", Must(Unmarshal([]byte(`{"other": true}`))))
+			},
+		},
+		{
+			name: "no right border",
+			json: `{"foo":"bar"}`,
+			breaks: func(root *Node) {
+				root.borders[1] = 0
+			},
+			err: "$: wrong request: node is not dirty, but has no right border",
+		},
+		{
+			name: "out of data",
+			json: `{"foo":"bar"}`,
+			breaks: func(root *Node) {
+				root.borders[1] = 100
+			},
+			err: "$: wrong request: node has borders [0 100] out of source data length 13",
+		},
+		{
+			name: "wrong source",
+			json: `{"foo":"bar"}`,
+			breaks: func(root *Node) {
+				root.MustKey("foo").borders[0]--
+			},
+			err: `$['foo']: wrong request: node has borders [6 12] with wrong source: :"bar"`,
+		},
+		{
+			name: "out of parent",
+			json: `[[1], 2]`,
+			breaks: func(root *Node) {
+				root.MustIndex(0).MustIndex(0).borders = [2]int{6, 7}
+			},
+			err: "$[0][0]: wrong request: node has borders [6 7] out of the parent borders [1 4]",
+		},
+		{
+			name: "overlapped",
+			json: `[1, 2]`,
+			breaks: func(root *Node) {
+				root.MustIndex(0).borders, root.MustIndex(1).borders = root.MustIndex(1).borders, root.MustIndex(0).borders
+			},
+			err: "$[1]: wrong request: node has borders [1 2] overlapped with the previous element borders [4 5]",
+		},
+		{
+			name: "different data",
+			json: `[1, 2]`,
+			breaks: func(root *Node) {
+				root.MustIndex(1).data = Must(Unmarshal([]byte(`[1, 2]`))).data
+			},
+			err: "$[1]: wrong request: node has source data different from its parent",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			if test.breaks != nil {
+				test.breaks(root)
+			}
+			err := root.validateBorders()
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("validateBorders() unexpected error: %s", err)
+				}
+			} else if err == nil || err.Error() != test.err {
+				t.Errorf("validateBorders() error = %v, expected %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestNode_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		breaks func(root *Node)
+		err    string
+		is     error
+	}{
+		{name: "example", json: string(jsonPathTestData)},
+		{name: "scalar", json: `"foo"`},
+		{
+			name: "changed",
+			json: `{"foo":[1,2],"bar":{"baz":null}}`,
+			breaks: func(root *Node) {
+				_ = root.AppendObject("new", ArrayNode("", []*Node{NumericNode("", 1), StringNode("", "x")}))
+				_ = root.MustKey("foo").DeleteIndex(0)
+				_ = root.MustKey("bar").MustKey("baz").SetBool(true)
+			},
+		},
+		{
+			name: "wrong type",
+			json: `{"foo":1}`,
+			breaks: func(root *Node) {
+				root.MustKey("foo")._type = 10
+			},
+			err: "$['foo']: wrong request: node has wrong type NodeType(10)",
+		},
+		{
+			name: "scalar children",
+			json: `{"foo":1}`,
+			breaks: func(root *Node) {
+				root.MustKey("foo").children = map[string]*Node{"bar": NullNode("bar")}
+			},
+			err: "$['foo']: wrong request: node is Numeric, but has children",
+		},
+		{
+			name: "nil child",
+			json: `{"foo":1}`,
+			breaks: func(root *Node) {
+				root.children["bar"] = nil
+			},
+			err: "$: wrong request: node has nil child 'bar'",
+		},
+		{
+			name: "wrong parent",
+			json: `{"foo":{"bar":1}}`,
+			breaks: func(root *Node) {
+				root.MustKey("foo").MustKey("bar").parent = root
+			},
+			err: "$['foo']: wrong request: node has child 'bar' with wrong parent",
+		},
+		{
+			name: "wrong index",
+			json: `[1,2,3]`,
+			breaks: func(root *Node) {
+				delete(root.children, "1")
+			},
+			err: "$: wrong request: node has element '2' with wrong index",
+		},
+		{
+			name: "wrong key",
+			json: `{"foo":1}`,
+			breaks: func(root *Node) {
+				root.children["bar"] = root.children["foo"]
+				delete(root.children, "foo")
+			},
+			err: "$: wrong request: node has member 'bar' with wrong key",
+		},
+		{
+			name: "broken borders",
+			json: `{"foo":"bar"}`,
+			breaks: func(root *Node) {
+				root.borders[1] = 0
+			},
+			err: "$: wrong request: node is not dirty, but has no right border",
+		},
+		{
+			name: "missing value",
+			json: `{"foo":"bar"}`,
+			breaks: func(root *Node) {
+				root.MustKey("foo").dirty = true
+			},
+			err: "$['foo']: not parsed yet",
+			is:  ErrNotParsed,
+		},
+		{
+			name: "broken value",
+			json: `{"foo":1e999}`,
+			err:  `$['foo']: strconv.ParseFloat: parsing "1e999": value out of range`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			if test.breaks != nil {
+				test.breaks(root)
+			}
+			err := root.Validate()
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Validate() error = %v, expected %s", err, test.err)
+			}
+			if _, ok := err.(PathError); !ok {
+				t.Errorf("Validate() error = %#v, expected PathError", err)
+			}
+			if test.is != nil && !errors.Is(err, test.is) {
+				t.Errorf("Validate() error = %v, expected to be %v", err, test.is)
+			}
+		})
+	}
+	if err := (*Node)(nil).Validate(); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("Validate() error = %v, expected %v", err, ErrNotParsed)
+	}
+}
+
+func TestNode_Range(t *testing.T) {
+	data := []byte(`{"foo": {"bar": [1, "baz"]}, "fiz": null}`)
+	root := Must(Unmarshal(data))
+	tests := []struct {
+		name     string
+		node     *Node
+		expected string
+		ok       bool
+	}{
+		{name: "root", node: root, expected: string(data), ok: true},
+		{name: "object", node: root.MustKey("foo"), expected: `{"bar": [1, "baz"]}`, ok: true},
+		{name: "array", node: root.MustKey("foo").MustKey("bar"), expected: `[1, "baz"]`, ok: true},
+		{name: "string", node: root.MustKey("foo").MustKey("bar").MustIndex(1), expected: `"baz"`, ok: true},
+		{name: "null", node: root.MustKey("fiz"), expected: `null`, ok: true},
+		{name: "constructed", node: StringNode("", "baz"), ok: false},
+		{name: "nil", node: nil, ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, ok := test.node.Range()
+			if ok != test.ok {
+				t.Fatalf("Range() ok = %v, expected %v", ok, test.ok)
+			}
+			if !ok {
+				if start != 0 || end != 0 {
+					t.Errorf("Range() = [%d:%d], expected [0:0]", start, end)
+				}
+				return
+			}
+			if value := string(data[start:end]); value != test.expected {
+				t.Errorf("Range() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_KeyRange(t *testing.T) {
+	data := []byte(`{"foo": {"b\u0061r" :[1, {"": "baz"}]}, "fiz":null}`)
+	root := Must(UnmarshalWithOptions(data, KeyRanges()))
+	moved := Must(UnmarshalWithOptions(data, KeyRanges()))
+	if err := moved.AppendObject("new", moved.MustKey("fiz")); err != nil {
+		t.Fatal(err)
+	}
+	changed := Must(UnmarshalWithOptions(data, KeyRanges()))
+	if err := changed.MustKey("fiz").SetNumeric(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := changed.MustKey("foo").SetNode(StringNode("", "bar")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		node     *Node
+		expected string
+		ok       bool
+	}{
+		{name: "object", node: root.MustKey("foo"), expected: `"foo"`, ok: true},
+		{name: "escaped", node: root.MustKey("foo").MustKey("bar"), expected: `"b\u0061r"`, ok: true},
+		{name: "empty", node: root.MustKey("foo").MustKey("bar").MustIndex(1).MustKey(""), expected: `""`, ok: true},
+		{name: "null", node: root.MustKey("fiz"), expected: `"fiz"`, ok: true},
+		{name: "changed value", node: changed.MustKey("fiz"), expected: `"fiz"`, ok: true},
+		{name: "replaced node", node: changed.MustKey("foo"), expected: `"foo"`, ok: true},
+		{name: "clone", node: root.MustKey("foo").Clone(), ok: false},
+		{name: "moved", node: moved.MustKey("new"), ok: false},
+		{name: "element", node: root.MustKey("foo").MustKey("bar").MustIndex(0), ok: false},
+		{name: "root", node: root, ok: false},
+		{name: "constructed", node: ObjectNode("", map[string]*Node{"foo": NullNode("")}).MustKey("foo"), ok: false},
+		{name: "without option", node: Must(Unmarshal(data)).MustKey("foo"), ok: false},
+		{name: "duplicate keys", node: Must(UnmarshalWithOptions(data, DuplicateKeys())).MustKey("fiz"), expected: `"fiz"`, ok: true},
+		{name: "nil", node: nil, ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, ok := test.node.KeyRange()
+			if ok != test.ok {
+				t.Fatalf("KeyRange() ok = %v, expected %v", ok, test.ok)
+			}
+			if !ok {
+				if start != 0 || end != 0 {
+					t.Errorf("KeyRange() = [%d:%d], expected [0:0]", start, end)
+				}
+				return
+			}
+			if value := string(data[start:end]); value != test.expected {
+				t.Errorf("KeyRange() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_KeyRange_replace(t *testing.T) {
+	data := []byte(`{"name": "foo", "nested": {"name": "foo"}}`)
+	root := Must(UnmarshalWithOptions(data, KeyRanges()))
+	start, end, ok := root.MustKey("nested").MustKey("name").KeyRange()
+	if !ok {
+		t.Fatalf("KeyRange() is not ok")
+	}
+	result := string(data[:start]) + `"title"` + string(data[end:])
+	expected := `{"name": "foo", "nested": {"title": "foo"}}`
+	if result != expected {
+		t.Errorf("replaced = %s, expected %s", result, expected)
+	}
+	compacted := Must(UnmarshalWithOptions([]byte(`{ "a" : { "b" : 1 } }`), KeyRanges()))
+	if err := compacted.Compact(); err != nil {
+		t.Fatalf("Compact() unexpected error: %s", err)
+	}
+	if start, end, ok = compacted.MustKey("a").MustKey("b").KeyRange(); !ok || compacted.String()[start:end] != `"b"` {
+		t.Errorf("KeyRange() after Compact = [%d:%d], %v", start, end, ok)
+	}
+}
+
+func TestNode_Range_replace(t *testing.T) {
+	data := []byte(`{"name": "foo", "tags": ["foo", "bar"], "nested": {"name": "foo"}}`)
+	root := Must(Unmarshal(data))
+	nodes, err := root.JSONPath(`$.nested.name`)
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf("JSONPath() = %v, %v", nodes, err)
+	}
+	start, end, ok := nodes[0].Range()
+	if !ok {
+		t.Fatalf("Range() is not ok")
+	}
+	result := string(data[:start]) + `"bar"` + string(data[end:])
+	expected := `{"name": "foo", "tags": ["foo", "bar"], "nested": {"name": "bar"}}`
+	if result != expected {
+		t.Errorf("replaced = %s, expected %s", result, expected)
+	}
+
+	if err = nodes[0].SetString("bar"); err != nil {
+		t.Fatalf("SetString() unexpected error: %s", err)
+	}
+	if _, _, ok = nodes[0].Range(); ok {
+		t.Errorf("Range() is ok for the modified node")
+	}
+	if _, _, ok = root.Range(); ok {
+		t.Errorf("Range() is ok for the parent of the modified node")
+	}
+	if _, _, ok = root.MustKey("tags").Range(); !ok {
+		t.Errorf("Range() is not ok for the unmodified node")
+	}
+}
+
+func TestNode_CompactSource(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"big": [1, 2, 3], "part": {"num": 1.50, "str": "foo\u0021", "list": [true, null]}}`)))
+	part := root.MustKey("part")
+	if err := part.Delete(); err != nil {
+		t.Fatalf("Delete() unexpected error: %s", err)
+	}
+	if err := part.CompactSource(); err != nil {
+		t.Fatalf("CompactSource() unexpected error: %s", err)
+	}
+	err := part.WalkPath(func(_ string, _ int, node *Node) error {
+		if node.data != nil || node.Source() != nil {
+			t.Errorf("node %s has the source data", node.Path())
+		}
+		if !node.IsDirty() {
+			t.Errorf("node %s is not dirty", node.Path())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() unexpected error: %s", err)
+	}
+	if value, err := MarshalWithOptions(part, SortKeys()); err != nil {
+		t.Errorf("Marshal() unexpected error: %s", err)
+	} else if string(value) != `{"list":[true,null],"num":1.5,"str":"foo!"}` {
+		t.Errorf("Marshal() = %s", value)
+	}
+	if value := part.MustKey("str").MustString(); value != "foo!" {
+		t.Errorf("MustString() = %s", value)
+	}
+	if value := part.MustKey("list").MustIndex(0).MustBool(); !value {
+		t.Errorf("MustBool() = %v", value)
+	}
+
+	if err := NullNode("").CompactSource(); err != nil {
+		t.Errorf("CompactSource() unexpected error: %s", err)
+	}
+	var node *Node
+	if err := node.CompactSource(); err == nil {
+		t.Errorf("CompactSource() expected error for nil node")
+	}
+}
+
+func TestNode_CompactSource_child(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a": {"b": {"c": [1, 2]}}, "d": 1.0}`)))
+	if err := root.MustKey("a").CompactSource(); err != nil {
+		t.Fatalf("CompactSource() unexpected error: %s", err)
+	}
+	if !root.IsDirty() {
+		t.Errorf("parent is not marked as changed")
+	}
+	if root.MustKey("d").Source() == nil {
+		t.Errorf("source of the sibling is dropped")
+	}
+	if err := root.MustKey("a").MustKey("b").MustKey("c").MustIndex(0).SetNumeric(3); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if value, err := MarshalWithOptions(root, SortKeys()); err != nil {
+		t.Errorf("Marshal() unexpected error: %s", err)
+	} else if string(value) != `{"a":{"b":{"c":[3,2]}},"d":1.0}` {
+		t.Errorf("Marshal() = %s", value)
+	}
+}
+
+func BenchmarkNode_Dig(b *testing.B) {
+	root := Must(Unmarshal(jsonPathTestData))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := root.Dig("store", "book", "3", "title"); err != nil {
+			b.Error()
+		}
+	}
+}
+
+func BenchmarkNode_Dig_JSONPath(b *testing.B) {
+	root := Must(Unmarshal(jsonPathTestData))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := root.JSONPath("$.store.book[3].title"); err != nil {
+			b.Error()
+		}
+	}
+}
+
 func TestNode_Empty(t *testing.T) {
 	root, err := Unmarshal([]byte(`{
         "tag1": [1, 2, 3],
@@ -462,6 +1028,153 @@ func TestNode_MustString(t *testing.T) {
 	}
 }
 
+func TestNode_AsString(t *testing.T) {
+	value, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	exact := NumericNode("", 0)
+	_ = exact.SetBigInt(value)
+	tests := []struct {
+		name     string
+		node     *Node
+		expected string
+		err      error
+	}{
+		{name: "string", node: Must(Unmarshal([]byte(`"foo \"bar\""`))), expected: `foo "bar"`},
+		{name: "empty string", node: StringNode("", ""), expected: ""},
+		{name: "numeric source", node: Must(Unmarshal([]byte(`1.50e2`))), expected: "1.50e2"},
+		{name: "numeric", node: NumericNode("", 1.5), expected: "1.5"},
+		{name: "numeric large", node: NumericNode("", 1e21), expected: "1e+21"},
+		{name: "numeric exact", node: exact, expected: "123456789012345678901234567890"},
+		{name: "true", node: Must(Unmarshal([]byte(`true`))), expected: "true"},
+		{name: "false", node: BoolNode("", false), expected: "false"},
+		{name: "null", node: Must(Unmarshal([]byte(`null`))), expected: ""},
+		{name: "array", node: Must(Unmarshal([]byte(`[1]`))), err: ErrWrongType},
+		{name: "object", node: ObjectNode("", nil), err: ErrWrongType},
+		{name: "nil", node: nil, err: ErrNotParsed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.node.AsString()
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("AsString() error = %v, expected %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AsString() unexpected error: %s", err)
+			}
+			if value != test.expected {
+				t.Errorf("AsString() = %q, expected %q", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_StringBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *Node
+		expected string
+		error    bool
+	}{
+		{name: "parsed", node: Must(Unmarshal([]byte(`"foo bar"`))), expected: "foo bar"},
+		{name: "parsed escaped", node: Must(Unmarshal([]byte(`"one \"encoded\" \u0410"`))), expected: `one "encoded" А`},
+		{name: "created", node: StringNode("", "UTF-8: 😹"), expected: "UTF-8: 😹"},
+		{name: "numeric", node: NumericNode("", 1), error: true},
+		{name: "nil", node: nil, error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.node.StringBytes()
+			if err != nil {
+				if !test.error {
+					t.Errorf("Unexpected error: %s", err.Error())
+				}
+			} else if test.error {
+				t.Errorf("Expected error")
+			} else if string(value) != test.expected {
+				t.Errorf("Wrong value: %q, expected %q", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_StringBytes_noCopy(t *testing.T) {
+	data := []byte(`["foo"]`)
+	root := Must(Unmarshal(data))
+	value, err := root.MustIndex(0).StringBytes()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+	if &value[0] != &data[2] {
+		t.Errorf("Value was copied")
+	}
+}
+
+func TestNode_RuneLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *Node
+		expected int
+		error    bool
+	}{
+		{name: "ascii", node: StringNode("", "foo_bar"), expected: 7},
+		{name: "unicode", node: StringNode("", "UTF-8: 😹"), expected: 8},
+		{name: "parsed", node: Must(Unmarshal([]byte(`"\u0410\u0411 😹"`))), expected: 4},
+		{name: "empty", node: StringNode("", ""), expected: 0},
+		{name: "numeric", node: NumericNode("", 1), error: true},
+		{name: "nil", node: nil, error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.node.RuneLen()
+			if err != nil {
+				if !test.error {
+					t.Errorf("Unexpected error: %s", err.Error())
+				}
+			} else if test.error {
+				t.Errorf("Expected error")
+			} else if value != test.expected {
+				t.Errorf("Wrong length: %d, expected %d", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_Substring(t *testing.T) {
+	tests := []struct {
+		name       string
+		node       *Node
+		start, end int
+		expected   string
+		error      bool
+	}{
+		{name: "ascii", node: StringNode("", "foo_bar"), start: 4, end: 7, expected: "bar"},
+		{name: "unicode", node: StringNode("", "😹 cat 😹"), start: 0, end: 3, expected: "😹 c"},
+		{name: "empty", node: StringNode("", "foo"), start: 1, end: 1, expected: ""},
+		{name: "negative start", node: StringNode("", "foo"), start: -1, end: 1, error: true},
+		{name: "end out of range", node: StringNode("", "😹"), start: 0, end: 2, error: true},
+		{name: "start after end", node: StringNode("", "foo"), start: 2, end: 1, error: true},
+		{name: "numeric", node: NumericNode("", 1), start: 0, end: 0, error: true},
+		{name: "nil", node: nil, start: 0, end: 0, error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.node.Substring(test.start, test.end)
+			if err != nil {
+				if !test.error {
+					t.Errorf("Unexpected error: %s", err.Error())
+				}
+			} else if test.error {
+				t.Errorf("Expected error")
+			} else if value.MustString() != test.expected {
+				t.Errorf("Wrong substring: %q, expected %q", value.MustString(), test.expected)
+			}
+		})
+	}
+}
+
 func TestNode_Index(t *testing.T) {
 	root, err := Unmarshal([]byte(`[1, 2, 3]`))
 	if err != nil {
@@ -734,6 +1447,112 @@ func TestNode_Parent(t *testing.T) {
 	}
 }
 
+func TestNode_Ancestor(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":[{"bar":[1,2]}]}`)))
+	leaf := root.MustKey("foo").MustIndex(0).MustKey("bar").MustIndex(1)
+	tests := []struct {
+		name     string
+		node     *Node
+		pred     func(node *Node) bool
+		expected string
+	}{
+		{name: "self", node: leaf, pred: (*Node).IsNumeric, expected: "$['foo'][0]['bar'][1]"},
+		{name: "nearest array", node: leaf, pred: (*Node).IsArray, expected: "$['foo'][0]['bar']"},
+		{name: "nearest object", node: leaf, pred: (*Node).IsObject, expected: "$['foo'][0]"},
+		{name: "by key", node: leaf, pred: func(node *Node) bool { return node.Key() == "foo" }, expected: "$['foo']"},
+		{name: "root", node: leaf, pred: func(node *Node) bool { return node.Parent() == nil }, expected: "$"},
+		{name: "not found", node: leaf, pred: (*Node).IsString, expected: ""},
+		{name: "nil", node: nil, pred: (*Node).IsArray, expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.node.Ancestor(test.pred)
+			if result.Path() != test.expected {
+				t.Errorf("Ancestor() = %q, expected %q", result.Path(), test.expected)
+			}
+		})
+	}
+}
+
+func TestCommonAncestor(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":[1,{"c":2}],"d":3},"e":[4]}`)))
+	other := Must(Unmarshal([]byte(`{"a":{"b":1}}`)))
+	b := root.MustKey("a").MustKey("b")
+	c := b.MustIndex(1).MustKey("c")
+	tests := []struct {
+		name     string
+		a, b     *Node
+		expected *Node
+	}{
+		{name: "same", a: c, b: c, expected: c},
+		{name: "root", a: root, b: root, expected: root},
+		{name: "siblings", a: b.MustIndex(0), b: b.MustIndex(1), expected: b},
+		{name: "parent and child", a: b, b: c, expected: b},
+		{name: "child and parent", a: c, b: b, expected: b},
+		{name: "root and leaf", a: root, b: c, expected: root},
+		{name: "cousins", a: c, b: root.MustKey("a").MustKey("d"), expected: root.MustKey("a")},
+		{name: "different branches", a: c, b: root.MustKey("e").MustIndex(0), expected: root},
+		{name: "different trees", a: c, b: other.MustKey("a").MustKey("b"), expected: nil},
+		{name: "detached", a: c, b: NumericNode("", 1), expected: nil},
+		{name: "nil", a: c, b: nil, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := CommonAncestor(test.a, test.b); result != test.expected {
+				t.Errorf("CommonAncestor() = %s, expected %s", result.Path(), test.expected.Path())
+			}
+		})
+	}
+}
+
+func TestNode_RawMessages(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a": {"b": [1, 2]}, "c": "d", "e": null}`)))
+	if err := root.AppendObject("f", NumericNode("", 1.5)); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	result, err := root.RawMessages()
+	if err != nil {
+		t.Fatalf("RawMessages() unexpected error: %s", err)
+	}
+	expected := map[string]json.RawMessage{
+		"a": json.RawMessage(`{"b": [1, 2]}`),
+		"c": json.RawMessage(`"d"`),
+		"e": json.RawMessage(`null`),
+		"f": json.RawMessage(`1.5`),
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("RawMessages() = %s, expected %s", result, expected)
+	}
+	var target struct {
+		A struct {
+			B []int `json:"b"`
+		} `json:"a"`
+	}
+	if err = json.Unmarshal(result["a"], &target.A); err != nil || len(target.A.B) != 2 {
+		t.Errorf("json.Unmarshal() = %v, %v", target, err)
+	}
+
+	back, err := FromRawMessages(result)
+	if err != nil {
+		t.Fatalf("FromRawMessages() unexpected error: %s", err)
+	}
+	if ok, err := back.Eq(root); err != nil || !ok {
+		t.Errorf("FromRawMessages() = %s, expected %s", back, root)
+	}
+	if _, err = FromRawMessages(map[string]json.RawMessage{"a": json.RawMessage(`{`)}); err == nil {
+		t.Errorf("FromRawMessages() expected error")
+	} else if err.Error() != "wrong request: key 'a': unexpected end of file" {
+		t.Errorf("FromRawMessages() wrong error: %s", err)
+	}
+
+	if _, err = Must(Unmarshal([]byte(`[1]`))).RawMessages(); err == nil {
+		t.Errorf("RawMessages() expected error for Array")
+	}
+	if _, err = root.MustKey("f").RawMessages(); err == nil {
+		t.Errorf("RawMessages() expected error for Numeric")
+	}
+}
+
 func TestNode_Source(t *testing.T) {
 	root, err := Unmarshal([]byte(`{"foo":true,"bar":null}`))
 	if err != nil {
@@ -869,6 +1688,55 @@ func TestNode_Path(t *testing.T) {
 	}
 }
 
+func TestNode_Path_escaped(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a.b": 1, "a'b": 2, "a\"b": 3, "a[0]": 4, "a]b": 5, "a\\b": 6, "a,b": 7,` +
+		` "a\n": 8, "\u0001": 9, "": 10, "'": 11, "\\": 12, "*": 13, "..": 14, "a:b": 15, "?(@)": 16}`)))
+	tests := []struct {
+		key   string
+		path  string
+		paths []string
+	}{
+		{key: "a.b", path: `$['a.b']`, paths: []string{`$["a.b"]`, `$.['a.b']`}},
+		{key: "a'b", path: `$['a\'b']`, paths: []string{`$["a'b"]`, `$["a\'b"]`}},
+		{key: `a"b`, path: `$['a"b']`, paths: []string{`$["a\"b"]`, `$['a\"b']`}},
+		{key: "a[0]", path: `$['a[0]']`, paths: []string{`$["a[0]"]`}},
+		{key: "a]b", path: `$['a]b']`, paths: []string{`$["a]b"]`}},
+		{key: `a\b`, path: `$['a\\b']`, paths: []string{`$["a\\b"]`}},
+		{key: "a,b", path: `$['a,b']`, paths: []string{`$["a,b"]`}},
+		{key: "a\n", path: `$['a\n']`, paths: []string{`$["a\n"]`}},
+		{key: "\u0001", path: `$['\u0001']`, paths: []string{`$["\u0001"]`}},
+		{key: "", path: `$['']`, paths: []string{`$[""]`}},
+		{key: "'", path: `$['\'']`, paths: []string{`$["'"]`}},
+		{key: `\`, path: `$['\\']`, paths: []string{`$["\\"]`}},
+		{key: "*", path: `$['*']`, paths: []string{`$["*"]`}},
+		{key: "..", path: `$['..']`, paths: []string{`$[".."]`}},
+		{key: "a:b", path: `$['a:b']`, paths: []string{`$["a:b"]`}},
+		{key: "?(@)", path: `$['?(@)']`, paths: []string{`$["?(@)"]`}},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			node, err := root.GetKey(test.key)
+			if err != nil {
+				t.Fatalf("GetKey() unexpected error: %s", err)
+			}
+			if node.Key() != test.key {
+				t.Errorf("Key() = %q, expected %q", node.Key(), test.key)
+			}
+			if node.Path() != test.path {
+				t.Errorf("Path() = %s, expected %s", node.Path(), test.path)
+			}
+			for _, path := range append(test.paths, test.path) {
+				result, err := root.JSONPath(path)
+				if err != nil {
+					t.Errorf("JSONPath(%s) unexpected error: %s", path, err)
+				} else if len(result) != 1 || result[0] != node {
+					t.Errorf("JSONPath(%s) = %s, expected %s", path, fullPath(result), test.path)
+				}
+			}
+		})
+	}
+}
+
 func TestNode_Eq(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -1037,6 +1905,138 @@ func TestNode_Eq(t *testing.T) {
 	}
 }
 
+func TestNode_EqualWith(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		options  []EqualOption
+		with     EqualOptions
+		expected bool
+	}{
+		{name: "equal", left: `{"a":[1,{"b":"c"}]}`, right: `{"a":[1.0,{"b":"c"}]}`, expected: true},
+		{name: "not equal", left: `{"a":[1,{"b":"c"}]}`, right: `{"a":[1,{"b":"d"}]}`, expected: false},
+		{name: "types", left: `[1]`, right: `["1"]`, expected: false},
+		{name: "ignore key", left: `{"id":1,"a":2}`, right: `{"id":3,"a":2}`, options: []EqualOption{IgnorePaths("$.id")}, with: EqualOptions{IgnorePaths: []string{"$.id"}}, expected: true},
+		{name: "ignore recursive", left: `[{"id":1,"ts":"x","a":2},{"id":2}]`, right: `[{"id":5,"a":2},{"id":6}]`, options: []EqualOption{IgnorePaths("$..id", "$..ts")}, with: EqualOptions{IgnorePaths: []string{"$..id", "$..ts"}}, expected: true},
+		{name: "ignore other", left: `{"id":1,"a":2}`, right: `{"id":1,"a":3}`, options: []EqualOption{IgnorePaths("$.id")}, with: EqualOptions{IgnorePaths: []string{"$.id"}}, expected: false},
+		{name: "ignore missing", left: `{"a":2}`, right: `{"id":1,"a":2}`, options: []EqualOption{IgnorePaths("$.id")}, with: EqualOptions{IgnorePaths: []string{"$.id"}}, expected: true},
+		{name: "ignore element", left: `[1,2,3]`, right: `[1,5,3]`, options: []EqualOption{IgnorePaths("$[1]")}, with: EqualOptions{IgnorePaths: []string{"$[1]"}}, expected: true},
+		{name: "ignore root", left: `[1]`, right: `{}`, options: []EqualOption{IgnorePaths("$")}, with: EqualOptions{IgnorePaths: []string{"$"}}, expected: true},
+		{name: "tolerance", left: `{"a":[0.1,1000]}`, right: `{"a":[0.1000001,1000.0000001]}`, options: []EqualOption{Tolerance(1e-6)}, with: EqualOptions{Tolerance: 1e-6}, expected: true},
+		{name: "out of tolerance", left: `[0.1]`, right: `[0.1001]`, options: []EqualOption{Tolerance(1e-6)}, with: EqualOptions{Tolerance: 1e-6}, expected: false},
+		{name: "no tolerance", left: `[0.1]`, right: `[0.1000001]`, expected: false},
+		{name: "missing as null", left: `{"a":null,"b":{"c":1}}`, right: `{"b":{"c":1,"d":null}}`, options: []EqualOption{MissingAsNull()}, with: EqualOptions{MissingAsNull: true}, expected: true},
+		{name: "missing not null", left: `{"a":null}`, right: `{"b":null}`, expected: false},
+		{name: "missing value", left: `{"a":1}`, right: `{}`, options: []EqualOption{MissingAsNull()}, with: EqualOptions{MissingAsNull: true}, expected: false},
+		{name: "missing in array", left: `[null]`, right: `[]`, options: []EqualOption{MissingAsNull()}, with: EqualOptions{MissingAsNull: true}, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			left := Must(Unmarshal([]byte(test.left)))
+			right := Must(Unmarshal([]byte(test.right)))
+			result, err := left.EqualWithOptions(right, test.options...)
+			if err != nil {
+				t.Fatalf("EqualWithOptions() unexpected error: %s", err)
+			}
+			if result != test.expected {
+				t.Errorf("EqualWithOptions() = %v, expected %v", result, test.expected)
+			}
+			if result, err = right.EqualWithOptions(left, test.options...); err != nil || result != test.expected {
+				t.Errorf("EqualWithOptions() reversed = %v, %v, expected %v", result, err, test.expected)
+			}
+			if result = left.EqualWith(right, test.with); result != test.expected {
+				t.Errorf("EqualWith() = %v, expected %v", result, test.expected)
+			}
+		})
+	}
+	root := Must(Unmarshal([]byte(`{"a":1}`)))
+	if _, err := root.EqualWithOptions(root, IgnorePaths("$[")); err == nil {
+		t.Errorf("EqualWithOptions() expected error for the wrong path")
+	}
+	if _, err := root.EqualWithOptions(nil); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("EqualWithOptions() error = %v, expected ErrNotParsed", err)
+	}
+	if root.EqualWith(root, EqualOptions{IgnorePaths: []string{"$["}}) {
+		t.Errorf("EqualWith() = true for the wrong path")
+	}
+	if root.EqualWith(nil, EqualOptions{}) {
+		t.Errorf("EqualWith() = true for nil")
+	}
+}
+
+func TestNode_Hash(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  string
+		right string
+		equal bool
+	}{
+		{name: "null", left: `null`, right: ` null `, equal: true},
+		{name: "numeric", left: `1.0`, right: `1`, equal: true},
+		{name: "numeric exponent", left: `100`, right: `1e2`, equal: true},
+		{name: "zero", left: `-0`, right: `0.0`, equal: true},
+		{name: "string", left: `"a\u0062"`, right: `"ab"`, equal: true},
+		{name: "object order", left: `{"a": 1, "b": [true, null]}`, right: `{"b":[true,null],"a":1.0}`, equal: true},
+		{name: "nested", left: `[{"a": {"b": "c"}}, []]`, right: `[{"a":{"b":"c"}},[]]`, equal: true},
+
+		{name: "numeric different", left: `1`, right: `2`},
+		{name: "bool different", left: `true`, right: `false`},
+		{name: "types", left: `1`, right: `"1"`},
+		{name: "null and false", left: `null`, right: `false`},
+		{name: "empty containers", left: `[]`, right: `{}`},
+		{name: "array order", left: `[1, 2]`, right: `[2, 1]`},
+		{name: "array nesting", left: `[[1], 2]`, right: `[[1, 2]]`},
+		{name: "keys and values", left: `{"ab": "c"}`, right: `{"a": "bc"}`},
+		{name: "strings concatenation", left: `["ab", "c"]`, right: `["a", "bc"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			left := Must(Unmarshal([]byte(test.left)))
+			right := Must(Unmarshal([]byte(test.right)))
+			if eq, err := left.Eq(right); err != nil || eq != test.equal {
+				t.Fatalf("Eq() = %v, %v, expected %v", eq, err, test.equal)
+			}
+			lhash, err := left.Hash()
+			if err != nil {
+				t.Fatalf("Hash() unexpected error: %s", err)
+			}
+			rhash, err := right.Hash()
+			if err != nil {
+				t.Fatalf("Hash() unexpected error: %s", err)
+			}
+			if (lhash == rhash) != test.equal {
+				t.Errorf("Hash() = %x and %x, expected equal: %v", lhash, rhash, test.equal)
+			}
+		})
+	}
+}
+
+func TestNode_Hash_modified(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a": [1, 2]}`)))
+	expected, err := Must(Unmarshal([]byte(`{"a": [1, 3], "b": null}`))).Hash()
+	if err != nil {
+		t.Fatalf("Hash() unexpected error: %s", err)
+	}
+	if err = root.MustKey("a").MustIndex(1).SetNumeric(3); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if err = root.AppendObject("b", NullNode("")); err != nil {
+		t.Fatalf("AppendObject() unexpected error: %s", err)
+	}
+	if value, err := root.Hash(); err != nil || value != expected {
+		t.Errorf("Hash() = %x, %v, expected %x", value, err, expected)
+	}
+
+	var node *Node
+	if _, err = node.Hash(); err == nil {
+		t.Errorf("Hash() expected error for nil node")
+	}
+	if _, err = valueNode(nil, "", Numeric, "foo").Hash(); err == nil {
+		t.Errorf("Hash() expected error for broken node")
+	}
+}
+
 func TestNode_Neq(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -1672,6 +2672,165 @@ func TestObjectNode(t *testing.T) {
 	}
 }
 
+func TestObjectNode_keys(t *testing.T) {
+	element := Must(Unmarshal([]byte(`[1]`))).MustIndex(0)
+	objects := map[string]*Node{
+		"foo": StringNode("bar", "value"),
+		"baz": element,
+	}
+	node := ObjectNode("", objects)
+	objects["other"] = objects["foo"]
+	delete(objects, "foo")
+	if keys := node.Keys(); !sliceEqual(keys, []string{"baz", "foo"}) && !sliceEqual(keys, []string{"foo", "baz"}) {
+		t.Errorf("Keys() = %v, changed with the map", keys)
+	}
+	if key := node.MustKey("foo").Key(); key != "foo" {
+		t.Errorf("Key() = %s, expected the key of the map", key)
+	}
+	if path := node.MustKey("baz").Path(); path != "$['baz']" || element.index != nil {
+		t.Errorf("Path() = %s, expected the member of the object", path)
+	}
+	if err := node.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %s", err)
+	}
+	if value, err := MarshalWithOptions(node, SortKeys()); err != nil || string(value) != `{"baz":1,"foo":"value"}` {
+		t.Errorf("Marshal() = %s, %v", value, err)
+	}
+	if len(ObjectNode("", nil).children) != 0 || ObjectNode("", nil).children == nil {
+		t.Errorf("ObjectNode() expected empty children")
+	}
+
+	invalid := ObjectNode("", map[string]*Node{"\xff": NullNode("")})
+	if err := invalid.Validate(); err == nil || err.Error() != `$: wrong request: invalid key "\xff"` {
+		t.Errorf("Validate() error = %v, expected invalid key", err)
+	}
+}
+
+func TestNode_Reduce(t *testing.T) {
+	sum := func(acc interface{}, _ string, child *Node) (interface{}, error) {
+		value, err := child.GetNumeric()
+		return acc.(float64) + value, err
+	}
+	keys := func(acc interface{}, key string, _ *Node) (interface{}, error) {
+		return acc.(string) + key + ";", nil
+	}
+	count := func(acc interface{}, _ string, child *Node) (interface{}, error) {
+		if child.IsNull() {
+			return acc, nil
+		}
+		return acc.(int) + 1, nil
+	}
+	tests := []struct {
+		name     string
+		json     string
+		init     interface{}
+		fn       func(acc interface{}, key string, child *Node) (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{name: "sum array", json: `[1,2,3.5]`, init: 0.0, fn: sum, expected: 6.5},
+		{name: "sum object", json: `{"a":1,"b":2}`, init: 10.0, fn: sum, expected: 13.0},
+		{name: "keys array", json: `[1,2,3]`, init: "", fn: keys, expected: "0;1;2;"},
+		{name: "keys object", json: `{"c":1,"a":2,"b":3}`, init: "", fn: keys, expected: "a;b;c;"},
+		{name: "count", json: `[1,null,"a",{}]`, init: 0, fn: count, expected: 3},
+		{name: "empty", json: `[]`, init: "init", fn: keys, expected: "init"},
+		{name: "error", json: `[1,"a",3]`, init: 0.0, fn: sum, err: ErrWrongType},
+		{name: "scalar", json: `1`, init: 0.0, fn: sum, err: ErrWrongType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.Reduce(test.init, test.fn)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("Reduce() error = %v, expected %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reduce() unexpected error: %s", err)
+			}
+			if result != test.expected {
+				t.Errorf("Reduce() = %v, expected %v", result, test.expected)
+			}
+		})
+	}
+
+	calls := 0
+	stop := errors.New("stop")
+	_, err := Must(Unmarshal([]byte(`[1,2,3]`))).Reduce(nil, func(acc interface{}, key string, child *Node) (interface{}, error) {
+		calls++
+		if key == "1" {
+			return nil, stop
+		}
+		return acc, nil
+	})
+	if err != stop || calls != 2 {
+		t.Errorf("Reduce() error = %v, calls = %d", err, calls)
+	}
+	if _, err = (*Node)(nil).Reduce(nil, nil); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("Reduce() error = %v, expected ErrNotParsed", err)
+	}
+}
+
+func TestNode_BigInt(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		value string
+		ok    bool
+	}{
+		{name: "small", json: `42`, value: "42", ok: true},
+		{name: "negative", json: `-7`, value: "-7", ok: true},
+		{name: "big", json: `12345678901234567890123`, value: "12345678901234567890123", ok: true},
+		{name: "exponent", json: `1.5e30`, value: "1500000000000000000000000000000", ok: true},
+		{name: "integer fraction", json: `2.000`, value: "2", ok: true},
+		{name: "fraction", json: `2.5`, ok: false},
+		{name: "string", json: `"1"`, ok: false},
+		{name: "too big exponent", json: `1e100000`, ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, ok := Must(Unmarshal([]byte(test.json))).BigInt()
+			if ok != test.ok {
+				t.Fatalf("BigInt() ok = %v, expected %v", ok, test.ok)
+			}
+			if ok && value.String() != test.value {
+				t.Errorf("BigInt() = %s, expected %s", value, test.value)
+			}
+		})
+	}
+	if _, ok := NumericNode("", 1e21).BigInt(); !ok {
+		t.Errorf("BigInt() expected ok for the constructed node")
+	}
+}
+
+func TestNode_BigFloat(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		value string
+		ok    bool
+	}{
+		{name: "integer", json: `12345678901234567890123`, value: "1.2345678901234567890123e+22", ok: true},
+		{name: "fraction", json: `0.1`, value: "0.1", ok: true},
+		{name: "precise", json: `1.000000000000000000001`, value: "1.000000000000000000001", ok: true},
+		{name: "huge", json: `-1e400`, value: "-1e+400", ok: true},
+		{name: "bool", json: `true`, ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, ok := Must(Unmarshal([]byte(test.json))).BigFloat()
+			if ok != test.ok {
+				t.Fatalf("BigFloat() ok = %v, expected %v", ok, test.ok)
+			}
+			if ok && value.Text('g', -1) != test.value {
+				t.Errorf("BigFloat() = %s, expected %s", value.Text('g', -1), test.value)
+			}
+		})
+	}
+}
+
 func TestNode_Inheritors(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1762,6 +2921,326 @@ func TestNode_JSONPath_error(t *testing.T) {
 	}
 }
 
+func TestNode_QueryWithPaths(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"id":1,"a":{"id":2,"b":[{"id":3},{"c":{"id":4}}]}}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		path     string
+		expected []string
+		err      bool
+	}{
+		{name: "root", node: root, path: "$", expected: []string{"$"}},
+		{name: "recursive", node: root, path: "$..id", expected: []string{"$['id']", "$['a']['id']", "$['a']['b'][0]['id']", "$['a']['b'][1]['c']['id']"}},
+		{name: "filter", node: root, path: "$..[?(@.id > 2)]", expected: []string{"$['a']['b'][0]", "$['a']['b'][1]['c']"}},
+		{name: "nested", node: root.MustKey("a").MustKey("b"), path: "$..id", expected: []string{"$['a']['b'][0]['id']", "$['a']['b'][1]['c']['id']"}},
+		{name: "empty", node: root, path: "$.x", expected: []string{}},
+		{name: "error", node: root, path: "$[", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.node.QueryWithPaths(test.path)
+			if test.err {
+				if err == nil {
+					t.Errorf("QueryWithPaths() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QueryWithPaths() unexpected error: %s", err)
+			}
+			paths := make([]string, 0, len(result))
+			for _, item := range result {
+				paths = append(paths, item.Path)
+				found, err := root.JSONPath(item.Path)
+				if err != nil || len(found) != 1 || found[0] != item.Node {
+					t.Errorf("QueryWithPaths() path %s doesn't refer to the node %s", item.Path, item.Node)
+				}
+			}
+			sort.Strings(paths)
+			sort.Strings(test.expected)
+			if !reflect.DeepEqual(paths, test.expected) {
+				t.Errorf("QueryWithPaths() = %v, expected %v", paths, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_TypeAt(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"name":"foo","count":10,"enabled":true,"empty":null,"list":["a",{}]}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		path     string
+		expected NodeType
+		err      string
+	}{
+		{name: "root", node: root, path: "$", expected: Object},
+		{name: "string", node: root, path: "$.name", expected: String},
+		{name: "numeric", node: root, path: "$.count", expected: Numeric},
+		{name: "bool", node: root, path: "$.enabled", expected: Bool},
+		{name: "null", node: root, path: "$.empty", expected: Null},
+		{name: "array", node: root, path: "$.list", expected: Array},
+		{name: "filter", node: root, path: "$.list[?(@ == 'a')]", expected: String},
+		{name: "relative", node: root.MustKey("list"), path: "$[1]", expected: Object},
+		{name: "pointer", node: root, path: "/list/1", expected: Object},
+		{name: "pointer root", node: root.MustKey("list"), path: "", expected: Array},
+		{name: "pointer missing", node: root, path: "/missing", err: "wrong request: wrong key 'missing'"},
+		{name: "missing", node: root, path: "$.missing", err: "wrong request: path '$.missing' found 0 nodes, expected exactly one"},
+		{name: "many", node: root, path: "$.list[*]", err: "wrong request: path '$.list[*]' found 2 nodes, expected exactly one"},
+		{name: "invalid path", node: root, path: "$[", err: "unexpected end of file"},
+		{name: "nil", node: nil, path: "$", err: "not parsed yet"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.node.TypeAt(test.path)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("TypeAt() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("TypeAt() unexpected error: %s", err)
+			} else if value != test.expected {
+				t.Errorf("TypeAt() = %d, expected %d", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_TypeHistogram(t *testing.T) {
+	root := Must(Unmarshal([]byte(`[{"id":"1","v":1},{"id":2,"v":[1]},{"id":"3"},{"v":{"id":null}}]`)))
+	tests := []struct {
+		name     string
+		path     string
+		expected map[NodeType]int
+		err      bool
+	}{
+		{name: "mixed", path: "$..id", expected: map[NodeType]int{String: 2, Numeric: 1, Null: 1}},
+		{name: "consistent", path: "$[*].id", expected: map[NodeType]int{String: 2, Numeric: 1}},
+		{name: "containers", path: "$[*].v", expected: map[NodeType]int{Numeric: 1, Array: 1, Object: 1}},
+		{name: "root", path: "$", expected: map[NodeType]int{Array: 1}},
+		{name: "not found", path: "$.none", expected: map[NodeType]int{}},
+		{name: "error", path: "$[", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := root.TypeHistogram(test.path)
+			if test.err {
+				if err == nil {
+					t.Errorf("TypeHistogram() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TypeHistogram() unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("TypeHistogram() = %v, expected %v", result, test.expected)
+			}
+		})
+	}
+	if _, err := (*Node)(nil).TypeHistogram("$"); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("TypeHistogram() error = %v, expected ErrNotParsed", err)
+	}
+}
+
+func TestNodeType_String(t *testing.T) {
+	tests := map[NodeType]string{
+		Null:        "Null",
+		Numeric:     "Numeric",
+		String:      "String",
+		Bool:        "Bool",
+		Array:       "Array",
+		Object:      "Object",
+		NodeType(6): "NodeType(6)",
+		-1:          "NodeType(-1)",
+	}
+	for _type, expected := range tests {
+		if value := _type.String(); value != expected {
+			t.Errorf("String() = %s, expected %s", value, expected)
+		}
+	}
+}
+
+func TestNode_StringOr(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"name":"foo","count":10,"enabled":true,"empty":null,"list":["a","b"]}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		path     string
+		expected string
+	}{
+		{name: "found", node: root, path: "$.name", expected: "foo"},
+		{name: "first", node: root, path: "$.list[*]", expected: "a"},
+		{name: "pointer", node: root, path: "/list/1", expected: "b"},
+		{name: "pointer missing", node: root, path: "/list/2", expected: "def"},
+		{name: "missing", node: root, path: "$.missing", expected: "def"},
+		{name: "wrong type", node: root, path: "$.count", expected: "def"},
+		{name: "null", node: root, path: "$.empty", expected: "def"},
+		{name: "invalid path", node: root, path: "XXX", expected: "def"},
+		{name: "nil", node: nil, path: "$", expected: "def"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := test.node.StringOr(test.path, "def"); value != test.expected {
+				t.Errorf("StringOr() = %q, expected %q", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_NumericOr(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"name":"foo","count":10,"enabled":true,"empty":null,"list":[1,2]}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		path     string
+		expected float64
+	}{
+		{name: "found", node: root, path: "$.count", expected: 10},
+		{name: "first", node: root, path: "$.list[*]", expected: 1},
+		{name: "pointer", node: root, path: "/count", expected: 10},
+		{name: "missing", node: root, path: "$.missing", expected: -1},
+		{name: "wrong type", node: root, path: "$.name", expected: -1},
+		{name: "null", node: root, path: "$.empty", expected: -1},
+		{name: "invalid path", node: root, path: "XXX", expected: -1},
+		{name: "nil", node: nil, path: "$", expected: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := test.node.NumericOr(test.path, -1); value != test.expected {
+				t.Errorf("NumericOr() = %v, expected %v", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_BoolOr(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"name":"foo","count":10,"enabled":false,"empty":null}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		path     string
+		expected bool
+	}{
+		{name: "found", node: root, path: "$.enabled", expected: false},
+		{name: "pointer", node: root, path: "/enabled", expected: false},
+		{name: "missing", node: root, path: "$.missing", expected: true},
+		{name: "wrong type", node: root, path: "$.count", expected: true},
+		{name: "null", node: root, path: "$.empty", expected: true},
+		{name: "invalid path", node: root, path: "XXX", expected: true},
+		{name: "nil", node: nil, path: "$", expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := test.node.BoolOr(test.path, true); value != test.expected {
+				t.Errorf("BoolOr() = %v, expected %v", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_CountJSONPath(t *testing.T) {
+	root, err := Unmarshal(jsonPathTestData)
+	if err != nil {
+		t.Errorf("Error: %s", err.Error())
+		return
+	}
+	tests := []struct {
+		path     string
+		expected int
+		error    bool
+	}{
+		{path: "$", expected: 1},
+		{path: "$.store.book[*]", expected: 4},
+		{path: "$..price", expected: 5},
+		{path: "$..book[?(@.price < 10)]", expected: 2},
+		{path: "$.store.book[0:2].title", expected: 2},
+		{path: "$.store.book[0,1,2].isbn", expected: 1},
+		{path: "$.store.unknown[*]", expected: 0},
+		{path: "$.store.unknown[?(@.price < 10)]", expected: 0},
+		{path: "$..*", expected: 27},
+		{path: "XXX", error: true},
+		{path: "$.store.book[?(@.price <)]", error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			count, err := root.CountJSONPath(test.path)
+			if err != nil {
+				if !test.error {
+					t.Errorf("Unexpected error: %s", err.Error())
+				}
+				return
+			}
+			if test.error {
+				t.Errorf("Expected error")
+				return
+			}
+			if count != test.expected {
+				t.Errorf("Wrong count: %d, expected %d", count, test.expected)
+			}
+			result, err := root.JSONPath(test.path)
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err.Error())
+			} else if len(result) != count {
+				t.Errorf("Count differs from JSONPath: %d != %d", count, len(result))
+			}
+		})
+	}
+}
+
+func TestNode_CountJSONPath_nil(t *testing.T) {
+	var node *Node
+	count, err := node.CountJSONPath("$..*")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if count != 0 {
+		t.Errorf("Wrong count: %d", count)
+	}
+}
+
+func TestNode_Coalesce(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":null,"bar":{"baz":"value"},"list":[null,1],"empty":[]}`)))
+	tests := []struct {
+		name     string
+		paths    []string
+		expected string
+	}{
+		{name: "empty", paths: nil, expected: ""},
+		{name: "first", paths: []string{"$.bar.baz", "$.foo"}, expected: `"value"`},
+		{name: "skip null", paths: []string{"$.foo", "$.bar.baz"}, expected: `"value"`},
+		{name: "skip missing", paths: []string{"$.missing", "$.bar"}, expected: `{"baz":"value"}`},
+		{name: "skip invalid", paths: []string{"XXX", "$.bar.baz"}, expected: `"value"`},
+		{name: "skip empty result", paths: []string{"$.empty[*]", "$.bar.baz"}, expected: `"value"`},
+		{name: "first non-null in result", paths: []string{"$.list[*]"}, expected: `1`},
+		{name: "nothing", paths: []string{"$.foo", "$.missing", "$.list[0]"}, expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := root.Coalesce(test.paths...)
+			if test.expected == "" {
+				if result != nil {
+					t.Errorf("Expected nil, got: %s", result)
+				}
+			} else if result == nil {
+				t.Errorf("Expected %s, got nil", test.expected)
+			} else if result.String() != test.expected {
+				t.Errorf("Expected %s, got: %s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestNode_Coalesce_nil(t *testing.T) {
+	var node *Node
+	if result := node.Coalesce("$"); result != nil {
+		t.Errorf("Expected nil, got: %s", result)
+	}
+}
+
 func TestNode_IsDirty(t *testing.T) {
 	root, err := Unmarshal(jsonPathTestData)
 	if err != nil {
@@ -0,0 +1,105 @@
+package ajson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Pointer returns the node found by the JSON Pointer (RFC 6901) relative to current node, e.g. `/store/book/0/title`.
+// Empty pointer refers to current node itself, `~1` and `~0` in the reference tokens are decoded as `/` and `~`.
+// Indexes of the arrays are decimal numbers without leading zeros, `-` (the element after the last one) is never
+// found. For the missing keys and indexes ErrKeyNotFound and ErrIndexOutOfRange errors will be returned.
+//
+// Non-standard extension: a reference token could end with the occurrence suffix `~[N]` to address the N-th
+// (zero-based) occurrence of the repeated key of the Object, kept with DuplicateKeys option, in the source order, e.g.
+// `/headers/Set-Cookie~[1]` is the second "Set-Cookie" member, same as KeyAll("Set-Cookie")[1]. Without the suffix,
+// the last occurrence is used, as in GetKey. The sequence `~[` is invalid in the standard pointers, so it doesn't
+// change the meaning of any of them, but such pointers are not portable to other implementations.
+func (n *Node) Pointer(pointer string) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if pointer == "" {
+		return n, nil
+	}
+	if pointer[0] != '/' {
+		return nil, errorRequest("wrong pointer '%s': it should start with '/'", pointer)
+	}
+	node := n
+	for _, token := range strings.Split(pointer[1:], "/") {
+		occurrence := -1
+		if i := strings.LastIndex(token, "~["); i != -1 && strings.HasSuffix(token, "]") {
+			value, err := strconv.Atoi(token[i+2 : len(token)-1])
+			if err != nil || value < 0 || token[i+2] == '+' {
+				return nil, errorRequest("wrong pointer '%s': wrong occurrence '%s'", pointer, token[i:])
+			}
+			occurrence, token = value, token[:i]
+		}
+		key, ok := pointerToken(token)
+		if !ok {
+			return nil, errorRequest("wrong pointer '%s': wrong escape in '%s'", pointer, token)
+		}
+		switch node._type {
+		case Object:
+			if occurrence == -1 {
+				value, err := node.GetKey(key)
+				if err != nil {
+					return nil, err
+				}
+				node = value
+				continue
+			}
+			list := node.KeyAll(key)
+			if len(list) == 0 {
+				return nil, errorKeyNotFound(key)
+			}
+			if occurrence >= len(list) {
+				return nil, errorOutOfRange("out of occurrence %d of the key '%s'", occurrence, key)
+			}
+			node = list[occurrence]
+		case Array:
+			if occurrence != -1 {
+				return nil, errorRequest("wrong pointer '%s': occurrence of the array index '%s'", pointer, key)
+			}
+			if key == "-" {
+				return nil, errorOutOfRange("out of index '-'")
+			}
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || key[0] == '+' || (key[0] == '0' && len(key) > 1) {
+				return nil, errorRequest("wrong pointer '%s': wrong index '%s'", pointer, key)
+			}
+			value, err := node.GetIndex(index)
+			if err != nil {
+				return nil, err
+			}
+			node = value
+		default:
+			return nil, errorType()
+		}
+	}
+	return node, nil
+}
+
+// pointerToken decodes the reference token of the JSON Pointer, false is returned for the wrong escape sequence
+func pointerToken(token string) (string, bool) {
+	if !strings.Contains(token, "~") {
+		return token, true
+	}
+	var result strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			result.WriteByte(token[i])
+			continue
+		}
+		if i+1 == len(token) || (token[i+1] != '0' && token[i+1] != '1') {
+			return "", false
+		}
+		if token[i+1] == '0' {
+			result.WriteByte('~')
+		} else {
+			result.WriteByte('/')
+		}
+		i++
+	}
+	return result.String(), true
+}
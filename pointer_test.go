@@ -0,0 +1,110 @@
+package ajson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNode_Pointer(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":["bar","baz"],"":0,"a/b":1,"c%d":2,"e^f":3,"g|h":4,"i\\j":5,"k\"l":6," ":7,"m~n":8,"01":9}`)))
+	tests := []struct {
+		pointer  string
+		expected string
+		err      error
+		wrong    bool // wrong pointer
+	}{
+		// examples of RFC 6901
+		{pointer: "", expected: "$"},
+		{pointer: "/foo", expected: "$['foo']"},
+		{pointer: "/foo/0", expected: "$['foo'][0]"},
+		{pointer: "/", expected: "$['']"},
+		{pointer: "/a~1b", expected: "$['a/b']"},
+		{pointer: "/c%d", expected: "$['c%d']"},
+		{pointer: "/e^f", expected: "$['e^f']"},
+		{pointer: "/g|h", expected: "$['g|h']"},
+		{pointer: "/i\\j", expected: "$['i\\\\j']"},
+		{pointer: "/k\"l", expected: "$['k\"l']"},
+		{pointer: "/ ", expected: "$[' ']"},
+		{pointer: "/m~0n", expected: "$['m~n']"},
+		{pointer: "/01", expected: "$['01']"},
+		{pointer: "/foo/-1", wrong: true},
+		{pointer: "/foo/01", wrong: true},
+		{pointer: "/foo/2", err: ErrIndexOutOfRange},
+		{pointer: "/foo/-", err: ErrIndexOutOfRange},
+		{pointer: "/bar", err: ErrKeyNotFound},
+		{pointer: "/foo/0/bar", err: ErrWrongType},
+		{pointer: "foo", wrong: true},
+		{pointer: "/m~2n", wrong: true},
+		{pointer: "/m~", wrong: true},
+		{pointer: "/foo~[0]/0", expected: "$['foo'][0]"},
+		{pointer: "/foo/0~[0]", wrong: true},
+	}
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			result, err := root.Pointer(test.pointer)
+			if test.wrong {
+				var value Error
+				if !errors.As(err, &value) || value.Type != WrongRequest || value.Unwrap() != nil {
+					t.Errorf("Pointer() error = %v, expected wrong pointer error", err)
+				}
+				return
+			}
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("Pointer() error = %v, expected %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Pointer() unexpected error: %s", err)
+			}
+			if path := result.Path(); path != test.expected {
+				t.Errorf("Pointer() = %s, expected %s", path, test.expected)
+			}
+		})
+	}
+	if _, err := (*Node)(nil).Pointer(""); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("Pointer() error = %v, expected ErrNotParsed", err)
+	}
+}
+
+func TestNode_Pointer_duplicates(t *testing.T) {
+	data := []byte(`{"headers":{"Set-Cookie":"a=1","Host":"x","Set-Cookie":"b=2","Set-Cookie":{"c":3}}}`)
+	root, err := UnmarshalWithOptions(data, DuplicateKeys())
+	if err != nil {
+		t.Fatalf("UnmarshalWithOptions() unexpected error: %s", err)
+	}
+	tests := []struct {
+		pointer  string
+		expected string
+		err      string
+	}{
+		{pointer: "/headers/Set-Cookie", expected: `{"c":3}`},
+		{pointer: "/headers/Set-Cookie~[0]", expected: `"a=1"`},
+		{pointer: "/headers/Set-Cookie~[1]", expected: `"b=2"`},
+		{pointer: "/headers/Set-Cookie~[2]/c", expected: `3`},
+		{pointer: "/headers/Host~[0]", expected: `"x"`},
+		{pointer: "/headers/Set-Cookie~[3]", err: "wrong request: out of occurrence 3 of the key 'Set-Cookie'"},
+		{pointer: "/headers/Cookie~[0]", err: "wrong request: wrong key 'Cookie'"},
+		{pointer: "/headers/Set-Cookie~[-1]", err: "wrong request: wrong pointer '/headers/Set-Cookie~[-1]': wrong occurrence '~[-1]'"},
+		{pointer: "/headers/Set-Cookie~[+1]", err: "wrong request: wrong pointer '/headers/Set-Cookie~[+1]': wrong occurrence '~[+1]'"},
+		{pointer: "/headers/Set-Cookie~[x]", err: "wrong request: wrong pointer '/headers/Set-Cookie~[x]': wrong occurrence '~[x]'"},
+	}
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			result, err := root.Pointer(test.pointer)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Pointer() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Pointer() unexpected error: %s", err)
+			}
+			if value := result.String(); value != test.expected {
+				t.Errorf("Pointer() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package ajson
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// QueryCache memoizes the results of the JSONPath queries for the node, so the same query for the unchanged tree
+// is evaluated only once. It's safe to use QueryCache from several goroutines, as long as the tree isn't mutated
+// concurrently.
+//
+// Every mutation of the node or any of its children (Set*, Append*, Delete*, etc.) changes the version of the node,
+// and all cached results are dropped on the next query after it. Versions are counted only for the nodes with
+// a QueryCache, so the mutations of other trees don't pay for them. Mutations outside the subtree of the node don't
+// affect the cache. Results are kept until the next mutation or Reset, so the memory grows with the count of
+// different paths: create QueryCache only for the queries, which are repeated many times.
+type QueryCache struct {
+	mu      sync.Mutex
+	node    *Node
+	watch   *watch
+	version uint64
+	results map[string][]*Node
+}
+
+// watch is the version of the node with a QueryCache, changed by every mutation of the node or its children
+type watch struct {
+	version uint64
+	caches  int // count of QueryCache for the node
+}
+
+var (
+	// watches are the versions of the nodes with a QueryCache
+	watches   = make(map[*Node]*watch)
+	watchesMu sync.Mutex
+	// watching is the count of the watches, so the mutations don't look for them while there are none
+	watching int32
+)
+
+// NewQueryCache creates a QueryCache for the node
+func NewQueryCache(node *Node) *QueryCache {
+	cache := &QueryCache{
+		node:    node,
+		results: make(map[string][]*Node),
+	}
+	if node != nil {
+		cache.watch = watchNode(node)
+		cache.version = cache.getVersion()
+		runtime.SetFinalizer(cache, func(cache *QueryCache) {
+			unwatchNode(cache.node)
+		})
+	}
+	return cache
+}
+
+// watchNode starts counting the versions of the node
+func watchNode(node *Node) *watch {
+	watchesMu.Lock()
+	defer watchesMu.Unlock()
+	current, ok := watches[node]
+	if !ok {
+		current = new(watch)
+		watches[node] = current
+		atomic.AddInt32(&watching, 1)
+	}
+	current.caches++
+	return current
+}
+
+// unwatchNode stops counting the versions of the node, when its last QueryCache is collected
+func unwatchNode(node *Node) {
+	watchesMu.Lock()
+	defer watchesMu.Unlock()
+	if current, ok := watches[node]; ok {
+		if current.caches--; current.caches == 0 {
+			delete(watches, node)
+			atomic.AddInt32(&watching, -1)
+		}
+	}
+}
+
+// touch changes the versions of current node and all of its parents, which have a QueryCache
+func (n *Node) touch() {
+	if atomic.LoadInt32(&watching) == 0 {
+		return
+	}
+	watchesMu.Lock()
+	defer watchesMu.Unlock()
+	for node := n; node != nil; node = node.parent {
+		if current, ok := watches[node]; ok {
+			atomic.AddUint64(&current.version, 1)
+		}
+	}
+}
+
+// JSONPath returns the result of the JSONPath query for the node, same as Node.JSONPath does. The result is cached,
+// errors are not. Returned slice is a copy, so it could be changed by the caller.
+func (c *QueryCache) JSONPath(path string) ([]*Node, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version := c.getVersion(); version != c.version {
+		c.version = version
+		c.results = make(map[string][]*Node)
+	}
+	result, ok := c.results[path]
+	if !ok {
+		var err error
+		result, err = c.node.JSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		c.results[path] = result
+	}
+	return append(make([]*Node, 0, len(result)), result...), nil
+}
+
+// Len returns the count of the cached results, which are still valid
+func (c *QueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.getVersion() != c.version {
+		return 0
+	}
+	return len(c.results)
+}
+
+// Reset drops all cached results
+func (c *QueryCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = make(map[string][]*Node)
+}
+
+// getVersion returns the version of the node of the cache, it's 0 for the nil node
+func (c *QueryCache) getVersion() uint64 {
+	if c.watch == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.watch.version)
+}
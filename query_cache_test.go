@@ -0,0 +1,136 @@
+package ajson
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestQueryCache(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":[1,2,3]},"c":{"d":4}}`)))
+	cache := NewQueryCache(root)
+	query := func(path string, expected string) []*Node {
+		t.Helper()
+		result, err := cache.JSONPath(path)
+		if err != nil {
+			t.Fatalf("JSONPath() unexpected error: %s", err)
+		}
+		if value := fullPath(result); value != expected {
+			t.Errorf("JSONPath(%s) = %s, expected %s", path, value, expected)
+		}
+		return result
+	}
+
+	first := query("$..b[*]", "[$['a']['b'][0], $['a']['b'][1], $['a']['b'][2]]")
+	first[0] = nil
+	second := query("$..b[*]", "[$['a']['b'][0], $['a']['b'][1], $['a']['b'][2]]")
+	if second[0] == nil {
+		t.Errorf("JSONPath() returned the cached slice")
+	}
+	query("$.c.d", "[$['c']['d']]")
+	if size := cache.Len(); size != 2 {
+		t.Errorf("Len() = %d, expected 2", size)
+	}
+	if _, err := cache.JSONPath("$["); err == nil {
+		t.Errorf("JSONPath() expected error")
+	}
+	if size := cache.Len(); size != 2 {
+		t.Errorf("Len() = %d, expected 2, errors are not cached", size)
+	}
+
+	// mutations in the subtree invalidate the cache
+	if err := root.MustKey("a").MustKey("b").AppendArray(NumericNode("", 4)); err != nil {
+		t.Fatalf("AppendArray() unexpected error: %s", err)
+	}
+	if size := cache.Len(); size != 0 {
+		t.Errorf("Len() = %d, expected 0 after mutation", size)
+	}
+	query("$..b[*]", "[$['a']['b'][0], $['a']['b'][1], $['a']['b'][2], $['a']['b'][3]]")
+	query("$..b[?(@ > 3)]", "[$['a']['b'][3]]")
+
+	// every mutation is noticed, even if the parents are already changed
+	if err := second[0].SetNumeric(10); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	query("$..b[?(@ > 3)]", "[$['a']['b'][0], $['a']['b'][3]]")
+	if err := root.MustKey("a").SetNode(Must(Unmarshal([]byte(`{"b":[5]}`)))); err != nil {
+		t.Fatalf("SetNode() unexpected error: %s", err)
+	}
+	query("$..b[?(@ > 3)]", "[$['a']['b'][0]]")
+	if err := root.DeleteKey("c"); err != nil {
+		t.Fatalf("DeleteKey() unexpected error: %s", err)
+	}
+	query("$.c.d", "[]")
+
+	cache.Reset()
+	if size := cache.Len(); size != 0 {
+		t.Errorf("Len() = %d, expected 0 after Reset", size)
+	}
+}
+
+func TestQueryCache_subtree(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":1},"c":{"d":2}}`)))
+	a := root.MustKey("a")
+	cache := NewQueryCache(a)
+	if _, err := cache.JSONPath("$.b"); err != nil {
+		t.Fatalf("JSONPath() unexpected error: %s", err)
+	}
+	if err := root.MustKey("c").MustKey("d").SetNumeric(3); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if size := cache.Len(); size != 1 {
+		t.Errorf("Len() = %d, expected 1 after mutation outside of the subtree", size)
+	}
+	if err := a.SetNode(NumericNode("", 1)); err != nil {
+		t.Fatalf("SetNode() unexpected error: %s", err)
+	}
+	result, err := cache.JSONPath("$.b")
+	if err != nil {
+		t.Fatalf("JSONPath() unexpected error: %s", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("JSONPath() = %v, expected empty result after SetNode", result)
+	}
+}
+
+func TestQueryCache_watch(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":1}`)))
+	first, second := NewQueryCache(root), NewQueryCache(root)
+	runtime.SetFinalizer(first, nil)
+	runtime.SetFinalizer(second, nil)
+	if first.watch != second.watch {
+		t.Fatalf("NewQueryCache() created two watches for the same node")
+	}
+	if err := root.MustKey("a").SetNumeric(2); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if first.getVersion() != 1 {
+		t.Errorf("getVersion() = %d, expected 1", first.getVersion())
+	}
+	unwatchNode(root)
+	if _, ok := watches[root]; !ok {
+		t.Errorf("unwatchNode() removed the watch of the second cache")
+	}
+	unwatchNode(root)
+	if _, ok := watches[root]; ok {
+		t.Errorf("unwatchNode() kept the watch without caches")
+	}
+	if err := root.MustKey("a").SetNumeric(3); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if first.getVersion() != 1 {
+		t.Errorf("getVersion() = %d, expected 1 for the removed watch", first.getVersion())
+	}
+	if cache := NewQueryCache(nil); cache.Len() != 0 {
+		t.Errorf("Len() = %d for the nil node", cache.Len())
+	}
+}
+
+func BenchmarkQueryCache(b *testing.B) {
+	root := Must(Unmarshal(jsonPathTestData))
+	cache := NewQueryCache(root)
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.JSONPath("$..book[?(@.price > 10)].title"); err != nil {
+			b.Error(err)
+		}
+	}
+}
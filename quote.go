@@ -294,3 +294,29 @@ func quoteString(s string, escapeHTML bool) []byte {
 	}
 	return result
 }
+
+// quotedLength returns the length of the result of quoteString, without building it
+func quotedLength(s string, escapeHTML bool) (result int) {
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			switch {
+			case htmlSafeSet[b] || (!escapeHTML && safeSet[b]):
+				result++
+			case b == '\\' || b == '"' || b == '\n' || b == '\r' || b == '\t':
+				result += 2
+			default:
+				result += 6
+			}
+			i++
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if (c == utf8.RuneError && size == 1) || c == '\u2028' || c == '\u2029' {
+			result += 6
+		} else {
+			result += size
+		}
+		i += size
+	}
+	return result
+}
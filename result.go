@@ -0,0 +1,142 @@
+package ajson
+
+// Result is the result of the JSONPath query, with helper methods for the common use cases.
+// Found nodes are available with All method.
+type Result struct {
+	nodes []*Node
+}
+
+// Query returns the Result of the JSONPath evaluation for current node, same as JSONPath does
+func (n *Node) Query(path string) (*Result, error) {
+	nodes, err := n.JSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{nodes: nodes}, nil
+}
+
+// First returns the first found node, or nil if nothing was found
+func (r *Result) First() *Node {
+	if r == nil || len(r.nodes) == 0 {
+		return nil
+	}
+	return r.nodes[0]
+}
+
+// All returns all found nodes
+func (r *Result) All() []*Node {
+	if r == nil {
+		return nil
+	}
+	return r.nodes
+}
+
+// Len returns count of found nodes
+func (r *Result) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.nodes)
+}
+
+// Strings returns values of the found String nodes, nodes of other types and broken values are skipped
+func (r *Result) Strings() []string {
+	result := make([]string, 0, r.Len())
+	for _, node := range r.All() {
+		if node.IsString() {
+			if value, err := node.GetString(); err == nil {
+				result = append(result, value)
+			}
+		}
+	}
+	return result
+}
+
+// Numbers returns values of the found Numeric nodes, nodes of other types and broken values are skipped
+func (r *Result) Numbers() []float64 {
+	result := make([]float64, 0, r.Len())
+	for _, node := range r.All() {
+		if node.IsNumeric() {
+			if value, err := node.GetNumeric(); err == nil {
+				result = append(result, value)
+			}
+		}
+	}
+	return result
+}
+
+// QueryMulti returns the results of the JSONPath evaluation for current node for all paths, same as JSONPath does for
+// each of them. Paths are evaluated in one pass: common leading commands of the paths (like `$.response.body` for
+// `$.response.body.id` and `$.response.body.name`) are applied only once, so it's faster than calling JSONPath for
+// each path. If any path is invalid or fails, only the error is returned.
+func (n *Node) QueryMulti(paths []string) (map[string][]*Node, error) {
+	root := new(pathTrie)
+	for _, path := range paths {
+		commands, err := ParseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		root.insert(path, commands)
+	}
+	result := make(map[string][]*Node, len(paths))
+	for _, path := range paths {
+		result[path] = make([]*Node, 0)
+	}
+	if n == nil {
+		return result, nil
+	}
+	return result, root.apply(n, 0, make([]*Node, 0), result)
+}
+
+// pathTrie is the prefix tree of the JSONPath commands, paths with the same leading commands share the same branch
+type pathTrie struct {
+	command  string
+	children []*pathTrie
+	paths    []string // paths ending at this command
+}
+
+// insert adds the commands of the path to the tree
+func (t *pathTrie) insert(path string, commands []string) {
+	current := t
+	for _, command := range commands {
+		var next *pathTrie
+		for _, child := range current.children {
+			if child.command == command {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			next = &pathTrie{command: command}
+			current.children = append(current.children, next)
+		}
+		current = next
+	}
+	current.paths = append(current.paths, path)
+}
+
+// apply applies the commands of the children to the nodes found by the previous commands, i is the index of the
+// children commands in the paths
+func (t *pathTrie) apply(node *Node, i int, nodes []*Node, result map[string][]*Node) error {
+	for _, child := range t.children {
+		found := make([]*Node, 0)
+		err := applyCommand(node, i, child.command, nodes, new(jsonPathOptions), func(value *Node) bool {
+			found = append(found, value)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		for j, path := range child.paths {
+			if j == 0 {
+				result[path] = found
+			} else { // the same path could be written differently, each of them gets its own slice
+				result[path] = append(make([]*Node, 0, len(found)), found...)
+			}
+		}
+		if err = child.apply(node, i+1, found, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package ajson
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ExampleNode_Query() {
+	root := Must(Unmarshal(jsonPathTestData))
+	result, err := root.Query(`$..book[?(@.price < 10)]`)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(result.Len(), result.First().MustKey("title").MustString())
+
+	titles, _ := root.Query(`$..title`)
+	fmt.Println(titles.Strings())
+
+	prices, _ := root.Query(`$..price`)
+	fmt.Println(prices.Numbers())
+	// Output:
+	// 2 Sayings of the Century
+	// [Sayings of the Century Sword of Honour Moby Dick The Lord of the Rings]
+	// [19.95 8.95 12.99 8.99 22.99]
+}
+
+func TestNode_Query(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a": ["foo", 1, null, "bar", 2.5, true]}`)))
+	tests := []struct {
+		name    string
+		path    string
+		length  int
+		first   string
+		strings []string
+		numbers []float64
+	}{
+		{name: "mixed", path: `$.a.*`, length: 6, first: `"foo"`, strings: []string{"foo", "bar"}, numbers: []float64{1, 2.5}},
+		{name: "single", path: `$.a[1]`, length: 1, first: `1`, strings: []string{}, numbers: []float64{1}},
+		{name: "empty", path: `$.b`, length: 0, strings: []string{}, numbers: []float64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := root.Query(test.path)
+			if err != nil {
+				t.Fatalf("Query() unexpected error: %s", err)
+			}
+			if result.Len() != test.length || len(result.All()) != test.length {
+				t.Errorf("Len() = %d, All() = %d, expected %d", result.Len(), len(result.All()), test.length)
+			}
+			if first := result.First().String(); first != test.first {
+				t.Errorf("First() = %s, expected %s", first, test.first)
+			}
+			if value := result.Strings(); !reflect.DeepEqual(value, test.strings) {
+				t.Errorf("Strings() = %v, expected %v", value, test.strings)
+			}
+			if value := result.Numbers(); !reflect.DeepEqual(value, test.numbers) {
+				t.Errorf("Numbers() = %v, expected %v", value, test.numbers)
+			}
+		})
+	}
+}
+
+func TestNode_Query_error(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{}`)))
+	if result, err := root.Query(`$[`); err == nil {
+		t.Errorf("Query() expected error, got %v", result.All())
+	}
+	var result *Result
+	if result.First() != nil || result.All() != nil || result.Len() != 0 || len(result.Strings()) != 0 || len(result.Numbers()) != 0 {
+		t.Errorf("nil Result is not empty")
+	}
+}
+
+func TestNode_QueryMulti(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	paths := []string{
+		"$.store.book[*].author",
+		"$.store.book[?(@.price < 10)].title",
+		"$['store']['book'][*]['author']",
+		"$..price",
+		"$.store.bicycle.color",
+		"$.store.missing.key",
+		"$.store.book.length",
+		"$",
+	}
+	result, err := root.QueryMulti(paths)
+	if err != nil {
+		t.Fatalf("QueryMulti() unexpected error: %s", err)
+	}
+	if len(result) != len(paths) {
+		t.Errorf("QueryMulti() returned %d results, expected %d", len(result), len(paths))
+	}
+	for _, path := range paths {
+		expected, err := root.JSONPath(path)
+		if err != nil {
+			t.Fatalf("JSONPath() unexpected error: %s", err)
+		}
+		if actual := fullPath(result[path]); actual != fullPath(expected) {
+			t.Errorf("QueryMulti()[%s] = %s, expected %s", path, actual, fullPath(expected))
+		}
+		if result[path] == nil {
+			t.Errorf("QueryMulti()[%s] is nil", path)
+		}
+	}
+	result[paths[0]][0] = nil
+	if result[paths[2]][0] == nil {
+		t.Errorf("QueryMulti() results of the same paths are shared")
+	}
+}
+
+func TestNode_QueryMulti_error(t *testing.T) {
+	root := Must(Unmarshal(jsonPathTestData))
+	for _, path := range []string{"$.store[", "$.store.book[?(@.price / 0 > 0)]", "$.store.book[(@.length / 0)]"} {
+		if _, err := root.QueryMulti([]string{"$.store", path}); err == nil {
+			t.Errorf("QueryMulti(%s) expected error", path)
+		}
+	}
+}
+
+func BenchmarkNode_QueryMulti(b *testing.B) {
+	root := Must(Unmarshal(jsonPathTestData))
+	paths := []string{
+		"$.store.book[*].author",
+		"$.store.book[*].title",
+		"$.store.book[*].price",
+		"$.store.book[*].isbn",
+		"$.store.bicycle.color",
+		"$.store.bicycle.price",
+	}
+	b.Run("QueryMulti", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := root.QueryMulti(paths); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.Run("ApplyJSONPath", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, path := range paths {
+				commands, err := ParseJSONPath(path)
+				if err != nil {
+					b.Error(err)
+				}
+				if _, err = ApplyJSONPath(root, commands); err != nil {
+					b.Error(err)
+				}
+			}
+		}
+	})
+}
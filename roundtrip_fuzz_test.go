@@ -0,0 +1,22 @@
+//go:build go1.18
+// +build go1.18
+
+package ajson
+
+import "testing"
+
+// FuzzRoundTrip checks that RoundTrip produces the valid JSON, which is equal to the source data and stable.
+// Run it with: go test -run '^$' -fuzz FuzzRoundTrip
+func FuzzRoundTrip(f *testing.F) {
+	for _, seed := range []string{
+		`null`, `true`, `0`, `-0.0`, `1.5e300`, `1e400`, `1e5000`, `12345678901234567890`, `""`,
+		`"A\/\"\\\b\f\n\r\t"`, `"😀 ☺   <&>"`, `"\ud800"`,
+		`[]`, `{}`, `[[[[[[[[[[1]]]]]]]]]]`, `{"":{"a":[1,"2",null,true,{}]}}`, `{"a":1,"a":2}`,
+		string(jsonPathTestData),
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkRoundTrip(t, data)
+	})
+}
@@ -0,0 +1,151 @@
+package ajson
+
+// Select returns a new Object node with copies of the given members of current Object node, missing keys are skipped.
+// The result is parsed from the encoded members, so it's independent of current node and keeps the order of the
+// given keys on marshaling. For non Object node WrongType error will be returned.
+func (n *Node) Select(keys ...string) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if n._type != Object {
+		return nil, errorType()
+	}
+	result := []byte{bracesL}
+	used := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		child, ok := n.children[key]
+		if !ok || used[key] {
+			continue
+		}
+		used[key] = true
+		value, err := Marshal(child)
+		if err != nil {
+			return nil, err
+		}
+		if len(result) != 1 {
+			result = append(result, coma)
+		}
+		result = append(result, EscapeString(key)...)
+		result = append(result, colon)
+		result = append(result, value...)
+	}
+	return Unmarshal(append(result, bracesR))
+}
+
+// SelectPaths returns a copy of current node, which contains only the nodes found by the given JSONPath queries,
+// with all their ancestors. Selected elements of the arrays are kept in their order, without gaps, so their indexes
+// could change. Returns an empty container of the same type, if nothing was found. For non-container node WrongType
+// error will be returned.
+//
+//	root := ajson.Must(ajson.Unmarshal([]byte(`{"id":1,"items":[{"id":2,"price":3},{"id":4,"price":5}]}`)))
+//	result, _ := root.SelectPaths("$.id", "$.items[*].id")
+//	// {"id":1,"items":[{"id":2},{"id":4}]}
+func (n *Node) SelectPaths(paths ...string) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if !n.isContainer() {
+		return nil, errorType()
+	}
+	selected := make(map[*Node]bool)
+	kept := make(map[*Node]bool)
+	for _, path := range paths {
+		nodes, err := n.JSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			selected[node] = true
+			for ; node != nil && !kept[node]; node = node.parent {
+				kept[node] = true
+			}
+		}
+	}
+	return n.selectNodes(selected, kept)
+}
+
+// selectNodes returns a copy of current container with the kept children only, selected children are copied as is
+func (n *Node) selectNodes(selected, kept map[*Node]bool) (result *Node, err error) {
+	if selected[n] {
+		return n.Clone(), nil
+	}
+	if n.IsArray() {
+		result = ArrayNode("", nil)
+		for _, child := range n.Inheritors() {
+			if !kept[child] {
+				continue
+			}
+			value, err := child.selectNodes(selected, kept)
+			if err != nil {
+				return nil, err
+			}
+			if err = result.AppendArray(value); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	result = ObjectNode("", nil)
+	for key, child := range n.children {
+		if !kept[child] {
+			continue
+		}
+		value, err := child.selectNodes(selected, kept)
+		if err != nil {
+			return nil, err
+		}
+		if err = result.AppendObject(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Omit returns a copy of current Object node without the given members, missing keys are skipped.
+// Current node stays untouched. For non Object node WrongType error will be returned.
+func (n *Node) Omit(keys ...string) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if n._type != Object {
+		return nil, errorType()
+	}
+	result := n.Clone()
+	for _, key := range keys {
+		if child, ok := result.children[key]; ok {
+			if err := result.remove(child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// OmitPaths returns a copy of current node without the nodes found by the given JSONPath queries. Current node stays
+// untouched and is never removed itself. Removed elements of the arrays leave no gaps, so indexes of the next
+// elements are changed. For non-container node WrongType error will be returned.
+//
+//	root := ajson.Must(ajson.Unmarshal([]byte(`{"id":1,"items":[{"id":2,"debug":true}],"debug":{}}`)))
+//	result, _ := root.OmitPaths("$..debug")
+//	// {"id":1,"items":[{"id":2}]}
+func (n *Node) OmitPaths(paths ...string) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	if !n.isContainer() {
+		return nil, errorType()
+	}
+	result := n.Clone()
+	for _, path := range paths {
+		nodes, err := result.JSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if err = node.Delete(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,207 @@
+package ajson
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleNode_Select() {
+	root := Must(Unmarshal([]byte(`{"id":1,"name":"foo","password":"secret","email":"foo@example.com"}`)))
+	result, _ := root.Select("name", "id", "phone")
+	fmt.Printf("%s", result)
+	// Output:
+	// {"name":"foo","id":1}
+}
+
+func TestNode_Select(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		keys     []string
+		expected string
+		err      string
+	}{
+		{name: "order", json: `{"a":1,"b":{"c":[2]},"d":"3"}`, keys: []string{"d", "b"}, expected: `{"d":"3","b":{"c":[2]}}`},
+		{name: "missing", json: `{"a":1}`, keys: []string{"b", "a", "c"}, expected: `{"a":1}`},
+		{name: "repeated", json: `{"a":1,"b":2}`, keys: []string{"a", "b", "a"}, expected: `{"a":1,"b":2}`},
+		{name: "nothing", json: `{"a":1}`, keys: nil, expected: `{}`},
+		{name: "escaped", json: `{"a\"b":1}`, keys: []string{`a"b`}, expected: `{"a\"b":1}`},
+		{name: "array", json: `[1]`, keys: []string{"0"}, err: "wrong type of Node"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.Select(test.keys...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Select() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() unexpected error: %s", err)
+			}
+			if value, err := Marshal(result); err != nil || string(value) != test.expected {
+				t.Errorf("Select() = %s, expected %s", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestNode_Select_copy(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"a":{"b":1}}`)))
+	if err := root.MustKey("a").MustKey("b").SetNumeric(2); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	result, err := root.Select("a")
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %s", err)
+	}
+	if err = result.MustKey("a").MustKey("b").SetNumeric(3); err != nil {
+		t.Fatalf("SetNumeric() unexpected error: %s", err)
+	}
+	if value := root.MustKey("a").MustKey("b").MustNumeric(); value != 2 {
+		t.Errorf("source was changed: %v", value)
+	}
+	if result.Parent() != nil {
+		t.Errorf("result has a parent")
+	}
+}
+
+func TestNode_SelectPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		paths    []string
+		expected string
+		err      string
+	}{
+		{
+			name:     "nested",
+			json:     `{"id":1,"items":[{"id":2,"price":3},{"id":4,"price":5}],"total":8}`,
+			paths:    []string{"$.id", "$.items[*].id"},
+			expected: `{"id":1,"items":[{"id":2},{"id":4}]}`,
+		},
+		{
+			name:     "elements",
+			json:     `[{"a":1},{"a":2},{"a":3}]`,
+			paths:    []string{"$[2]", "$[0].a"},
+			expected: `[{"a":1},{"a":3}]`,
+		},
+		{
+			name:     "overlapping",
+			json:     `{"a":{"b":1,"c":2},"d":3}`,
+			paths:    []string{"$.a.b", "$.a"},
+			expected: `{"a":{"b":1,"c":2}}`,
+		},
+		{name: "root", json: `{"a":1}`, paths: []string{"$"}, expected: `{"a":1}`},
+		{name: "nothing", json: `{"a":1}`, paths: []string{"$.b"}, expected: `{}`},
+		{name: "nothing array", json: `[1]`, paths: nil, expected: `[]`},
+		{name: "filter", json: `{"x":[1,5,2,7]}`, paths: []string{"$.x[?(@ > 3)]"}, expected: `{"x":[5,7]}`},
+		{name: "wrong path", json: `{}`, paths: []string{"$["}, err: "unexpected end of file"},
+		{name: "scalar", json: `1`, paths: []string{"$"}, err: "wrong type of Node"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.SelectPaths(test.paths...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("SelectPaths() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectPaths() unexpected error: %s", err)
+			}
+			if value, err := MarshalWithOptions(result, SortKeys()); err != nil || string(value) != test.expected {
+				t.Errorf("SelectPaths() = %s, expected %s", value, test.expected)
+			}
+			if root.String() != Must(Unmarshal([]byte(test.json))).String() {
+				t.Errorf("source was changed: %s", root)
+			}
+		})
+	}
+}
+
+func TestNode_Omit(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		keys     []string
+		expected string
+		err      string
+	}{
+		{name: "members", json: `{"a":1,"b":{"c":[2]},"d":"3"}`, keys: []string{"d", "b"}, expected: `{"a":1}`},
+		{name: "missing", json: `{"a":1}`, keys: []string{"b", "c"}, expected: `{"a":1}`},
+		{name: "repeated", json: `{"a":1,"b":2}`, keys: []string{"a", "a"}, expected: `{"b":2}`},
+		{name: "all", json: `{"a":1}`, keys: []string{"a"}, expected: `{}`},
+		{name: "array", json: `[1]`, keys: []string{"0"}, err: "wrong type of Node"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.Omit(test.keys...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Omit() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Omit() unexpected error: %s", err)
+			}
+			if value, err := MarshalWithOptions(result, SortKeys()); err != nil || string(value) != test.expected {
+				t.Errorf("Omit() = %s, expected %s", value, test.expected)
+			}
+			if root.String() != test.json {
+				t.Errorf("source was changed: %s", root)
+			}
+		})
+	}
+}
+
+func TestNode_OmitPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		paths    []string
+		expected string
+		err      string
+	}{
+		{
+			name:     "nested",
+			json:     `{"id":1,"items":[{"id":2,"debug":true}],"debug":{}}`,
+			paths:    []string{"$..debug"},
+			expected: `{"id":1,"items":[{"id":2}]}`,
+		},
+		{name: "elements", json: `[0,1,2,3,4]`, paths: []string{"$[1,3]", "$[0]"}, expected: `[2,4]`},
+		{name: "overlapping", json: `{"a":{"b":1},"c":2}`, paths: []string{"$.a.b", "$.a"}, expected: `{"c":2}`},
+		{name: "root", json: `{"a":1}`, paths: []string{"$"}, expected: `{"a":1}`},
+		{name: "nothing", json: `{"a":1}`, paths: []string{"$.b"}, expected: `{"a":1}`},
+		{name: "filter", json: `{"x":[1,5,2,7]}`, paths: []string{"$.x[?(@ > 3)]"}, expected: `{"x":[1,2]}`},
+		{name: "wrong path", json: `{}`, paths: []string{"$["}, err: "unexpected end of file"},
+		{name: "scalar", json: `1`, paths: []string{"$"}, err: "wrong type of Node"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.OmitPaths(test.paths...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("OmitPaths() error = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OmitPaths() unexpected error: %s", err)
+			}
+			if value, err := MarshalWithOptions(result, SortKeys()); err != nil || string(value) != test.expected {
+				t.Errorf("OmitPaths() = %s, expected %s", value, test.expected)
+			}
+			if root.String() != test.json {
+				t.Errorf("source was changed: %s", root)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package ajson
+
+import "io"
+
+// StreamBuilder writes JSON directly to the io.Writer, without building the Node tree.
+//
+// StreamBuilder checks that the structure is balanced: keys are allowed only inside of the Object, every value of the
+// Object should have a key, every container should be closed. As Builder, it never panics: the first error is stored
+// and returned by StreamBuilder.Close, all subsequent calls are ignored. Every call writes to w, so use bufio.Writer
+// for the unbuffered writers. Example:
+//
+//	err := NewStreamBuilder(w).
+//		BeginObject().
+//		Key("status_code").Value(200).
+//		Key("tags").BeginArray().Value("foo").Value("bar").EndArray().
+//		EndObject().
+//		Close()
+type StreamBuilder struct {
+	w     io.Writer
+	stack []streamLevel
+	done  bool // true, if the root value was started
+	err   error
+}
+
+// streamLevel is the state of the open container
+type streamLevel struct {
+	object bool // true for the Object, false for the Array
+	count  int  // count of the written elements
+	key    bool // true, if the key of the Object was written, but its value wasn't
+}
+
+// NewStreamBuilder creates a StreamBuilder, which writes to w
+func NewStreamBuilder(w io.Writer) *StreamBuilder {
+	return &StreamBuilder{w: w}
+}
+
+// BeginObject starts the Object value
+func (b *StreamBuilder) BeginObject() *StreamBuilder {
+	return b.begin(true, bracesL)
+}
+
+// EndObject ends the current Object
+func (b *StreamBuilder) EndObject() *StreamBuilder {
+	return b.end(true, bracesR)
+}
+
+// BeginArray starts the Array value
+func (b *StreamBuilder) BeginArray() *StreamBuilder {
+	return b.begin(false, bracketL)
+}
+
+// EndArray ends the current Array
+func (b *StreamBuilder) EndArray() *StreamBuilder {
+	return b.end(false, bracketR)
+}
+
+// Key writes the key of the next value of the current Object
+func (b *StreamBuilder) Key(key string) *StreamBuilder {
+	if b.err != nil {
+		return b
+	}
+	level := b.top()
+	if level == nil || !level.object {
+		b.err = errorRequest("key %q outside of the object", key)
+		return b
+	}
+	if level.key {
+		b.err = errorRequest("key %q after the key without value", key)
+		return b
+	}
+	if b.err = validateKey(key); b.err != nil {
+		return b
+	}
+	result := make([]byte, 0, len(key)+4)
+	if level.count != 0 {
+		result = append(result, coma)
+	}
+	result = append(result, EscapeString(key)...)
+	b.write(append(result, colon))
+	level.count++
+	level.key = true
+	return b
+}
+
+// Value writes the value. Value could be one of: nil, numeric types, string, bool, *Node, *Builder,
+// []interface{} or map[string]interface{}, same as for Builder.Set.
+func (b *StreamBuilder) Value(value interface{}) *StreamBuilder {
+	if b.err != nil {
+		return b
+	}
+	node, err := anyNode("", value)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	result, err := Marshal(node)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if b.value() {
+		b.write(result)
+	}
+	return b
+}
+
+// Close returns the first error happened during the writing, or an error if the JSON is not complete
+func (b *StreamBuilder) Close() error {
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.stack) != 0 {
+		return errorRequest("%d containers are not closed", len(b.stack))
+	}
+	if !b.done {
+		return errorRequest("nothing was written")
+	}
+	return nil
+}
+
+// begin writes the opening bracket of the container
+func (b *StreamBuilder) begin(object bool, bracket byte) *StreamBuilder {
+	if b.err != nil {
+		return b
+	}
+	if b.value() {
+		b.write([]byte{bracket})
+		b.stack = append(b.stack, streamLevel{object: object})
+	}
+	return b
+}
+
+// end writes the closing bracket of the container
+func (b *StreamBuilder) end(object bool, bracket byte) *StreamBuilder {
+	if b.err != nil {
+		return b
+	}
+	level := b.top()
+	if level == nil || level.object != object {
+		b.err = errorRequest("unexpected '%c'", bracket)
+		return b
+	}
+	if level.key {
+		b.err = errorRequest("unexpected '%c' after the key without value", bracket)
+		return b
+	}
+	b.stack = b.stack[:len(b.stack)-1]
+	b.write([]byte{bracket})
+	return b
+}
+
+// value checks that the value could be written in the current state and writes the separator, if needed
+func (b *StreamBuilder) value() bool {
+	level := b.top()
+	switch {
+	case level == nil:
+		if b.done {
+			b.err = errorRequest("value after the end of the root value")
+			return false
+		}
+		b.done = true
+	case level.object:
+		if !level.key {
+			b.err = errorRequest("value without key in the object")
+			return false
+		}
+		level.key = false
+	default:
+		if level.count != 0 {
+			b.write([]byte{coma})
+		}
+		level.count++
+	}
+	return b.err == nil
+}
+
+// top returns the current container, or nil for the root
+func (b *StreamBuilder) top() *streamLevel {
+	if len(b.stack) == 0 {
+		return nil
+	}
+	return &b.stack[len(b.stack)-1]
+}
+
+// write writes data to w, the error is stored
+func (b *StreamBuilder) write(data []byte) {
+	if b.err == nil {
+		_, b.err = b.w.Write(data)
+	}
+}
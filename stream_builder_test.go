@@ -0,0 +1,142 @@
+package ajson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func ExampleNewStreamBuilder() {
+	buf := new(bytes.Buffer)
+	err := NewStreamBuilder(buf).
+		BeginObject().
+		Key("status_code").Value(200).
+		Key("tags").BeginArray().Value("foo").Value(nil).BeginObject().EndObject().EndArray().
+		Key("body").Value(NewObject().Set("message", "OK")).
+		EndObject().
+		Close()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(buf.String())
+	// Output:
+	// {"status_code":200,"tags":["foo",null,{}],"body":{"message":"OK"}}
+}
+
+func TestStreamBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(b *StreamBuilder) *StreamBuilder
+		expected string
+	}{
+		{name: "scalar", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.Value("foo\n\"bar\"")
+		}, expected: `"foo\n\"bar\""`},
+		{name: "empty object", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().EndObject()
+		}, expected: `{}`},
+		{name: "empty array", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().EndArray()
+		}, expected: `[]`},
+		{name: "nested", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().
+				BeginArray().BeginArray().EndArray().Value(1.5).EndArray().
+				BeginObject().Key("a\"").BeginObject().Key("").Value(true).EndObject().Key("b").Value(false).EndObject().
+				Value([]interface{}{1, "2"}).
+				EndArray()
+		}, expected: `[[[],1.5],{"a\"":{"":true},"b":false},[1,"2"]]`},
+		{name: "node", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().Value(Must(Unmarshal([]byte(`{"a": [1, 2]}`)))).Value((*Node)(nil)).EndArray()
+		}, expected: `[{"a": [1, 2]},null]`},
+		{name: "integers", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().Value(uint64(math.MaxUint64)).Value(int64(math.MinInt64)).Value(int32(5)).EndArray()
+		}, expected: `[18446744073709551615,-9223372036854775808,5]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := test.build(NewStreamBuilder(buf)).Close(); err != nil {
+				t.Fatalf("Close() unexpected error: %s", err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("result = %s, expected %s", buf.String(), test.expected)
+			}
+			if _, err := Unmarshal(buf.Bytes()); err != nil {
+				t.Errorf("result is not a valid JSON: %s", err)
+			}
+		})
+	}
+}
+
+func TestStreamBuilder_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *StreamBuilder) *StreamBuilder
+		err   string
+	}{
+		{name: "nothing", build: func(b *StreamBuilder) *StreamBuilder {
+			return b
+		}, err: "wrong request: nothing was written"},
+		{name: "key at root", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.Key("foo")
+		}, err: `wrong request: key "foo" outside of the object`},
+		{name: "key in array", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().Key("foo")
+		}, err: `wrong request: key "foo" outside of the object`},
+		{name: "key after key", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().Key("foo").Key("bar")
+		}, err: `wrong request: key "bar" after the key without value`},
+		{name: "invalid key", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().Key("\xff")
+		}, err: `wrong request: invalid key "\xff"`},
+		{name: "value without key", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().Value(1)
+		}, err: "wrong request: value without key in the object"},
+		{name: "object without key", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().BeginObject()
+		}, err: "wrong request: value without key in the object"},
+		{name: "end without value", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().Key("foo").EndObject()
+		}, err: "wrong request: unexpected '}' after the key without value"},
+		{name: "wrong end", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginObject().EndArray()
+		}, err: "wrong request: unexpected ']'"},
+		{name: "end at root", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.EndObject()
+		}, err: "wrong request: unexpected '}'"},
+		{name: "not closed", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().BeginObject()
+		}, err: "wrong request: 2 containers are not closed"},
+		{name: "second root", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().EndArray().Value(1)
+		}, err: "wrong request: value after the end of the root value"},
+		{name: "unsupported value", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.Value(struct{}{})
+		}, err: "unsupported type was given: 'struct {}'"},
+		{name: "non finite value", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.BeginArray().Value(1).Value(math.Inf(1))
+		}, err: "wrong request: unsupported numeric value '+Inf'"},
+		{name: "error is kept", build: func(b *StreamBuilder) *StreamBuilder {
+			return b.Key("foo").BeginArray().EndArray()
+		}, err: `wrong request: key "foo" outside of the object`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.build(NewStreamBuilder(new(bytes.Buffer))).Close()
+			if err == nil {
+				t.Fatalf("Close() expected error")
+			}
+			if err.Error() != test.err {
+				t.Errorf("Close() error = %s, expected %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestStreamBuilder_writer(t *testing.T) {
+	err := NewStreamBuilder(&failWriter{limit: 2}).BeginArray().Value(1).Value(2).EndArray().Close()
+	if err == nil || err.Error() != "write failed" {
+		t.Errorf("Close() error = %v, expected write failed", err)
+	}
+}
@@ -0,0 +1,137 @@
+package ajson
+
+import "strings"
+
+// RenderOption is a functional option for Render
+type RenderOption func(options *renderOptions)
+
+type renderOptions struct {
+	keepUnresolved bool
+}
+
+// KeepUnresolved makes Render to keep the placeholders of the missing variables as is, instead of the error
+func KeepUnresolved() RenderOption {
+	return func(options *renderOptions) {
+		options.keepUnresolved = true
+	}
+}
+
+// Render returns a copy of current node, used as a template, with the `${name}` placeholders in the String values
+// substituted with the variables. Current node stays untouched.
+//
+// String value, which is a bare placeholder, is replaced with the copy of the whole variable node, so it could become
+// a number, an object, etc. Placeholders inside the text are replaced with the value of the String variable or
+// with the JSON of any other variable. Keys of the objects are not changed. Variable with nil value is a Null.
+//
+// Placeholder of the missing variable returns an error, which is ErrKeyNotFound, use KeepUnresolved to keep it as is.
+//
+//	tmpl := ajson.Must(ajson.Unmarshal([]byte(`{"id":"${id}","name":"user ${id}","tags":"${tags}"}`)))
+//	result, _ := tmpl.Render(map[string]*ajson.Node{
+//		"id":   ajson.NumericNode("", 1),
+//		"tags": ajson.Must(ajson.Unmarshal([]byte(`["a","b"]`))),
+//	})
+//	// {"id":1,"name":"user 1","tags":["a","b"]}
+func (n *Node) Render(vars map[string]*Node, options ...RenderOption) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	opts := new(renderOptions)
+	for _, option := range options {
+		option(opts)
+	}
+	result := n.Clone()
+	var nodes []*Node
+	_ = result.walk(func(node *Node) error {
+		if node.IsString() {
+			nodes = append(nodes, node)
+		}
+		return nil
+	})
+	for _, node := range nodes {
+		value, err := node.GetString()
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := placeholder(value); ok {
+			if variable, ok := vars[name]; ok {
+				if variable == nil {
+					variable = NullNode("")
+				}
+				if err = node.SetNode(variable); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if opts.keepUnresolved {
+				continue
+			}
+			return nil, errorUnresolved(name, node.Path())
+		}
+		text, changed, err := opts.substitute(node, value, vars)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if err = node.SetString(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// placeholder returns the name of the variable, if the value is a bare `${name}` placeholder
+func placeholder(value string) (string, bool) {
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") && strings.Count(value, "}") == 1 {
+		return value[2 : len(value)-1], true
+	}
+	return "", false
+}
+
+// substitute replaces all placeholders in the value of the node with the text of the variables
+func (o *renderOptions) substitute(node *Node, value string, vars map[string]*Node) (result string, changed bool, err error) {
+	var sb strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end == -1 {
+			break
+		}
+		end += start
+		name := value[start+2 : end]
+		variable, ok := vars[name]
+		if !ok {
+			if !o.keepUnresolved {
+				return "", false, errorUnresolved(name, node.Path())
+			}
+			sb.WriteString(value[:end+1])
+			value = value[end+1:]
+			continue
+		}
+		text, err := variableText(variable)
+		if err != nil {
+			return "", false, err
+		}
+		sb.WriteString(value[:start])
+		sb.WriteString(text)
+		value = value[end+1:]
+		changed = true
+	}
+	sb.WriteString(value)
+	return sb.String(), changed, nil
+}
+
+// variableText returns the value of the String variable or the JSON of any other one
+func variableText(variable *Node) (string, error) {
+	if variable == nil {
+		return "null", nil
+	}
+	if variable.IsString() {
+		return variable.GetString()
+	}
+	value, err := Marshal(variable)
+	return string(value), err
+}
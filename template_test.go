@@ -0,0 +1,85 @@
+package ajson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func ExampleNode_Render() {
+	tmpl := Must(Unmarshal([]byte(`{"id":"${id}","name":"user ${id}","tags":"${tags}"}`)))
+	for id := 1; id <= 2; id++ {
+		result, _ := tmpl.Render(map[string]*Node{
+			"id":   NumericNode("", float64(id)),
+			"tags": Must(Unmarshal([]byte(`["a","b"]`))),
+		})
+		value, _ := MarshalWithOptions(result, SortKeys())
+		fmt.Printf("%s\n", value)
+	}
+	// Output:
+	// {"id":1,"name":"user 1","tags":["a","b"]}
+	// {"id":2,"name":"user 2","tags":["a","b"]}
+}
+
+func TestNode_Render(t *testing.T) {
+	vars := map[string]*Node{
+		"num":  NumericNode("", 1.5),
+		"str":  StringNode("", `a "b"`),
+		"obj":  Must(Unmarshal([]byte(`{"x":[1,2]}`))),
+		"null": nil,
+		"bool": BoolNode("", true),
+	}
+	tests := []struct {
+		name     string
+		json     string
+		options  []RenderOption
+		expected string
+		err      string
+	}{
+		{name: "no placeholders", json: `{"a":[1,"b",null]}`, expected: `{"a":[1,"b",null]}`},
+		{name: "root", json: `"${obj}"`, expected: `{"x":[1,2]}`},
+		{name: "bare", json: `{"a":"${num}","b":["${str}","${null}","${bool}"]}`, expected: `{"a":1.5,"b":["a \"b\"",null,true]}`},
+		{name: "text", json: `["n=${num}","s=${str};","${obj}!","${null}${bool}"]`, expected: `["n=1.5","s=a \"b\";","{\"x\":[1,2]}!","nulltrue"]`},
+		{name: "keys untouched", json: `{"${num}":"${num}"}`, expected: `{"${num}":1.5}`},
+		{name: "not closed", json: `["${num","$num","${num}${"]`, expected: `["${num","$num","1.5${"]`},
+		{name: "copy", json: `["${obj}","${obj}"]`, expected: `[{"x":[1,2]},{"x":[1,2]}]`},
+		{name: "unresolved", json: `{"a":["${missing}"]}`, err: "wrong request: unresolved variable 'missing' at $['a'][0]"},
+		{name: "unresolved text", json: `{"a":"x ${num} ${missing}"}`, err: "wrong request: unresolved variable 'missing' at $['a']"},
+		{
+			name:     "keep unresolved",
+			json:     `["${missing}","x ${missing} ${num}"]`,
+			options:  []RenderOption{KeepUnresolved()},
+			expected: `["${missing}","x ${missing} 1.5"]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl := Must(Unmarshal([]byte(test.json)))
+			result, err := tmpl.Render(vars, test.options...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Render() error = %v, expected %s", err, test.err)
+				}
+				if !errors.Is(err, ErrKeyNotFound) {
+					t.Errorf("Render() error = %v, expected to be ErrKeyNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() unexpected error: %s", err)
+			}
+			if value, err := MarshalWithOptions(result, SortKeys()); err != nil || string(value) != test.expected {
+				t.Errorf("Render() = %s, expected %s", value, test.expected)
+			}
+			if tmpl.String() != test.json {
+				t.Errorf("template was changed: %s", tmpl)
+			}
+		})
+	}
+	if vars["obj"].String() != `{"x":[1,2]}` || vars["obj"].Parent() != nil {
+		t.Errorf("variable was changed: %s", vars["obj"])
+	}
+	if _, err := (*Node)(nil).Render(vars); !errors.Is(err, ErrNotParsed) {
+		t.Errorf("Render() error = %v, expected %v", err, ErrNotParsed)
+	}
+}
@@ -0,0 +1,101 @@
+package ajson
+
+import "sort"
+
+// TransformFunc is a single step of the Node.Transform pipeline. It could change the given node in place and return
+// it (or nil), or return a new node, which will be passed to the next step.
+type TransformFunc func(node *Node) (*Node, error)
+
+// Transform applies the steps in sequence to the copy of current node and returns the result. Current node stays
+// untouched. The error of the step is wrapped with its index, starting from 0, and could be unwrapped with errors.Is.
+//
+//	result, err := root.Transform(
+//		ajson.RenameKeys(map[string]string{"cost": "price"}),
+//		ajson.FilterChildren(func(node *ajson.Node) bool { return node.HasKey("price") }),
+//		ajson.MapNumerics(math.Round),
+//	)
+func (n *Node) Transform(steps ...TransformFunc) (*Node, error) {
+	if n == nil {
+		return nil, errorUnparsed()
+	}
+	result := n.Clone()
+	for i, step := range steps {
+		value, err := step(result)
+		if err != nil {
+			return nil, errorStep(i, err)
+		}
+		if value != nil {
+			result = value
+		}
+	}
+	return result, nil
+}
+
+// RenameKeys returns the step, which renames members of all objects in the tree by the names map: from the old key
+// to the new one. Existing member with the new key is replaced, if several keys are renamed to the same one, the last
+// of them in the sorted order wins.
+func RenameKeys(names map[string]string) TransformFunc {
+	return func(node *Node) (*Node, error) {
+		var objects []*Node
+		err := node.walk(func(node *Node) error {
+			if node.IsObject() {
+				objects = append(objects, node)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			keys := object.Keys()
+			sort.Strings(keys)
+			renamed := make([]*Node, 0)
+			for _, key := range keys {
+				if name, ok := names[key]; ok && name != key {
+					child := object.children[key]
+					if err = object.remove(child); err != nil {
+						return nil, err
+					}
+					renamed = append(renamed, child)
+				}
+			}
+			// members are removed before adding, so the keys could be swapped
+			for _, child := range renamed {
+				if err = object.AppendObject(names[*child.key], child); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return node, nil
+	}
+}
+
+// FilterChildren returns the step, which keeps only the elements of the Array, or members of the Object, for which
+// pred returns true. For non-container node WrongType error will be returned.
+func FilterChildren(pred func(node *Node) bool) TransformFunc {
+	return func(node *Node) (*Node, error) {
+		if !node.isContainer() {
+			return nil, errorType()
+		}
+		for _, child := range node.Inheritors() {
+			if !pred(child) {
+				if err := node.remove(child); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return node, nil
+	}
+}
+
+// MapNumerics returns the step, which updates all Numeric nodes in the tree with the result of fn
+func MapNumerics(fn func(value float64) float64) TransformFunc {
+	return func(node *Node) (*Node, error) {
+		return node, node.walk(func(node *Node) error {
+			if node.IsNumeric() {
+				return node.MapNumeric(fn)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package ajson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func ExampleNode_Transform() {
+	root := Must(Unmarshal([]byte(`[{"name":"foo","cost":1.6},{"name":"bar"},{"name":"baz","cost":2.2}]`)))
+	result, _ := root.Transform(
+		RenameKeys(map[string]string{"cost": "price"}),
+		FilterChildren(func(node *Node) bool { return node.HasKey("price") }),
+		MapNumerics(math.Round),
+	)
+	value, _ := MarshalWithOptions(result, SortKeys())
+	fmt.Printf("%s", value)
+	// Output:
+	// [{"name":"foo","price":2},{"name":"baz","price":2}]
+}
+
+func TestNode_Transform(t *testing.T) {
+	double := func(value float64) float64 { return value * 2 }
+	numeric := func(node *Node) bool { return node.IsNumeric() }
+	tests := []struct {
+		name     string
+		json     string
+		steps    []TransformFunc
+		expected string
+		err      string
+	}{
+		{name: "no steps", json: `{"a":1}`, expected: `{"a":1}`},
+		{
+			name:     "rename",
+			json:     `{"a":1,"b":{"a":2,"c":[{"a":3}]}}`,
+			steps:    []TransformFunc{RenameKeys(map[string]string{"a": "x", "c": "y"})},
+			expected: `{"b":{"x":2,"y":[{"x":3}]},"x":1}`,
+		},
+		{
+			name:     "rename swap",
+			json:     `{"a":1,"b":2,"c":3}`,
+			steps:    []TransformFunc{RenameKeys(map[string]string{"a": "b", "b": "a", "c": "c"})},
+			expected: `{"a":2,"b":1,"c":3}`,
+		},
+		{
+			name:     "rename replace",
+			json:     `{"a":1,"b":2}`,
+			steps:    []TransformFunc{RenameKeys(map[string]string{"a": "b"})},
+			expected: `{"b":1}`,
+		},
+		{
+			name:     "filter array",
+			json:     `[1,"a",2,null,3]`,
+			steps:    []TransformFunc{FilterChildren(numeric)},
+			expected: `[1,2,3]`,
+		},
+		{
+			name:     "filter object",
+			json:     `{"a":1,"b":"2","c":3}`,
+			steps:    []TransformFunc{FilterChildren(numeric)},
+			expected: `{"a":1,"c":3}`,
+		},
+		{
+			name:     "map",
+			json:     `{"a":1,"b":[2,{"c":3}],"d":"4"}`,
+			steps:    []TransformFunc{MapNumerics(double)},
+			expected: `{"a":2,"b":[4,{"c":6}],"d":"4"}`,
+		},
+		{
+			name: "new node",
+			json: `{"a":[1,2]}`,
+			steps: []TransformFunc{
+				func(node *Node) (*Node, error) { return node.MustKey("a"), nil },
+				MapNumerics(double),
+			},
+			expected: `[2,4]`,
+		},
+		{
+			name:  "error",
+			json:  `1`,
+			steps: []TransformFunc{MapNumerics(double), FilterChildren(numeric)},
+			err:   "wrong request: step #1: wrong type of Node",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			result, err := root.Transform(test.steps...)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("Transform() error = %v, expected %s", err, test.err)
+				}
+				if !errors.Is(err, ErrWrongType) {
+					t.Errorf("Transform() error = %v, expected to be ErrWrongType", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Transform() unexpected error: %s", err)
+			}
+			if value, err := MarshalWithOptions(result, SortKeys()); err != nil || string(value) != test.expected {
+				t.Errorf("Transform() = %s, expected %s", value, test.expected)
+			}
+			if root.String() != test.json {
+				t.Errorf("source was changed: %s", root)
+			}
+		})
+	}
+}
@@ -90,7 +90,7 @@ func unquoteBytes(s []byte, border byte) (t []byte, ok bool) {
 			switch s[r] {
 			default:
 				return
-			case border, '\\', '/', '\'':
+			case border, '\\', '/', '\'', '"':
 				b[w] = s[r]
 				r++
 				w++
@@ -0,0 +1,190 @@
+package ajson
+
+import (
+	"errors"
+	"sort"
+)
+
+// SkipSubtree can be returned by the WalkPath callback to skip the children of the current node
+var SkipSubtree = errors.New("skip subtree")
+
+// errFound stops walking, when the node is found
+var errFound = errors.New("found")
+
+// WalkPath calls fn for current node and all of it's children recursively, in depth-first order.
+// Children of the Object are visited sorted by keys, children of the Array - by index.
+//
+// fn receives the full JsonPath of the node (same as Node.Path), it's depth relative to current node
+// (0 for current node) and the node itself. If fn returns SkipSubtree, children of the node will not be visited;
+// any other error stops walking and will be returned.
+func (n *Node) WalkPath(fn func(path string, depth int, node *Node) error) error {
+	if n == nil {
+		return nil
+	}
+	err := n.walkPath(n.Path(), 0, fn)
+	if err == SkipSubtree {
+		return nil
+	}
+	return err
+}
+
+func (n *Node) walkPath(path string, depth int, fn func(path string, depth int, node *Node) error) error {
+	err := fn(path, depth, n)
+	if err != nil {
+		return err
+	}
+	for _, child := range n.Inheritors() {
+		err = child.walkPath(path+child.pathSegment(), depth+1, fn)
+		if err == SkipSubtree {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk calls fn for current node and all of it's children recursively, in the same order as WalkPath does,
+// but without calculating the paths
+func (n *Node) walk(fn func(node *Node) error) error {
+	return n.walkBy((*Node).Inheritors, fn)
+}
+
+// walkDocument calls fn for current node and all of it's children recursively, in the document order, see
+// documentChildren
+func (n *Node) walkDocument(fn func(node *Node) error) error {
+	return n.walkBy((*Node).documentChildren, fn)
+}
+
+// walkBy calls fn for current node and all of it's children recursively, in depth-first order, the children of each
+// node are visited in the order returned by children
+func (n *Node) walkBy(children func(node *Node) []*Node, fn func(node *Node) error) error {
+	err := fn(n)
+	if err != nil {
+		return err
+	}
+	for _, child := range children(n) {
+		err = child.walkBy(children, fn)
+		if err == SkipSubtree {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// documentChildren returns the children of current node in the document order: elements of the Array by index, members
+// of the Object in the order of the source, and the members added after parsing after them, sorted by keys. Without
+// the KeyRanges option the members with the replaced values count as added ones.
+func (n *Node) documentChildren() []*Node {
+	result := n.Inheritors()
+	if n.IsObject() {
+		sort.SliceStable(result, func(i, j int) bool {
+			return sourceBefore(result[i], result[j])
+		})
+	}
+	return result
+}
+
+// FindKey returns all members of the objects with the given key at any depth below current node, in the document
+// order: depth-first, members of the objects in the order of the source, new members after them, sorted by keys.
+// It's similar to the `$..key` JSONPath, but without parsing and evaluation of it.
+func (n *Node) FindKey(key string) (result []*Node) {
+	if n == nil {
+		return nil
+	}
+	_ = n.walkDocument(func(node *Node) error {
+		if node != n && node.parent.IsObject() && node.Key() == key {
+			result = append(result, node)
+		}
+		return nil
+	})
+	return result
+}
+
+// FindFirst returns the first node, for which pred returns true, in the document order (see FindKey), starting
+// from current node itself. Walking stops right after the node is found, the rest of the tree is not visited.
+// Returns nil, if nothing was found.
+func (n *Node) FindFirst(pred func(node *Node) bool) (result *Node) {
+	if n == nil {
+		return nil
+	}
+	_ = n.walkDocument(func(node *Node) error {
+		if pred(node) {
+			result = node
+			return errFound
+		}
+		return nil
+	})
+	return result
+}
+
+// Depth returns the maximum nesting depth of the tree below current node, including the node itself: 1 for scalars
+// and empty containers, 2 for `[1]` or `{"a":{}}`, etc. Returns 0 for nil node.
+// Note that MaxDepth option of UnmarshalWithOptions counts the containers only, so `[1]` passes MaxDepth(1).
+func (n *Node) Depth() (result int) {
+	if n == nil {
+		return 0
+	}
+	_ = n.WalkPath(func(_ string, depth int, _ *Node) error {
+		if depth >= result {
+			result = depth + 1
+		}
+		return nil
+	})
+	return result
+}
+
+// LeafValues returns the values of all scalar nodes (Null, Numeric, String and Bool) in current node and below it, in
+// the document order (see FindKey): nil, float64, string or bool. Containers, including the empty ones, are not
+// leaves, so they are not included. Broken values are skipped, use WalkErrors to find them.
+func (n *Node) LeafValues() (result []interface{}) {
+	if n == nil {
+		return nil
+	}
+	_ = n.walkDocument(func(node *Node) error {
+		if !node.isContainer() {
+			if value, err := node.Value(); err == nil {
+				result = append(result, value)
+			}
+		}
+		return nil
+	})
+	return result
+}
+
+// PathError is the error returned by the callback for the node with the given JsonPath
+type PathError struct {
+	Path string
+	Err  error
+}
+
+// Error interface implementation
+func (err PathError) Error() string {
+	return err.Path + ": " + err.Err.Error()
+}
+
+// Unwrap returns the original error
+func (err PathError) Unwrap() error {
+	return err.Err
+}
+
+// WalkErrors calls fn for current node and all of it's children recursively, in the same order as WalkPath does,
+// and collects all errors returned by fn with the paths of the nodes. Walking continues after the error,
+// so the result contains the complete report; nil means that there were no errors.
+// If fn returns SkipSubtree, children of the node will not be visited and no error is recorded.
+func (n *Node) WalkErrors(fn func(node *Node) error) (result []PathError) {
+	_ = n.WalkPath(func(path string, _ int, node *Node) error {
+		if err := fn(node); err != nil {
+			if err == SkipSubtree {
+				return err
+			}
+			result = append(result, PathError{Path: path, Err: err})
+		}
+		return nil
+	})
+	return result
+}
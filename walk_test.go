@@ -0,0 +1,360 @@
+package ajson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ExampleNode_WalkPath() {
+	root := Must(Unmarshal([]byte(`{"foo":[1,{"bar":null}],"baz":true}`)))
+	_ = root.WalkPath(func(path string, depth int, node *Node) error {
+		fmt.Printf("%s%s\n", strings.Repeat("  ", depth), path)
+		return nil
+	})
+	// Output:
+	// $
+	//   $['baz']
+	//   $['foo']
+	//     $['foo'][0]
+	//     $['foo'][1]
+	//       $['foo'][1]['bar']
+}
+
+func TestNode_WalkPath(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":[1,{"bar":null}],"baz":{"qux":[true]}}`)))
+	errStop := errors.New("stop")
+	tests := []struct {
+		name     string
+		node     *Node
+		fn       func(path string, depth int, node *Node) error
+		expected []string
+		err      error
+	}{
+		{
+			name:     "all",
+			node:     root,
+			expected: []string{"$:0", "$['baz']:1", "$['baz']['qux']:2", "$['baz']['qux'][0]:3", "$['foo']:1", "$['foo'][0]:2", "$['foo'][1]:2", "$['foo'][1]['bar']:3"},
+		},
+		{
+			name:     "not root",
+			node:     root.MustKey("foo"),
+			expected: []string{"$['foo']:0", "$['foo'][0]:1", "$['foo'][1]:1", "$['foo'][1]['bar']:2"},
+		},
+		{
+			name: "skip subtree",
+			node: root,
+			fn: func(path string, depth int, node *Node) error {
+				if node.Key() == "baz" {
+					return SkipSubtree
+				}
+				return nil
+			},
+			expected: []string{"$:0", "$['baz']:1", "$['foo']:1", "$['foo'][0]:2", "$['foo'][1]:2", "$['foo'][1]['bar']:3"},
+		},
+		{
+			name: "skip root",
+			node: root,
+			fn: func(path string, depth int, node *Node) error {
+				return SkipSubtree
+			},
+			expected: []string{"$:0"},
+		},
+		{
+			name: "error",
+			node: root,
+			fn: func(path string, depth int, node *Node) error {
+				if depth == 2 {
+					return errStop
+				}
+				return nil
+			},
+			expected: []string{"$:0", "$['baz']:1", "$['baz']['qux']:2"},
+			err:      errStop,
+		},
+		{
+			name: "nil",
+			node: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var visited []string
+			err := test.node.WalkPath(func(path string, depth int, node *Node) error {
+				visited = append(visited, fmt.Sprintf("%s:%d", path, depth))
+				if path != node.Path() {
+					t.Errorf("Wrong path: %s, expected %s", path, node.Path())
+				}
+				if test.fn != nil {
+					return test.fn(path, depth, node)
+				}
+				return nil
+			})
+			if err != test.err {
+				t.Errorf("WalkPath() error = %v, expected %v", err, test.err)
+			}
+			if strings.Join(visited, ", ") != strings.Join(test.expected, ", ") {
+				t.Errorf("WalkPath() visited = %v, expected %v", visited, test.expected)
+			}
+		})
+	}
+}
+
+func ExampleNode_WalkErrors() {
+	root := Must(Unmarshal([]byte(`{"name":"","age":-1,"tags":["ok",""]}`)))
+	errs := root.WalkErrors(func(node *Node) error {
+		if node.IsString() && node.MustString() == "" {
+			return errors.New("empty string")
+		}
+		if node.IsNumeric() && node.MustNumeric() < 0 {
+			return errors.New("negative number")
+		}
+		return nil
+	})
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	// Output:
+	// $['age']: negative number
+	// $['name']: empty string
+	// $['tags'][1]: empty string
+}
+
+func TestNode_WalkErrors(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"foo":[1,{"bar":null}],"baz":{"qux":[true]}}`)))
+	errWrong := errors.New("wrong")
+	tests := []struct {
+		name     string
+		node     *Node
+		fn       func(node *Node) error
+		expected []string
+	}{
+		{
+			name:     "no errors",
+			node:     root,
+			fn:       func(node *Node) error { return nil },
+			expected: nil,
+		},
+		{
+			name: "all errors",
+			node: root.MustKey("foo"),
+			fn:   func(node *Node) error { return errWrong },
+			expected: []string{
+				"$['foo']: wrong",
+				"$['foo'][0]: wrong",
+				"$['foo'][1]: wrong",
+				"$['foo'][1]['bar']: wrong",
+			},
+		},
+		{
+			name: "skip subtree",
+			node: root,
+			fn: func(node *Node) error {
+				if node.Key() == "baz" {
+					return SkipSubtree
+				}
+				if node.IsArray() || node.IsNull() || node.IsBool() {
+					return errWrong
+				}
+				return nil
+			},
+			expected: []string{
+				"$['foo']: wrong",
+				"$['foo'][1]['bar']: wrong",
+			},
+		},
+		{
+			name:     "nil",
+			node:     nil,
+			fn:       func(node *Node) error { return errWrong },
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.node.WalkErrors(test.fn)
+			if len(result) != len(test.expected) {
+				t.Fatalf("WalkErrors() = %v, expected %v", result, test.expected)
+			}
+			for i, err := range result {
+				if err.Error() != test.expected[i] {
+					t.Errorf("WalkErrors()[%d] = %s, expected %s", i, err, test.expected[i])
+				}
+				if !errors.Is(err, errWrong) {
+					t.Errorf("WalkErrors()[%d] = %v, expected to wrap %v", i, err, errWrong)
+				}
+			}
+		})
+	}
+}
+
+func ExampleNode_FindKey() {
+	root := Must(Unmarshal([]byte(`[{"latitude":1,"longitude":2},{"other":"value"},null,{"internal":{"name": "unknown", "longitude":22, "latitude":11}}]`)))
+	for _, node := range root.FindKey("latitude") {
+		fmt.Printf("%s: %s\n", node.Path(), node)
+	}
+	// Output:
+	// $[0]['latitude']: 1
+	// $[3]['internal']['latitude']: 11
+}
+
+func TestNode_FindKey(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"key":{"a":{"key":1},"key":[{"key":2}]},"b":[{"c":{"key":null}}],"0":[0]}`)))
+	tests := []struct {
+		name     string
+		node     *Node
+		key      string
+		expected []string
+	}{
+		{name: "all", node: root, key: "key", expected: []string{
+			"$['key']",
+			"$['key']['a']['key']",
+			"$['key']['key']",
+			"$['key']['key'][0]['key']",
+			"$['b'][0]['c']['key']",
+		}},
+		{name: "child", node: root.MustKey("key"), key: "key", expected: []string{
+			"$['key']['a']['key']",
+			"$['key']['key']",
+			"$['key']['key'][0]['key']",
+		}},
+		{name: "index", node: root, key: "0", expected: []string{"$['0']"}},
+		{name: "not found", node: root, key: "d", expected: nil},
+		{name: "scalar", node: root.MustKey("key").MustKey("a").MustKey("key"), key: "key", expected: nil},
+		{name: "nil", node: nil, key: "key", expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Paths(test.node.FindKey(test.key))
+			if !sliceEqual(result, test.expected) {
+				t.Errorf("FindKey() = %v, expected %v", result, test.expected)
+			}
+		})
+	}
+
+	root = Must(Unmarshal([]byte(`{"z":{"key":1},"a":{"key":2}}`)))
+	if err := root.AppendObject("c", ObjectNode("", map[string]*Node{"key": NumericNode("", 3)})); err != nil {
+		t.Fatalf("AppendObject() error: %s", err)
+	}
+	if err := root.AppendObject("b", ObjectNode("", map[string]*Node{"key": NumericNode("", 4)})); err != nil {
+		t.Fatalf("AppendObject() error: %s", err)
+	}
+	expected := []string{"$['z']['key']", "$['a']['key']", "$['b']['key']", "$['c']['key']"}
+	if result := Paths(root.FindKey("key")); !sliceEqual(result, expected) {
+		t.Errorf("FindKey() = %v, expected %v", result, expected)
+	}
+
+	data := []byte(`{"z":{"key":1},"a":{"key":2},"y":{"key":3}}`)
+	for _, test := range []struct {
+		name     string
+		options  []UnmarshalOption
+		expected []string
+	}{
+		{name: "replaced", expected: []string{"$['z']['key']", "$['y']['key']", "$['a']['key']"}},
+		{name: "replaced with key ranges", options: []UnmarshalOption{KeyRanges()}, expected: []string{"$['z']['key']", "$['a']['key']", "$['y']['key']"}},
+	} {
+		root = Must(UnmarshalWithOptions(data, test.options...))
+		if err := root.MustKey("a").SetNode(ObjectNode("", map[string]*Node{"key": NumericNode("", 4)})); err != nil {
+			t.Fatalf("SetNode() error: %s", err)
+		}
+		if result := Paths(root.FindKey("key")); !sliceEqual(result, test.expected) {
+			t.Errorf("%s: FindKey() = %v, expected %v", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestNode_FindFirst(t *testing.T) {
+	root := Must(Unmarshal([]byte(`{"b":[{"id":2},{"id":3}],"a":{"id":1,"c":{"id":4}}}`)))
+	withID := func(node *Node) bool { return node.HasKey("id") }
+	tests := []struct {
+		name     string
+		node     *Node
+		pred     func(node *Node) bool
+		expected string
+		visited  int
+	}{
+		{name: "self", node: root, pred: func(node *Node) bool { return true }, expected: "$", visited: 1},
+		{name: "first in order", node: root, pred: withID, expected: "$['b'][0]", visited: 3},
+		{name: "nested", node: root.MustKey("b"), pred: withID, expected: "$['b'][0]", visited: 2},
+		{name: "deep", node: root, pred: func(node *Node) bool { return node.IsNumeric() && node.MustNumeric() == 4 }, expected: "$['a']['c']['id']", visited: 10},
+		{name: "not found", node: root, pred: func(node *Node) bool { return node.IsBool() }, expected: "", visited: 10},
+		{name: "nil", node: nil, pred: withID, expected: "", visited: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			visited := 0
+			result := test.node.FindFirst(func(node *Node) bool {
+				visited++
+				return test.pred(node)
+			})
+			if result.Path() != test.expected {
+				t.Errorf("FindFirst() = %s, expected %s", result.Path(), test.expected)
+			}
+			if visited != test.visited {
+				t.Errorf("FindFirst() visited %d nodes, expected %d", visited, test.visited)
+			}
+		})
+	}
+}
+
+func TestNode_Depth(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected int
+	}{
+		{json: `1`, expected: 1},
+		{json: `"foo"`, expected: 1},
+		{json: `[]`, expected: 1},
+		{json: `{}`, expected: 1},
+		{json: `[1]`, expected: 2},
+		{json: `{"a":{}}`, expected: 2},
+		{json: `[[[]],1]`, expected: 3},
+		{json: `{"a":1,"b":[{"c":[null]}],"d":{"e":2}}`, expected: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.json, func(t *testing.T) {
+			root := Must(Unmarshal([]byte(test.json)))
+			if value := root.Depth(); value != test.expected {
+				t.Errorf("Depth() = %d, expected %d", value, test.expected)
+			}
+		})
+	}
+	root := Must(Unmarshal([]byte(`{"a":[[1]],"b":2}`)))
+	if value := root.MustKey("a").Depth(); value != 3 {
+		t.Errorf("Depth() = %d, expected 3 for the subtree", value)
+	}
+	if value := (*Node)(nil).Depth(); value != 0 {
+		t.Errorf("Depth() = %d, expected 0 for nil", value)
+	}
+}
+
+func TestNode_LeafValues(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected []interface{}
+	}{
+		{json: `1`, expected: []interface{}{float64(1)}},
+		{json: `null`, expected: []interface{}{nil}},
+		{json: `[]`, expected: nil},
+		{json: `{"a":{},"b":[[]]}`, expected: nil},
+		{json: `[1,"a",true,null]`, expected: []interface{}{float64(1), "a", true, nil}},
+		{
+			json:     `{"b":[2,{"d":"x","c":false}],"a":1.5,"e":{}}`,
+			expected: []interface{}{float64(2), "x", false, 1.5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.json, func(t *testing.T) {
+			result := Must(Unmarshal([]byte(test.json))).LeafValues()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("LeafValues() = %#v, expected %#v", result, test.expected)
+			}
+		})
+	}
+	var node *Node
+	if result := node.LeafValues(); result != nil {
+		t.Errorf("LeafValues() = %#v, expected nil", result)
+	}
+}